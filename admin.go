@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// routeAdmin adds the admin API:
+//
+//	GET    /admin/track          the queries the filter stream tracks
+//	PUT    /admin/track          replace them with a JSON list of queries
+//	POST   /admin/track          add the query in a {"query": ...} body
+//	DELETE /admin/track/{query}  stop tracking query
+//
+// Changing the queries reconnects the stream with the new set. The API is only served with an
+// admin token to require.
+func (s *HarvesterServer) routeAdmin() {
+	if s.adminToken == "" {
+		return
+	}
+	admin := s.router.PathPrefix("/admin").Subrouter()
+	admin.Handle("/track", s.requireAdmin(s.getTrack)).Methods(http.MethodGet)
+	admin.Handle("/track", s.requireAdmin(s.setTrack)).Methods(http.MethodPut)
+	admin.Handle("/track", s.requireAdmin(s.addTrack)).Methods(http.MethodPost)
+	admin.Handle("/track/{query}", s.requireAdmin(s.removeTrack)).Methods(http.MethodDelete)
+}
+
+// AdministerStream lets the admin API change what stream tracks
+func (s *HarvesterServer) AdministerStream(stream *FilterStream) {
+	s.streamMutex.Lock()
+	defer s.streamMutex.Unlock()
+	s.stream = stream
+}
+
+// requireAdmin only lets requests with the admin token as a bearer token through, and none
+// if there's no admin token
+func (s *HarvesterServer) requireAdmin(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			s.writeError(w, http.StatusForbidden, errors.New("no admin token is set"))
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			s.writeError(w, http.StatusUnauthorized, errors.New("a valid admin token is required"))
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// filterStream returns the administered stream, responding with an error if there's none
+func (s *HarvesterServer) filterStream(w http.ResponseWriter) *FilterStream {
+	s.streamMutex.Lock()
+	defer s.streamMutex.Unlock()
+	if s.stream == nil {
+		s.writeError(w, http.StatusConflict, errors.New("not harvesting a filter stream"))
+	}
+	return s.stream
+}
+
+func (s *HarvesterServer) getTrack(w http.ResponseWriter, r *http.Request) {
+	if stream := s.filterStream(w); stream != nil {
+		s.writeJSON(w, http.StatusOK, stream.Track())
+	}
+}
+
+func (s *HarvesterServer) setTrack(w http.ResponseWriter, r *http.Request) {
+	stream := s.filterStream(w)
+	if stream == nil {
+		return
+	}
+	var track []string
+	if err := json.NewDecoder(r.Body).Decode(&track); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	stream.SetTrack(track)
+	s.logger.Info("Tracked queries replaced", zap.Strings("track", track))
+	s.writeJSON(w, http.StatusOK, stream.Track())
+}
+
+func (s *HarvesterServer) addTrack(w http.ResponseWriter, r *http.Request) {
+	stream := s.filterStream(w)
+	if stream == nil {
+		return
+	}
+	var request struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Query == "" {
+		s.writeError(w, http.StatusBadRequest, errors.New("query is required"))
+		return
+	}
+	stream.AddTrack(request.Query)
+	s.logger.Info("Tracked query added", zap.String("query", request.Query))
+	s.writeJSON(w, http.StatusOK, stream.Track())
+}
+
+func (s *HarvesterServer) removeTrack(w http.ResponseWriter, r *http.Request) {
+	stream := s.filterStream(w)
+	if stream == nil {
+		return
+	}
+	query := mux.Vars(r)["query"]
+	if !stream.RemoveTrack(query) {
+		s.writeError(w, http.StatusNotFound, errors.New("query isn't tracked"))
+		return
+	}
+	s.logger.Info("Tracked query removed", zap.String("query", query))
+	s.writeJSON(w, http.StatusOK, stream.Track())
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// htmlArchiveEnricher keeps a copy of the destination's HTML next to the stored document, so the
+// archive is still useful once the original page is gone
+type htmlArchiveEnricher struct {
+	compress bool
+}
+
+func (e htmlArchiveEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	if !page.IsHTML() {
+		return
+	}
+
+	key := resource.Slug + ".html"
+	data := page.Body
+	if e.compress {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write(page.Body); err != nil {
+			return
+		}
+		if err := writer.Close(); err != nil {
+			return
+		}
+		key += ".gz"
+		data = compressed.Bytes()
+	}
+
+	resource.Attachments = append(resource.Attachments, Attachment{Key: key, Data: data})
+	resource.FrontMatter["htmlArchive"] = key
+	if page.Truncated {
+		resource.FrontMatter["htmlArchiveTruncated"] = true
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// minArticleParagraphLength is the shortest paragraph that counts towards finding the article;
+// shorter ones are usually captions, bylines or share buttons
+const minArticleParagraphLength = 40
+
+// boilerplateSelector matches the parts of a page that are never part of its main content
+const boilerplateSelector = "script, style, noscript, iframe, form, nav, header, footer, aside, [role=navigation], [role=banner], [role=contentinfo]"
+
+// extractArticleText finds the main content of a page, using the same idea as readability:
+// the element holding the most paragraph text is the article, everything else is boilerplate.
+// The document is shared with other enrichment stages so boilerplate is skipped, not removed.
+func extractArticleText(doc *goquery.Document) string {
+	isBoilerplate := func(s *goquery.Selection) bool {
+		return s.Closest(boilerplateSelector).Length() > 0
+	}
+
+	var best *goquery.Selection
+	bestScore := 0
+	// selections aren't comparable, so we score the underlying nodes
+	scores := make(map[interface{}]int)
+	doc.Find("p").Each(func(i int, p *goquery.Selection) {
+		length := len(strings.TrimSpace(p.Text()))
+		if length < minArticleParagraphLength || isBoilerplate(p) {
+			return
+		}
+		parent := p.Parent()
+		if len(parent.Nodes) == 0 {
+			return
+		}
+		scores[parent.Nodes[0]] += length
+		if score := scores[parent.Nodes[0]]; score > bestScore {
+			best, bestScore = parent, score
+		}
+	})
+	if best == nil {
+		return ""
+	}
+
+	var paragraphs []string
+	best.Find("h1, h2, h3, h4, p, li, blockquote, pre").Each(func(i int, block *goquery.Selection) {
+		text := strings.Join(strings.Fields(block.Text()), " ")
+		if text == "" || isBoilerplate(block) {
+			return
+		}
+		switch {
+		case block.Is("h1, h2, h3, h4"):
+			text = "## " + text
+		case block.Is("li"):
+			text = "- " + text
+		case block.Is("blockquote"):
+			text = "> " + text
+		}
+		paragraphs = append(paragraphs, text)
+	})
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// articleTextEnricher stores the main text of the destination below the front matter, which
+// makes the archive searchable and readable without going back to the site
+type articleTextEnricher struct{}
+
+func (articleTextEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	doc, err := page.Document()
+	if err != nil {
+		return
+	}
+	text := extractArticleText(doc)
+	if text == "" {
+		return
+	}
+	resource.ArticleText = text
+	resource.FrontMatter["wordCount"] = len(strings.Fields(text))
+	resource.Sections = append(resource.Sections, text)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// asyncWriteFullWarningInterval keeps a full queue from being logged for every resource
+const asyncWriteFullWarningInterval = time.Minute
+
+// errAsyncWriterClosed is returned for resources handed to the writer after Close
+var errAsyncWriterClosed = errors.New("the storage write queue is closed")
+
+type pendingWrite struct {
+	resource    *DocumentTemplateData
+	document    string
+	attachments []Attachment
+	// written and failed are the writer's own, unless the write is a record rather than a
+	// resource; written may be nil
+	written func(write *pendingWrite)
+	failed  func(write *pendingWrite, attempts int, err error)
+}
+
+// AsyncResourceWriter takes writing resources to the store off the harvesting path: resources
+// are queued and written in batches in the background, transient failures retried with
+// exponential backoff and the resources that still can't be written handed to failed. Once the
+// queue is full harvesting waits for it, which is logged and counted in
+// harvester_storage_write_queue_full_total.
+//
+// Each resource that's written is handed to written, and once a whole batch is written flushed
+// is called, so whatever has to be persisted about the resources is saved once per batch
+// rather than once per resource.
+type AsyncResourceWriter struct {
+	logger    *zap.Logger
+	mutex     sync.Mutex
+	next      ResourceWriter
+	queue     chan *pendingWrite
+	batchSize int
+	retries   int
+	written   func(write *pendingWrite)
+	failed    func(write *pendingWrite, attempts int, err error)
+	flushed   func()
+	warnedAt  time.Time
+	closing   sync.RWMutex
+	closed    bool
+	done      sync.WaitGroup
+}
+
+// NewAsyncResourceWriter starts writing to next in the background, queueing up to queueSize
+// resources and writing up to batchSize of them at a time
+func NewAsyncResourceWriter(next ResourceWriter, logger *zap.Logger, queueSize int, batchSize int, retries int, written func(write *pendingWrite), failed func(write *pendingWrite, attempts int, err error), flushed func()) *AsyncResourceWriter {
+	result := new(AsyncResourceWriter)
+	result.logger = logger
+	result.next = next
+	result.queue = make(chan *pendingWrite, queueSize)
+	result.batchSize = batchSize
+	if result.batchSize < 1 {
+		result.batchSize = 1
+	}
+	result.retries = retries
+	result.written = written
+	result.failed = failed
+	result.flushed = flushed
+
+	result.done.Add(1)
+	go func() {
+		defer result.done.Done()
+		for first := range result.queue {
+			batch := []*pendingWrite{first}
+		drain:
+			for len(batch) < result.batchSize {
+				select {
+				case write, ok := <-result.queue:
+					if !ok {
+						break drain
+					}
+					batch = append(batch, write)
+				default:
+					break drain
+				}
+			}
+			result.writeBatch(batch)
+		}
+	}()
+	return result
+}
+
+// WriteResource implements ResourceWriter, queueing the resource; it's only stored once it's
+// been handed to written
+func (w *AsyncResourceWriter) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	return w.enqueue(&pendingWrite{resource: resource, document: document, attachments: attachments, written: w.written, failed: w.failed})
+}
+
+func (w *AsyncResourceWriter) enqueue(write *pendingWrite) error {
+	// Close waits for resources being queued before it closes the queue
+	w.closing.RLock()
+	defer w.closing.RUnlock()
+	if w.closed {
+		return errAsyncWriterClosed
+	}
+
+	select {
+	case w.queue <- write:
+		return nil
+	default:
+	}
+
+	storageWriteQueueFullCounter.Inc()
+	w.mutex.Lock()
+	if time.Since(w.warnedAt) > asyncWriteFullWarningInterval {
+		w.warnedAt = time.Now()
+		w.logger.Warn("Storage write queue is full, harvesting is waiting for storage", zap.Int("queued", len(w.queue)))
+	}
+	w.mutex.Unlock()
+	w.queue <- write
+	return nil
+}
+
+// writeTo switches the writer resources are written with, e.g. when the output format is
+// reloaded
+func (w *AsyncResourceWriter) writeTo(next ResourceWriter) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.next = next
+}
+
+func (w *AsyncResourceWriter) writeBatch(batch []*pendingWrite) {
+	w.mutex.Lock()
+	next := w.next
+	w.mutex.Unlock()
+
+	for _, write := range batch {
+		if attempts, err := writeWithRetries(next, write.resource, write.document, write.attachments, w.retries); err != nil {
+			write.failed(write, attempts, err)
+			continue
+		}
+		if write.written != nil {
+			write.written(write)
+		}
+	}
+	w.flushed()
+	w.logger.Debug("Wrote batch of resources", zap.Int("resources", len(batch)), zap.Int("queued", len(w.queue)))
+}
+
+// Close waits for the queued resources to be written; resources handed to the writer from then
+// on are refused
+func (w *AsyncResourceWriter) Close() error {
+	w.closing.Lock()
+	if w.closed {
+		w.closing.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.closing.Unlock()
+	w.done.Wait()
+	return nil
+}
+
+// Queued implements QueuedWriter
+func (w *AsyncResourceWriter) Queued() int {
+	return len(w.queue)
+}
+
+// WriteAsynchronously makes the storage queue resources and write them in the background,
+// retrying and dead-lettering them like it does when writing them as they're harvested; see
+// AsyncResourceWriter. Resources only count as stored, and the seen URLs and manifest are only
+// saved, once they've been written.
+func (storage *HarvestedResourceStorage) WriteAsynchronously(queueSize int, batchSize int) {
+	written := func(write *pendingWrite) {
+		storage.written(write.resource, write.document, write.attachments)
+	}
+	failed := func(write *pendingWrite, attempts int, err error) {
+		storage.storeFailed(write.resource, write.document, write.attachments, "write", attempts, err)
+	}
+	storage.asyncWriter = NewAsyncResourceWriter(storage.writer, storage.logger, queueSize, batchSize, storage.writeRetries, written, failed, storage.saveIndexes)
+	storage.writer = storage.asyncWriter
+}
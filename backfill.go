@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"go.uber.org/zap"
+)
+
+// StreamBackfill searches for the tweets a filter stream missed while it was disconnected, so
+// outages don't leave holes in the store; it uses the standard search, which covers 7 days
+type StreamBackfill struct {
+	scheduler *twitter.RateLimitScheduler
+	state     *HarvestState
+	logger    *zap.Logger
+	params    url.Values
+}
+
+// NewStreamBackfill prepares backfilling, passing params (e.g. lang) along with each search;
+// the tweets state (which may be nil) says were harvested already are left out
+func NewStreamBackfill(scheduler *twitter.RateLimitScheduler, state *HarvestState, logger *zap.Logger, params url.Values) *StreamBackfill {
+	result := new(StreamBackfill)
+	result.scheduler = scheduler
+	result.state = state
+	result.logger = logger
+	result.params = params
+	return result
+}
+
+// Backfill sends on the tweets each of the track queries finds between sinceID, the last
+// tweet received before the disconnection at from, and untilID, the first one after it;
+// the ones already harvested are left out
+func (b *StreamBackfill) Backfill(ctx context.Context, track []string, sinceID int64, untilID int64, from time.Time, items chan<- Item) {
+	b.logger.Info("Backfilling Twitter Stream gap", zap.Time("from", from), zap.Duration("gap", time.Since(from)),
+		zap.Strings("track", track))
+	harvested := make(map[int64]bool)
+	if b.state != nil {
+		harvested = b.state.HarvestedTweetIDs(from)
+	}
+	seen := make(map[string]bool)
+	var found, skipped int
+	for _, query := range track {
+		maxID := untilID - 1
+		for ctx.Err() == nil && maxID > sinceID {
+			v := url.Values{}
+			for name, values := range b.params {
+				v[name] = values
+			}
+			v.Set("since_id", strconv.FormatInt(sinceID, 10))
+			v.Set("max_id", strconv.FormatInt(maxID, 10))
+			v.Set("count", "100")
+			v.Set("result_type", "recent")
+
+			var searchResult anaconda.SearchResponse
+			err := b.scheduler.Do(ctx, "search", "/search/tweets", func(api *anaconda.TwitterApi) error {
+				var searchErr error
+				searchResult, searchErr = api.GetSearch(query, v)
+				return searchErr
+			})
+			if err != nil {
+				b.logger.Error("Unable to backfill Twitter Stream gap", zap.String("query", query), zap.Error(err))
+				break
+			}
+			if len(searchResult.Statuses) == 0 {
+				break
+			}
+
+			// pages go back in time, from maxID down to sinceID
+			for i := range searchResult.Statuses {
+				tweet := &searchResult.Statuses[i]
+				if tweet.Id <= maxID {
+					maxID = tweet.Id - 1
+				}
+				if harvested[tweet.Id] || seen[tweet.IdStr] {
+					skipped++
+					continue
+				}
+				seen[tweet.IdStr] = true
+				found++
+				select {
+				case items <- Item{Tweet: tweet, Provenance: &Provenance{Query: query, Source: "twitter-backfill"}}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+	b.logger.Info("Backfilled Twitter Stream gap", zap.Time("from", from), zap.Int("tweets", found),
+		zap.Int("alreadyHarvested", skipped))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// BloomFilter is a probabilistic set of URLs, small enough to keep around across runs; it
+// may report a URL that was never added (at the configured rate) but never misses one that was
+type BloomFilter struct {
+	path   string
+	mutex  sync.Mutex
+	hashes uint64
+	bits   []uint64
+	dirty  bool
+}
+
+type bloomFilterFile struct {
+	Hashes uint64
+	Bits   []uint64
+}
+
+// NewBloomFilter loads the filter persisted at path or, if there isn't one yet, creates a
+// filter sized for capacity URLs with the given false positive rate
+func NewBloomFilter(path string, capacity int, falsePositiveRate float64) (*BloomFilter, error) {
+	result := new(BloomFilter)
+	result.path = path
+
+	file, err := os.Open(path)
+	if err == nil {
+		defer file.Close()
+		var persisted bloomFilterFile
+		if err := gob.NewDecoder(file).Decode(&persisted); err != nil {
+			return nil, err
+		}
+		// visit takes hashes modulo the number of bits
+		if len(persisted.Bits) == 0 || persisted.Hashes == 0 {
+			return nil, fmt.Errorf("%s is an empty Bloom filter", path)
+		}
+		result.hashes = persisted.Hashes
+		result.bits = persisted.Bits
+		return result, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	size := math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	result.bits = make([]uint64, uint64(size)/64+1)
+	result.hashes = uint64(math.Max(1, math.Round(size/float64(capacity)*math.Ln2)))
+	return result, nil
+}
+
+// Test returns true if url was (probably) added to the filter
+func (filter *BloomFilter) Test(url string) bool {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	return filter.visit(url, false)
+}
+
+// Add adds url to the filter
+func (filter *BloomFilter) Add(url string) {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	if !filter.visit(url, true) {
+		filter.dirty = true
+	}
+}
+
+// visit checks url's bits, setting them if set is true, and returns whether they all were
+func (filter *BloomFilter) visit(url string, set bool) bool {
+	hash := fnv.New64a()
+	hash.Write([]byte(url))
+	h1 := hash.Sum64()
+	h2 := h1>>33 | h1<<31
+
+	size := uint64(len(filter.bits)) * 64
+	found := true
+	for i := uint64(0); i < filter.hashes; i++ {
+		bit := (h1 + i*h2) % size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if filter.bits[word]&mask == 0 {
+			found = false
+			if set {
+				filter.bits[word] |= mask
+			}
+		}
+	}
+	return found
+}
+
+// Size returns how many bytes the filter's bits take
+func (filter *BloomFilter) Size() int {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	return len(filter.bits) * 8
+}
+
+// Save persists the filter if URLs were added since it was last saved
+func (filter *BloomFilter) Save() error {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+
+	if !filter.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filter.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := filter.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(bloomFilterFile{Hashes: filter.hashes, Bits: filter.bits}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filter.path); err != nil {
+		return err
+	}
+	filter.dirty = false
+	return nil
+}
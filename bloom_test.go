@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBloomFilterPersistsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".seen-urls.bloom")
+	harvested := "https://example.com/article"
+
+	filter, err := NewBloomFilter(path, 1000, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Test(harvested) {
+		t.Fatalf("%s was seen before it was added", harvested)
+	}
+	filter.Add(harvested)
+	if err := filter.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	nextRun, err := NewBloomFilter(path, 1000, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nextRun.Test(harvested) {
+		t.Errorf("%s wasn't seen by the next run", harvested)
+	}
+	if nextRun.Test("https://example.com/another-article") {
+		t.Errorf("a URL that was never added was seen by the next run")
+	}
+}
+
+func TestBloomFilterRejectsEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".seen-urls.bloom")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewEncoder(file).Encode(bloomFilterFile{Hashes: 3}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if _, err := NewBloomFilter(path, 1000, 0.001); err == nil {
+		t.Error("a filter without bits was loaded")
+	}
+}
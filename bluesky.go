@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// defaultJetstream is one of Bluesky's public Jetstream instances, which relay the AT Protocol
+// firehose as JSON
+const defaultJetstream = "wss://jetstream2.us-east.bsky.network/subscribe"
+
+// blueskyRewind is how far before the last event seen a reconnection resumes from, so nothing
+// posted while the connection was down is missed
+const blueskyRewind = 5 * time.Second
+
+type blueskyFacet struct {
+	Index struct {
+		ByteStart int `json:"byteStart"`
+		ByteEnd   int `json:"byteEnd"`
+	} `json:"index"`
+	Features []struct {
+		Type string `json:"$type"`
+		URI  string `json:"uri"`
+	} `json:"features"`
+}
+
+type blueskyPost struct {
+	Text   string         `json:"text"`
+	Langs  []string       `json:"langs"`
+	Facets []blueskyFacet `json:"facets"`
+	Embed  *struct {
+		External *struct {
+			URI string `json:"uri"`
+		} `json:"external"`
+	} `json:"embed"`
+}
+
+type jetstreamEvent struct {
+	DID    string `json:"did"`
+	TimeUS int64  `json:"time_us"`
+	Kind   string `json:"kind"`
+	Commit *struct {
+		Operation  string       `json:"operation"`
+		Collection string       `json:"collection"`
+		RKey       string       `json:"rkey"`
+		Record     *blueskyPost `json:"record"`
+	} `json:"commit"`
+}
+
+// BlueskyFirehose is a Source of the posts on Bluesky that mention one of its keywords (or of
+// all of them, without keywords), from a Jetstream relay of the AT Protocol firehose
+type BlueskyFirehose struct {
+	jetstream string
+	keywords  []string
+	logger    *zap.Logger
+	cursor    int64
+}
+
+// NewBlueskyFirehose follows the posts at jetstream, keeping those whose text contains one of
+// keywords, regardless of case
+func NewBlueskyFirehose(jetstream string, keywords []string, logger *zap.Logger) *BlueskyFirehose {
+	result := new(BlueskyFirehose)
+	result.jetstream = jetstream
+	for _, keyword := range keywords {
+		result.keywords = append(result.keywords, strings.ToLower(keyword))
+	}
+	result.logger = logger
+	return result
+}
+
+// Start implements Source, following the firehose until ctx is done
+func (b *BlueskyFirehose) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		backoff := time.Second
+		for {
+			b.logger.Info("Connecting to Bluesky firehose", zap.String("jetstream", b.jetstream), zap.Strings("keywords", b.keywords))
+			connectedAt := time.Now()
+			err := b.receive(ctx, items)
+			if ctx.Err() != nil {
+				b.logger.Info("Stopped Bluesky firehose", zap.String("jetstream", b.jetstream))
+				return
+			}
+			if time.Since(connectedAt) > time.Minute {
+				backoff = time.Second
+			}
+			b.logger.Warn("Bluesky firehose disconnected", zap.String("jetstream", b.jetstream), zap.Duration("backoff", backoff), zap.Error(err))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > time.Minute {
+				backoff = time.Minute
+			}
+		}
+	}()
+	return items
+}
+
+func (b *BlueskyFirehose) subscribeURL() (string, error) {
+	u, err := url.Parse(b.jetstream)
+	if err != nil {
+		return "", err
+	}
+	v := u.Query()
+	v.Set("wantedCollections", "app.bsky.feed.post")
+	if b.cursor > 0 {
+		v.Set("cursor", strconv.FormatInt(b.cursor-blueskyRewind.Nanoseconds()/1000, 10))
+	}
+	u.RawQuery = v.Encode()
+	return u.String(), nil
+}
+
+// receive reads the events of one connection, sending the posts that match on
+func (b *BlueskyFirehose) receive(ctx context.Context, items chan<- Item) error {
+	subscribeURL, err := b.subscribeURL()
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, subscribeURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// closing the connection is the only way to interrupt a read
+	received := make(chan struct{})
+	defer close(received)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-received:
+		}
+	}()
+
+	for {
+		var event jetstreamEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			return err
+		}
+		b.cursor = event.TimeUS
+		commit := event.Commit
+		if event.Kind != "commit" || commit == nil || commit.Operation != "create" || commit.Record == nil {
+			continue
+		}
+		item, matches := b.item(event.DID, commit.RKey, commit.Record)
+		if !matches {
+			continue
+		}
+		select {
+		case items <- item:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// item returns the post as an item, if it matches one of the keywords
+func (b *BlueskyFirehose) item(did string, rkey string, post *blueskyPost) (Item, bool) {
+	provenance := &Provenance{Source: "bluesky", Author: did, PostURL: "https://bsky.app/profile/" + did + "/post/" + rkey}
+	if len(b.keywords) > 0 {
+		text := strings.ToLower(post.Text)
+		for _, keyword := range b.keywords {
+			if strings.Contains(text, keyword) {
+				provenance.Query = keyword
+				break
+			}
+		}
+		if provenance.Query == "" {
+			return Item{}, false
+		}
+	}
+	if len(post.Langs) > 0 {
+		provenance.Lang = post.Langs[0]
+	}
+	return Item{Text: blueskyPostText(post), Provenance: provenance}, true
+}
+
+// blueskyPostText spells out the links in a post's text, which Bluesky shortens, and adds the
+// link card's, if it has one
+func blueskyPostText(post *blueskyPost) string {
+	text := post.Text
+	// facets are replaced from the end so the byte offsets of the others still hold
+	facets := append([]blueskyFacet(nil), post.Facets...)
+	sort.Slice(facets, func(i, j int) bool { return facets[i].Index.ByteStart > facets[j].Index.ByteStart })
+	for _, facet := range facets {
+		start, end := facet.Index.ByteStart, facet.Index.ByteEnd
+		if start < 0 || end > len(text) || start >= end {
+			continue
+		}
+		for _, feature := range facet.Features {
+			if feature.Type == "app.bsky.richtext.facet#link" && feature.URI != "" {
+				text = text[:start] + feature.URI + text[end:]
+				break
+			}
+		}
+	}
+	if post.Embed != nil && post.Embed.External != nil && post.Embed.External.URI != "" && !strings.Contains(text, post.Embed.External.URI) {
+		text += " " + post.Embed.External.URI
+	}
+	return text
+}
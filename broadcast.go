@@ -0,0 +1,64 @@
+package main
+
+import "sync"
+
+// broadcastBufferSize is how many resources a subscriber can fall behind before it misses some
+const broadcastBufferSize = 100
+
+// ResourceBroadcaster fans newly stored resources out to the subscribers of the serve mode's
+// real-time APIs; subscribers that don't keep up miss resources rather than slowing harvesting
+type ResourceBroadcaster struct {
+	mutex       sync.Mutex
+	subscribers map[chan *StoredDocument]bool
+}
+
+// NewResourceBroadcaster creates a broadcaster without subscribers
+func NewResourceBroadcaster() *ResourceBroadcaster {
+	result := new(ResourceBroadcaster)
+	result.subscribers = make(map[chan *StoredDocument]bool)
+	return result
+}
+
+// Subscribe returns a channel that receives each resource stored from now on
+func (b *ResourceBroadcaster) Subscribe() chan *StoredDocument {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	subscriber := make(chan *StoredDocument, broadcastBufferSize)
+	b.subscribers[subscriber] = true
+	return subscriber
+}
+
+// Unsubscribe stops sending resources to subscriber and closes it
+func (b *ResourceBroadcaster) Unsubscribe(subscriber chan *StoredDocument) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.subscribers[subscriber] {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// WriteResource implements ResourceWriter
+func (b *ResourceBroadcaster) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	stored := newStoredDocument(resource, document)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- stored:
+		default:
+		}
+	}
+	return nil
+}
+
+// Queued implements QueuedWriter, counting the resources the subscribers haven't taken yet
+func (b *ResourceBroadcaster) Queued() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	queued := 0
+	for subscriber := range b.subscribers {
+		queued += len(subscriber)
+	}
+	return queued
+}
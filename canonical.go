@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// e.g. https://www-example-com.cdn.ampproject.org/c/s/www.example.com/story
+	ampCacheRegEx    = regexp.MustCompile(`^/[a-z](/s)?/(.+)$`)
+	ampPathRegEx     = regexp.MustCompile(`(/amp/?|\.amp(\.html)?|/amp\.html)$`)
+	ampQueryParams   = []string{"amp", "amp_js_v", "outputType", "usqp"}
+	ampQueryValueSet = map[string]bool{"": true, "1": true, "true": true, "amp": true}
+)
+
+// isAMPURL guesses from the URL alone whether it points at an AMP version of a page
+func isAMPURL(u *url.URL) bool {
+	if strings.HasSuffix(u.Host, ".cdn.ampproject.org") || ampPathRegEx.MatchString(u.Path) {
+		return true
+	}
+	query := u.Query()
+	for _, param := range ampQueryParams {
+		if values, found := query[param]; found && ampQueryValueSet[strings.ToLower(values[0])] {
+			return true
+		}
+	}
+	return false
+}
+
+// deAMPURL undoes the usual ways publishers and the AMP cache turn an article URL into its AMP
+// version; it's the fallback for AMP pages that don't declare their canonical URL
+func deAMPURL(u *url.URL) *url.URL {
+	result := *u
+	if strings.HasSuffix(result.Host, ".cdn.ampproject.org") {
+		if match := ampCacheRegEx.FindStringSubmatch(result.Path); match != nil {
+			scheme := "http"
+			if match[1] != "" {
+				scheme = "https"
+			}
+			if original, err := url.Parse(scheme + "://" + match[2]); err == nil {
+				result = *original
+			}
+		}
+	}
+	result.Path = ampPathRegEx.ReplaceAllString(result.Path, "")
+	if result.Path == "" {
+		result.Path = "/"
+	}
+	query := result.Query()
+	for _, param := range ampQueryParams {
+		query.Del(param)
+	}
+	result.RawQuery = query.Encode()
+	return &result
+}
+
+// canonicalURL returns the canonical URL of the page, reversing AMP pages back to the article
+// they were generated from, and whether the page is an AMP one; nil if it isn't HTML
+func canonicalURL(page *FetchedPage) (*url.URL, bool) {
+	doc, err := page.Document()
+	if err != nil {
+		return nil, false
+	}
+	isAMP := isAMPURL(page.URL) || doc.Find("html[amp], html[⚡]").Length() > 0
+	if href, found := doc.Find(`link[rel="canonical"]`).First().Attr("href"); found {
+		if link, err := page.URL.Parse(strings.TrimSpace(href)); err == nil {
+			return link, isAMP
+		}
+	}
+	if isAMP {
+		return deAMPURL(page.URL), true
+	}
+	return page.URL, false
+}
+
+// ResolveCanonicalURLs makes the storage fetch each destination before storing it and store it
+// under its canonical URL: that's the cleanedURL, and what slugs and dedupe go by
+func (storage *HarvestedResourceStorage) ResolveCanonicalURLs() {
+	storage.resolveCanonical = true
+}
+
+// canonicalEnricher records the canonical URL of the destination, and its AMP version
+type canonicalEnricher struct{}
+
+func (canonicalEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	canonical, isAMP := canonicalURL(page)
+	if canonical == nil {
+		return
+	}
+	doc, _ := page.Document()
+
+	if isAMP {
+		resource.FrontMatter["ampURL"] = page.URL.String()
+	} else if href, found := doc.Find(`link[rel="amphtml"]`).First().Attr("href"); found {
+		if link, err := page.URL.Parse(strings.TrimSpace(href)); err == nil {
+			resource.FrontMatter["ampURL"] = link.String()
+		}
+	}
+	resource.FrontMatter["canonicalURL"] = canonical.String()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+)
+
+// ExcludeCategories makes the storage skip resources whose destination is on a domain in one
+// of the excluded categories
+func (storage *HarvestedResourceStorage) ExcludeCategories(categories *filter.DomainCategories, excluded []string) {
+	storage.domainCategories = categories
+	storage.excludedCategories = make(map[string]bool)
+	for _, categoryList := range excluded {
+		for _, category := range strings.Split(categoryList, ",") {
+			if category = strings.ToLower(strings.TrimSpace(category)); category != "" {
+				storage.excludedCategories[category] = true
+			}
+		}
+	}
+}
+
+// ignoreCategory returns true, with a reason, if destination is on a domain of an excluded category
+func (storage *HarvestedResourceStorage) ignoreCategory(destination *url.URL) (bool, string) {
+	if storage.domainCategories == nil || destination == nil {
+		return false, ""
+	}
+	category := storage.domainCategories.Category(destination.Hostname())
+	if storage.excludedCategories[category] {
+		return true, fmt.Sprintf("Domain `%s` is in the excluded category %s", destination.Hostname(), category)
+	}
+	return false, ""
+}
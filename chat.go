@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// chatMessageParts are what Slack and Discord messages are made of
+type chatMessageParts struct {
+	Title  string
+	URL    string
+	Author string
+	Query  string
+	Text   string
+}
+
+func newChatMessageParts(record *ResourceRecord) *chatMessageParts {
+	result := new(chatMessageParts)
+	result.URL = record.CleanedURL
+	if canonical, _ := record.FrontMatter["canonicalURL"].(string); canonical != "" {
+		result.URL = canonical
+	}
+	result.Title, _ = record.FrontMatter["title"].(string)
+	if result.Title == "" {
+		result.Title = result.URL
+	}
+	if record.Author != "" {
+		result.Author = "@" + record.Author
+	}
+	for _, source := range []string{record.Query, record.Timeline, record.List} {
+		if source != "" {
+			result.Query = source
+			break
+		}
+	}
+	result.Text = removeNewLinesRegEx.ReplaceAllString(record.Text, " ")
+	return result
+}
+
+// footer is the "by @author · query" line
+func (parts *chatMessageParts) footer() string {
+	var footer []string
+	if parts.Author != "" {
+		footer = append(footer, "by "+parts.Author)
+	}
+	if parts.Query != "" {
+		footer = append(footer, parts.Query)
+	}
+	return strings.Join(footer, " · ")
+}
+
+// slackEscaper escapes the characters Slack's mrkdwn treats as control characters
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func formatSlackMessage(event *webhookEvent) ([]byte, error) {
+	parts := newChatMessageParts(event.Resource)
+	text := fmt.Sprintf("*<%s|%s>*", parts.URL, slackEscaper.Replace(parts.Title))
+	if parts.Text != "" {
+		text += "\n>" + slackEscaper.Replace(parts.Text)
+	}
+	if footer := parts.footer(); footer != "" {
+		text += "\n_" + slackEscaper.Replace(footer) + "_"
+	}
+	return json.Marshal(map[string]interface{}{"text": text, "unfurl_links": false})
+}
+
+func formatDiscordMessage(event *webhookEvent) ([]byte, error) {
+	parts := newChatMessageParts(event.Resource)
+	embed := map[string]interface{}{
+		"title":       parts.Title,
+		"url":         parts.URL,
+		"description": parts.Text,
+		"timestamp":   event.Resource.HarvestedAt.Format(time.RFC3339),
+	}
+	if parts.Author != "" {
+		embed["author"] = map[string]string{"name": parts.Author}
+	}
+	if parts.Query != "" {
+		embed["footer"] = map[string]string{"text": parts.Query}
+	}
+	return json.Marshal(map[string]interface{}{"embeds": []interface{}{embed}})
+}
+
+// NewSlackNotifier posts a message to a Slack incoming webhook for each stored resource whose
+// tweet, URL or title matches match (every resource if it's nil)
+func NewSlackNotifier(logger *zap.Logger, webhookURL string, timeout time.Duration, maxRetries int, match *regexp.Regexp) *WebhookNotifier {
+	result := NewWebhookNotifier(logger, []string{webhookURL}, "", timeout, maxRetries, false)
+	result.format = formatSlackMessage
+	result.match = match
+	return result
+}
+
+// NewDiscordNotifier posts an embed to a Discord webhook for each stored resource whose
+// tweet, URL or title matches match (every resource if it's nil)
+func NewDiscordNotifier(logger *zap.Logger, webhookURL string, timeout time.Duration, maxRetries int, match *regexp.Regexp) *WebhookNotifier {
+	result := NewWebhookNotifier(logger, []string{webhookURL}, "", timeout, maxRetries, false)
+	result.format = formatDiscordMessage
+	result.match = match
+	return result
+}
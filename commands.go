@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// subcommand is one of the harvester's commands, e.g. "harvest stream" or "digest". Commands
+// are shorthands over the one flag set: each turns on its mode's flag (set) and only accepts
+// the flags that matter to it, so -help for a command lists those instead of all of them.
+type subcommand struct {
+	name    string
+	summary string
+	// set are the flags the command implies, unless one of unless is given
+	set    map[string]string
+	unless []string
+	// only are the flags that are the command's alone; other commands don't accept them
+	only []string
+	// harvesting commands take every flag that's not some other command's alone, while the
+	// others only take commonFlags, their own and uses
+	harvesting bool
+	uses       []string
+	// positional says what the command's arguments are: the flag each sets, if any
+	positional string
+}
+
+// commonFlags are accepted by every command
+var commonFlags = []string{
+	"config", "storage-base-path", "storage-compression", "storage-encryption-key", "storage-encryption-key-command",
+	"storage-layout", "manifest", "log-level", "log-format", "log-file", "log-max-size", "log-max-backups", "log-max-age",
+	"otlp-endpoint", "otlp-insecure", "trace-sample-ratio",
+}
+
+var subcommands = []*subcommand{
+	{name: "harvest stream", summary: "Harvest the links in tweets matching -query from Twitter's filter stream, until Ctrl+C is pressed",
+		set: map[string]string{"filter-stream": "true"}, harvesting: true, positional: "query"},
+	{name: "harvest sample", summary: "Harvest the links in tweets from Twitter's sampled stream matching -query and -sample-domain, until Ctrl+C is pressed",
+		set: map[string]string{"sample-stream": "true"}, harvesting: true, positional: "query"},
+	{name: "harvest search", summary: "Harvest the links in tweets matching -query from Twitter's search, once or every -poll-interval",
+		set: map[string]string{"search": "true"}, harvesting: true, positional: "query"},
+	{name: "harvest archive", summary: "Harvest the links in tweets matching -query from Twitter's full archive (v2, Academic or paid access), between -search-start-time and -search-end-time",
+		set: map[string]string{"full-archive-search": "true"}, harvesting: true, positional: "query"},
+	{name: "harvest timeline", summary: "Harvest the links shared in users' timelines (@user arguments)",
+		harvesting: true, positional: "timeline"},
+	{name: "harvest list", summary: "Harvest the links shared by the members of Twitter Lists (owner/slug arguments)",
+		harvesting: true, positional: "list"},
+	{name: "harvest mastodon", summary: "Harvest the links in the statuses streamed by -mastodon-instance, on hashtag (#tag arguments) or public timelines",
+		harvesting: true, positional: "mastodon-hashtag"},
+	{name: "harvest bluesky", summary: "Harvest the links in the Bluesky posts containing keyword arguments, from the firehose",
+		set: map[string]string{"bluesky": "true"}, harvesting: true, positional: "bluesky-keyword"},
+	{name: "harvest reddit", summary: "Harvest the links in the submissions to subreddits (r/name arguments), polled every -reddit-poll-interval",
+		harvesting: true, positional: "subreddit"},
+	{name: "harvest hn", summary: "Harvest the links in the Hacker News stories containing keyword arguments and in their comments",
+		set: map[string]string{"hn": "true"}, harvesting: true, positional: "hn-keyword"},
+	{name: "harvest rss", summary: "Harvest the links in the entries of RSS and Atom feeds (URL arguments), polled every -rss-poll-interval",
+		harvesting: true, positional: "rss-feed"},
+	{name: "harvest telegram", summary: "Harvest the links in the posts of Telegram channels (@username arguments) that -telegram-bot-token's bot is in",
+		harvesting: true, positional: "telegram-channel"},
+	{name: "harvest slack", summary: "Harvest the links shared in a Slack workspace, received from the Events API on -slack-events (:3000 unless given)",
+		set: map[string]string{"slack-events": ":3000"}, harvesting: true, positional: "slack-events"},
+	{name: "harvest input", summary: "Harvest the links in lines of text, or of tweets' JSON, read from files (arguments) or stdin, without Twitter",
+		set: map[string]string{"input": "-"}, harvesting: true, positional: "input"},
+	{name: "import-archive", summary: "Harvest every link in a Twitter account archive (the zip, its directory or tweets.js argument) into the store",
+		harvesting: true, positional: "import-archive"},
+	{name: "replay", summary: "Harvest the tweets retained with -retain-raw-tweets again, e.g. after changing the clean rules or enrichment",
+		set: map[string]string{"replay": "true"}, harvesting: true},
+	{name: "serve", summary: "Serve the GraphQL, REST and gRPC APIs over the store",
+		set: map[string]string{"serve": "localhost:8080"}, unless: []string{"serve-grpc"},
+		uses: []string{"serve", "serve-grpc", "admin-token", "pprof", "stale-stream-after", "search-index", "output-format"}, positional: "serve"},
+	{name: "export", summary: "Print the most recent resources in the store as an rss (the default) or atom feed",
+		set:  map[string]string{"feed": "rss"},
+		only: []string{"feed", "feed-items", "feed-title", "feed-link"}, positional: "feed"},
+	{name: "digest", summary: "Print a daily or weekly markdown digest of the store",
+		set:  map[string]string{"digest": "daily"},
+		only: []string{"digest", "digest-top", "digest-skip-paywalled"}, positional: "digest"},
+	{name: "prune", summary: "Delete or archive the resources older than -retention-days",
+		set:  map[string]string{"prune": "true"},
+		only: []string{"prune", "prune-archive-dir", "prune-compress"}, uses: []string{"retention-days"}},
+	{name: "verify", summary: "Check every document in the store against its integrity hashes",
+		set: map[string]string{"verify-storage": "true"}, only: []string{"verify-storage"}},
+	{name: "find", summary: "Print the resources in -search-index matching a Bleve query string",
+		only: []string{"find", "find-results"}, uses: []string{"search-index"}, positional: "find"},
+	{name: "reindex", summary: "Add every document in the store to -search-index",
+		set: map[string]string{"reindex": "true"}, only: []string{"reindex"}, uses: []string{"search-index"}},
+}
+
+// modeFlags are given by the harvest commands rather than as flags
+var modeFlags = []string{"filter-stream", "sample-stream", "search", "full-archive-search", "bluesky", "hn", "replay"}
+
+// findSubcommand returns the command args start with and the rest of args
+func findSubcommand(args []string) (*subcommand, []string) {
+	for _, command := range subcommands {
+		words := strings.Fields(command.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == command.name {
+			return command, args[len(words):]
+		}
+	}
+	return nil, args
+}
+
+// accepts is true if name is one of the command's flags
+func (c *subcommand) accepts(name string) bool {
+	for _, flags := range [][]string{commonFlags, c.only, c.uses} {
+		for _, flag := range flags {
+			if flag == name {
+				return true
+			}
+		}
+	}
+	if !c.harvesting {
+		return false
+	}
+	for _, flag := range modeFlags {
+		if flag == name {
+			return false
+		}
+	}
+	for _, command := range subcommands {
+		if command == c {
+			continue
+		}
+		for _, flag := range command.only {
+			if flag == name {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, command := range subcommands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -help for the command's flags. Flags without a command run the harvester in the mode they select, as they always have.\n", os.Args[0])
+}
+
+// flagsInEnv is true if one of the flags is set in the environment, as flagutil.SetFlagsFromEnv
+// reads them: PREFIX_FLAG_NAME
+func flagsInEnv(flags *flag.FlagSet, prefix string) bool {
+	found := false
+	flags.VisitAll(func(f *flag.Flag) {
+		name := prefix + "_" + strings.ToUpper(strings.Replace(f.Name, "-", "_", -1))
+		if _, set := os.LookupEnv(name); set {
+			found = true
+		}
+	})
+	return found
+}
+
+// parseCommandLine parses args into flags, running the command they start with if there is
+// one; flags given without a command work like they always have
+func parseCommandLine(flags *flag.FlagSet, args []string) {
+	if len(args) == 0 && flagsInEnv(flags, "TWITTER") {
+		// configured through the environment, which is applied after the command line
+		flags.Parse(args)
+		return
+	}
+	if len(args) == 0 || args[0] == "help" {
+		printCommands()
+		os.Exit(2)
+	}
+	command, rest := findSubcommand(args)
+	if command == nil {
+		if !strings.HasPrefix(args[0], "-") {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.Join(args, " "))
+			printCommands()
+			os.Exit(2)
+		}
+		flags.Parse(args)
+		return
+	}
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags]", os.Args[0], command.name)
+		if command.positional != "" {
+			fmt.Fprintf(os.Stderr, " [%s...]", command.positional)
+		}
+		fmt.Fprintf(os.Stderr, "\n\n%s.\n\nFlags:\n", command.summary)
+		var names []string
+		flags.VisitAll(func(f *flag.Flag) {
+			if command.accepts(f.Name) && command.set[f.Name] == "" {
+				names = append(names, f.Name)
+			}
+		})
+		sort.Strings(names)
+		for _, name := range names {
+			f := flags.Lookup(name)
+			fmt.Fprintf(os.Stderr, "  -%s\n    \t%s (default %q)\n", f.Name, f.Usage, f.DefValue)
+		}
+	}
+	flags.Parse(rest)
+
+	var refused []string
+	flags.Visit(func(f *flag.Flag) {
+		if !command.accepts(f.Name) {
+			refused = append(refused, "-"+f.Name)
+		}
+	})
+	if len(refused) > 0 {
+		fmt.Fprintf(os.Stderr, "%s doesn't take %s\n\n", command.name, strings.Join(refused, ", "))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	for _, arg := range flags.Args() {
+		if command.positional == "" {
+			fmt.Fprintf(os.Stderr, "%s takes no arguments, got %q\n", command.name, arg)
+			os.Exit(2)
+		}
+		if err := flags.Set(command.positional, arg); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid %s %q: %v\n", command.positional, arg, err)
+			os.Exit(2)
+		}
+	}
+	// arguments count as given, so a command's default only applies without them
+	given := setOnCommandLine(flags)
+	implied := true
+	for _, name := range command.unless {
+		implied = implied && !given[name]
+	}
+	for name, value := range command.set {
+		if implied && !given[name] {
+			flags.Set(name, value)
+		}
+	}
+}
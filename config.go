@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+	"github.com/shah/content-harvester-utils"
+	"go.uber.org/zap"
+)
+
+// ruleFilesCheckInterval is how often the rule files are checked for changes
+const ruleFilesCheckInterval = 5 * time.Second
+
+// configFileLine is one "name = value" setting of a -config file
+type configFileLine struct {
+	name  string
+	value string
+}
+
+// readConfigFile reads the settings of a -config file: a flag name and its value per line
+// (name = value), lines for list flags repeated to give several values
+func readConfigFile(path string) ([]configFileLine, error) {
+	lines, err := filter.ReadListFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []configFileLine
+	for _, line := range lines {
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("%s: %q should be name = value", path, line)
+		}
+		result = append(result, configFileLine{
+			name:  strings.TrimSpace(line[:separator]),
+			value: strings.TrimSpace(line[separator+1:]),
+		})
+	}
+	return result, nil
+}
+
+// setOnCommandLine is the names of the flags given explicitly to flags
+func setOnCommandLine(flags *flag.FlagSet) map[string]bool {
+	result := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { result[f.Name] = true })
+	return result
+}
+
+// applyConfigFile sets the flags in the -config file at path, except the ones given on the
+// command line, which take precedence
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	lines, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	explicit := setOnCommandLine(flags)
+	for _, line := range lines {
+		if explicit[line.name] {
+			continue
+		}
+		if err := flags.Set(line.name, line.value); err != nil {
+			return fmt.Errorf("%s: %s: %v", path, line.name, err)
+		}
+	}
+	return nil
+}
+
+// withDefaultURLRules fills in the ignore and clean rules used when none are configured
+func withDefaultURLRules(ignoreURLsRegEx ignoreURLsRegExList, removeParamsFromURLsRegEx cleanURLsRegExList) (ignoreURLsRegExList, cleanURLsRegExList) {
+	if len(ignoreURLsRegEx) == 0 {
+		ignoreURLsRegEx = []*regexp.Regexp{regexp.MustCompile(`^https://twitter.com/(.*?)/status/(.*)$`), regexp.MustCompile(`https://t.co`)}
+	}
+	if len(removeParamsFromURLsRegEx) == 0 {
+		removeParamsFromURLsRegEx = []*regexp.Regexp{regexp.MustCompile(`^utm_`)}
+	}
+	return ignoreURLsRegEx, removeParamsFromURLsRegEx
+}
+
+// ReloadableConfig is the part of the configuration a running harvester picks up again from
+// its -config file on SIGHUP: the ignore and clean rules and the output settings
+type ReloadableConfig struct {
+	IgnoreURLsRegEx           ignoreURLsRegExList
+	RemoveParamsFromURLsRegEx cleanURLsRegExList
+	IgnoreURLsFile            string
+	CleanParamsFile           string
+	OutputFormat              string
+	FrontMatterTemplate       string
+}
+
+// register defines the reloadable flags on flags, bound to config
+func (config *ReloadableConfig) register(flags *flag.FlagSet) {
+	flags.Var(&config.IgnoreURLsRegEx, "ignore-urls-reg-ex", "Regular expression indicating which URL patterns to not harvest")
+	flags.Var(&config.RemoveParamsFromURLsRegEx, "remove-params-from-urls-reg-ex", "Regular expression indicating which URL query params to 'clean' in harvested URLs")
+	flags.StringVar(&config.IgnoreURLsFile, "ignore-urls-file", "", "File of ignore-urls-reg-ex patterns, one per line (# for comments), reloaded when it changes")
+	flags.StringVar(&config.CleanParamsFile, "clean-params-file", "", "File of remove-params-from-urls-reg-ex patterns, one per line (# for comments), reloaded when it changes")
+	flags.StringVar(&config.OutputFormat, "output-format", "diskv", "How to lay out stored resources: diskv (flat files keyed by slug), hugo (page bundles) or obsidian (a vault of linked notes); hugo and obsidian are exports that retention and the manifest don't cover")
+	flags.StringVar(&config.FrontMatterTemplate, "frontmatter-template", "", "Go template file to write documents with instead of the default serialization (see DocumentTemplateData)")
+}
+
+// urlRules combines the ignore and clean rules given as flags with the ones in the rule files,
+// falling back on the default rules
+func (config *ReloadableConfig) urlRules() (ignoreURLsRegExList, cleanURLsRegExList, error) {
+	ignoreURLsRegEx := append(ignoreURLsRegExList(nil), config.IgnoreURLsRegEx...)
+	removeParamsFromURLsRegEx := append(cleanURLsRegExList(nil), config.RemoveParamsFromURLsRegEx...)
+	if config.IgnoreURLsFile != "" {
+		patterns, err := readRuleFile(config.IgnoreURLsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		ignoreURLsRegEx = append(ignoreURLsRegEx, patterns...)
+	}
+	if config.CleanParamsFile != "" {
+		patterns, err := readRuleFile(config.CleanParamsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		removeParamsFromURLsRegEx = append(removeParamsFromURLsRegEx, patterns...)
+	}
+	ignoreURLsRegEx, removeParamsFromURLsRegEx = withDefaultURLRules(ignoreURLsRegEx, removeParamsFromURLsRegEx)
+	return ignoreURLsRegEx, removeParamsFromURLsRegEx, nil
+}
+
+// readRuleFile compiles the regular expressions in path, one per line
+func readRuleFile(path string) ([]*regexp.Regexp, error) {
+	patterns, err := filter.ReadListFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []*regexp.Regexp
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		result = append(result, compiled)
+	}
+	return result, nil
+}
+
+// ruleFiles returns the rule files config reads
+func (config *ReloadableConfig) ruleFiles() []string {
+	var files []string
+	for _, file := range []string{config.IgnoreURLsFile, config.CleanParamsFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// ConfigReloader re-reads a -config file and the rule files and applies the reloadable
+// settings to storage; settings given on the command line still win
+type ConfigReloader struct {
+	path        string
+	storage     *HarvestedResourceStorage
+	logger      *zap.Logger
+	commandLine *ReloadableConfig
+	explicit    map[string]bool
+	mutex       sync.Mutex
+	ruleFiles   []string
+}
+
+// NewConfigReloader reloads the config file at path, if there's one; commandLine has the
+// reloadable settings as the command line left them, explicit the names of the flags it set
+func NewConfigReloader(path string, storage *HarvestedResourceStorage, logger *zap.Logger, commandLine *ReloadableConfig, explicit map[string]bool, config *ReloadableConfig) *ConfigReloader {
+	result := new(ConfigReloader)
+	result.path = path
+	result.storage = storage
+	result.logger = logger
+	result.commandLine = commandLine
+	result.explicit = explicit
+	result.ruleFiles = config.ruleFiles()
+	return result
+}
+
+// Reload reads the config file and the rule files again and reconfigures the storage with them
+func (r *ConfigReloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	config := new(ReloadableConfig)
+	if r.path == "" {
+		*config = *r.commandLine
+	} else if err := r.readConfigFile(config); err != nil {
+		return err
+	}
+
+	ignoreURLsRegEx, removeParamsFromURLsRegEx, err := config.urlRules()
+	if err != nil {
+		return err
+	}
+	contentHarvester := harvester.MakeContentHarvester(r.logger, ignoreURLsRegEx, removeParamsFromURLsRegEx, true)
+	if err := r.storage.Reconfigure(contentHarvester, config.OutputFormat, config.FrontMatterTemplate); err != nil {
+		return err
+	}
+	r.ruleFiles = config.ruleFiles()
+	return nil
+}
+
+// WatchRuleFiles reloads whenever one of the rule files changes, checking every interval
+func (r *ConfigReloader) WatchRuleFiles(interval time.Duration) {
+	modified := make(map[string]time.Time)
+	for {
+		r.mutex.Lock()
+		files := r.ruleFiles
+		r.mutex.Unlock()
+
+		changed := false
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			if last, seen := modified[file]; seen && !info.ModTime().Equal(last) {
+				changed = true
+			}
+			modified[file] = info.ModTime()
+		}
+		if changed {
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Unable to reload rule files", zap.Strings("files", files), zap.Error(err))
+			} else {
+				r.logger.Info("Reloaded rule files", zap.Strings("files", files))
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// readConfigFile sets config from the config file, keeping the settings of the command line
+func (r *ConfigReloader) readConfigFile(config *ReloadableConfig) error {
+	lines, err := readConfigFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	config.register(flags)
+	for _, line := range lines {
+		if r.explicit[line.name] || flags.Lookup(line.name) == nil {
+			continue
+		}
+		if err := flags.Set(line.name, line.value); err != nil {
+			return fmt.Errorf("%s: %s: %v", r.path, line.name, err)
+		}
+	}
+	if r.explicit["ignore-urls-reg-ex"] {
+		config.IgnoreURLsRegEx = r.commandLine.IgnoreURLsRegEx
+	}
+	if r.explicit["remove-params-from-urls-reg-ex"] {
+		config.RemoveParamsFromURLsRegEx = r.commandLine.RemoveParamsFromURLsRegEx
+	}
+	if r.explicit["output-format"] {
+		config.OutputFormat = r.commandLine.OutputFormat
+	}
+	if r.explicit["frontmatter-template"] {
+		config.FrontMatterTemplate = r.commandLine.FrontMatterTemplate
+	}
+	if r.explicit["ignore-urls-file"] {
+		config.IgnoreURLsFile = r.commandLine.IgnoreURLsFile
+	}
+	if r.explicit["clean-params-file"] {
+		config.CleanParamsFile = r.commandLine.CleanParamsFile
+	}
+	return nil
+}
+
+// Reconfigure switches the storage to another content harvester, output format and document
+// template (none if it's empty) between two texts being saved
+func (storage *HarvestedResourceStorage) Reconfigure(contentHarvester *harvester.ContentHarvester, outputFormat string, frontMatterTemplate string) error {
+	storage.saving.Lock()
+	defer storage.saving.Unlock()
+
+	writer, err := storage.outputWriter(outputFormat)
+	if err != nil {
+		return err
+	}
+	var documentTemplate *template.Template
+	if frontMatterTemplate != "" {
+		if documentTemplate, err = parseDocumentTemplate(frontMatterTemplate); err != nil {
+			return err
+		}
+	}
+	// nothing changes until everything's known to work, so a failed reload leaves the storage as it was
+	storage.useWriter(outputFormat, writer)
+	storage.documentTemplate = documentTemplate
+	storage.contentHarvester = contentHarvester
+	return nil
+}
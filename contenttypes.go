@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// contentTypeList is a comma separated list of media types, which may end in /* to match any
+// subtype (e.g. video/*)
+type contentTypeList []string
+
+func (l *contentTypeList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *contentTypeList) Set(value string) error {
+	for _, contentType := range strings.Split(value, ",") {
+		if contentType = strings.ToLower(strings.TrimSpace(contentType)); contentType != "" {
+			*l = append(*l, contentType)
+		}
+	}
+	return nil
+}
+
+func (l contentTypeList) matches(contentType string) bool {
+	for _, pattern := range l {
+		if pattern == contentType || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentTypeFilter decides which destinations are worth storing based on their media type
+type ContentTypeFilter struct {
+	fetcher *PageFetcher
+	allow   contentTypeList
+	deny    contentTypeList
+}
+
+// IgnoreDestination asks the destination for its content type and returns true, with a reason,
+// if it's denied or not allowed
+func (f *ContentTypeFilter) IgnoreDestination(destination *url.URL) (bool, string) {
+	contentType, err := f.fetcher.ContentType(destination)
+	if err != nil {
+		if len(f.allow) > 0 {
+			return true, fmt.Sprintf("Unknown content type (%v)", err)
+		}
+		return false, ""
+	}
+	if f.deny.matches(contentType) {
+		return true, fmt.Sprintf("Content type `%s` is denied", contentType)
+	}
+	if len(f.allow) > 0 && !f.allow.matches(contentType) {
+		return true, fmt.Sprintf("Content type `%s` is not allowed", contentType)
+	}
+	return false, ""
+}
+
+// ContentType asks for the media type of destination without downloading it, falling back to a
+// GET that's abandoned after the headers for servers that don't handle HEAD
+func (f *PageFetcher) ContentType(destination *url.URL) (string, error) {
+	resp, err := f.client.Head(destination.String())
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		resp, err = f.client.Get(destination.String())
+	}
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("%s has no usable Content-Type: %v", destination, err)
+	}
+	return mediaType, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shah/content-harvester-utils"
+)
+
+var csvHeader = []string{"time", "text", "originalURL", "status", "reason", "referredBy", "finalURL", "resolvedURL", "cleanedURL"}
+
+// harvestedResourceRows has a CSV row for each resource found in text, including the invalid
+// and ignored ones along with why; reasons says why URLs were invalid, if that's known
+func harvestedResourceRows(tweet string, resources []*harvester.HarvestedResource, reasons invalidURLReasons) [][]string {
+	time := time.Now().Format("01-02 15:04:05")
+	tweetText := removeNewLinesRegEx.ReplaceAllString(tweet, " ")
+	var rows [][]string
+	for _, res := range resources {
+		isURLValid, isDestValid := res.IsValid()
+		if !isURLValid {
+			reason, known := reasons[res]
+			if !known {
+				reason = "Not sure why"
+			}
+			rows = append(rows, []string{time, tweetText, res.OriginalURLText(), "Invalid URL", reason})
+			continue
+		}
+		if !isDestValid {
+			isIgnored, ignoreReason := res.IsIgnored()
+			if isIgnored {
+				rows = append(rows, []string{time, tweetText, res.OriginalURLText(), "Invalid URL Destination", ignoreReason})
+			} else {
+				rows = append(rows, []string{time, tweetText, res.OriginalURLText(), "Invalid URL Destination", "Unknown reason"})
+			}
+			continue
+		}
+		finalURL, resolvedURL, cleanedURL := res.GetURLs()
+		isIgnored, ignoreReason := res.IsIgnored()
+		if isIgnored {
+			rows = append(rows, []string{time, tweetText, res.OriginalURLText(), "Ignored", ignoreReason, resourceToString(res.ReferredByResource()), urlToString(finalURL), urlToString(resolvedURL)})
+			continue
+		}
+
+		rows = append(rows, []string{time, tweetText, res.OriginalURLText(), "Resolved", "Success", resourceToString(res.ReferredByResource()), urlToString(finalURL), urlToString(resolvedURL), urlToString(cleanedURL)})
+	}
+	return rows
+}
+
+// CSVHarvestLog streams a row per harvested resource, in createTweetTestData's format, to a CSV file
+type CSVHarvestLog struct {
+	mutex  sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+// NewCSVHarvestLog appends to the CSV file at path, starting it with a header row if it's new
+func NewCSVHarvestLog(path string) (*CSVHarvestLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	result := new(CSVHarvestLog)
+	result.file = file
+	result.writer = csv.NewWriter(file)
+	if info.Size() == 0 {
+		result.writer.Write(csvHeader)
+		result.writer.Flush()
+	}
+	return result, nil
+}
+
+// Write adds the rows for the resources harvested from text
+func (l *CSVHarvestLog) Write(text string, resources []*harvester.HarvestedResource, reasons invalidURLReasons) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, row := range harvestedResourceRows(text, resources, reasons) {
+		// pad short rows so every record has the header's number of fields
+		for len(row) < len(csvHeader) {
+			row = append(row, "")
+		}
+		l.writer.Write(row)
+	}
+	l.writer.Flush()
+	return l.writer.Error()
+}
+
+// Close flushes and closes the CSV file
+func (l *CSVHarvestLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.writer.Flush()
+	return l.file.Close()
+}
+
+// LogHarvestsTo makes the storage write a CSV row for each resource it harvests, whether or
+// not it ends up being stored
+func (storage *HarvestedResourceStorage) LogHarvestsTo(log *CSVHarvestLog) {
+	storage.csvLog = log
+}
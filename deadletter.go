@@ -0,0 +1,68 @@
+package main
+
+import (
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/store"
+	"go.uber.org/zap"
+)
+
+// writeRetryBackoff is how long the first retry of a failed write waits; it doubles each time
+const writeRetryBackoff = 100 * time.Millisecond
+
+// writeWithRetries writes the resource with writer, retrying transient failures up to retries
+// times with exponential backoff; it returns how many attempts it took
+func writeWithRetries(writer ResourceWriter, resource *DocumentTemplateData, document string, attachments []Attachment, retries int) (int, error) {
+	backoff := writeRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := writer.WriteResource(resource, document, attachments)
+		if err == nil || attempt > retries || !store.TransientWriteError(err) {
+			return attempt, err
+		}
+		storageWriteRetriesCounter.Inc()
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// RetryWrites makes the storage retry transient write failures up to retries times and keep
+// what still couldn't be stored in deadLetterDir, if it's not empty
+func (storage *HarvestedResourceStorage) RetryWrites(retries int, deadLetterDir string) {
+	storage.writeRetries = retries
+	if deadLetterDir != "" {
+		storage.deadLetters = store.NewDeadLetters(deadLetterDir)
+	}
+}
+
+// storeFailed counts, logs and dead-letters a resource that couldn't be composed ("compose"
+// stage) or written ("write" stage)
+func (storage *HarvestedResourceStorage) storeFailed(resource *DocumentTemplateData, document string, attachments []Attachment, stage string, attempts int, cause error) {
+	storage.slugs.release(resource.Slug)
+	storageWriteErrorsCounter.Inc()
+	storageDeadLettersCounter.WithLabelValues(stage).Inc()
+	storage.logger.Error("Unable to store resource", zap.String("slug", resource.Slug), zap.String("stage", stage),
+		zap.Int("attempts", attempts), zap.Error(cause))
+	if storage.deadLetters == nil {
+		return
+	}
+	if document == "" {
+		document = resource.Serialized
+	}
+	files := make(map[string][]byte)
+	for _, attachment := range attachments {
+		files[attachment.Key] = attachment.Data
+	}
+	letter := &store.DeadLetter{
+		Slug:        resource.Slug,
+		Stage:       stage,
+		Error:       cause.Error(),
+		Attempts:    attempts,
+		FailedAt:    time.Now(),
+		OriginalURL: resource.OriginalURL,
+		FinalURL:    resource.FinalURL,
+		Text:        resource.Text,
+	}
+	if err := storage.deadLetters.Keep(letter, document, files); err != nil {
+		storage.logger.Error("Unable to dead-letter resource", zap.String("slug", resource.Slug), zap.Error(err))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// seenResourcesIndexFile is where -dedupe keeps its index, in the storage directory
+const seenResourcesIndexFile = ".seen-urls.json"
+
+// SeenResource is what we remember about a resource that's already been stored
+type SeenResource struct {
+	Slug string `json:"slug"`
+	Hits int    `json:"hits"`
+}
+
+// SeenResourcesIndex keeps track of the cleaned URLs already stored so that repeated links are
+// counted instead of being written again; it's kept in memory and mirrored to a JSON file
+type SeenResourcesIndex struct {
+	path      string
+	mutex     sync.Mutex
+	resources map[string]*SeenResource
+	dirty     bool
+}
+
+// NewSeenResourcesIndex loads (or starts) the index stored at path
+func NewSeenResourcesIndex(path string) (*SeenResourcesIndex, error) {
+	result := new(SeenResourcesIndex)
+	result.path = path
+	result.resources = make(map[string]*SeenResource)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &result.resources); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Hit counts another harvest of url and returns its index entry, along with true if it's in the
+// index; URLs that aren't are left out until they're stored and added
+func (index *SeenResourcesIndex) Hit(url string) (*SeenResource, bool) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	seen, found := index.resources[url]
+	if !found {
+		return nil, false
+	}
+	seen.Hits++
+	index.dirty = true
+	return seen, true
+}
+
+// Add records that url was stored under slug
+func (index *SeenResourcesIndex) Add(url string, slug string) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if _, found := index.resources[url]; !found {
+		index.resources[url] = &SeenResource{Slug: slug, Hits: 1}
+		index.dirty = true
+	}
+}
+
+// Hits returns how many times url was harvested, or 0 if it isn't in the index
+func (index *SeenResourcesIndex) Hits(url string) int {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if seen, found := index.resources[url]; found {
+		return seen.Hits
+	}
+	return 0
+}
+
+// Len returns how many URLs are in the index, along with a rough idea of the memory their
+// entries take
+func (index *SeenResourcesIndex) Len() (int, int) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	bytes := 0
+	for url, seen := range index.resources {
+		// the key and slug strings plus the map entry, pointer and SeenResource themselves
+		bytes += len(url) + len(seen.Slug) + 64
+	}
+	return len(index.resources), bytes
+}
+
+// Save writes the index to disk if anything changed since the last save
+func (index *SeenResourcesIndex) Save() error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if !index.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(index.resources, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(index.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := index.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, index.path); err != nil {
+		return err
+	}
+	index.dirty = false
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// QueuedWriter is implemented by the outputs that hold on to resources before sending them on
+type QueuedWriter interface {
+	Queued() int
+}
+
+type queueStats struct {
+	Output string `json:"output"`
+	Queued int    `json:"queued"`
+}
+
+type debugStats struct {
+	Goroutines int          `json:"goroutines"`
+	Uptime     string       `json:"uptime"`
+	Queues     []queueStats `json:"queues"`
+	Dedupe     struct {
+		IndexedURLs int `json:"indexedURLs"`
+		IndexBytes  int `json:"indexBytes"`
+		FilterBytes int `json:"filterBytes"`
+	} `json:"dedupe"`
+	Memory struct {
+		HeapAlloc  uint64 `json:"heapAlloc"`
+		HeapInuse  uint64 `json:"heapInuse"`
+		Sys        uint64 `json:"sys"`
+		NumGC      uint32 `json:"numGC"`
+		PauseTotal string `json:"pauseTotal"`
+	} `json:"memory"`
+}
+
+// startedAt is when the process started, for the uptime in /debug/stats
+var startedAt = time.Now()
+
+// ServeProfiles adds net/http/pprof's profiles under /debug/pprof/, behind the admin token; the
+// profiles give away the command line, so they're never served without one
+func (s *HarvesterServer) ServeProfiles() {
+	if s.adminToken == "" {
+		s.logger.Warn("Not serving profiles without an admin token")
+		return
+	}
+	s.router.Handle("/debug/pprof/cmdline", s.requireAdmin(pprof.Cmdline))
+	s.router.Handle("/debug/pprof/profile", s.requireAdmin(pprof.Profile))
+	s.router.Handle("/debug/pprof/symbol", s.requireAdmin(pprof.Symbol))
+	s.router.Handle("/debug/pprof/trace", s.requireAdmin(pprof.Trace))
+	s.router.PathPrefix("/debug/pprof/").Handler(s.requireAdmin(pprof.Index))
+}
+
+// routeDebug adds /debug/stats, a JSON report of goroutines, output queue depths and memory use
+func (s *HarvesterServer) routeDebug() {
+	s.router.Handle("/debug/stats", s.requireAdmin(s.debugStats)).Methods(http.MethodGet)
+}
+
+func (s *HarvesterServer) debugStats(w http.ResponseWriter, r *http.Request) {
+	stats := new(debugStats)
+	stats.Goroutines = runtime.NumGoroutine()
+	stats.Uptime = time.Since(startedAt).Round(time.Second).String()
+	stats.Queues = []queueStats{}
+	if s.storage.asyncWriter != nil {
+		stats.Queues = append(stats.Queues, queueStats{Output: "storage", Queued: s.storage.asyncWriter.Queued()})
+	}
+	for _, output := range s.storage.outputs {
+		if queued, ok := output.(QueuedWriter); ok {
+			stats.Queues = append(stats.Queues, queueStats{
+				Output: strings.TrimPrefix(fmt.Sprintf("%T", output), "*main."),
+				Queued: queued.Queued(),
+			})
+		}
+	}
+	if s.storage.seen != nil {
+		stats.Dedupe.IndexedURLs, stats.Dedupe.IndexBytes = s.storage.seen.Len()
+	}
+	if s.storage.seenBefore != nil {
+		stats.Dedupe.FilterBytes = s.storage.seenBefore.Size()
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	stats.Memory.HeapAlloc = memory.HeapAlloc
+	stats.Memory.HeapInuse = memory.HeapInuse
+	stats.Memory.Sys = memory.Sys
+	stats.Memory.NumGC = memory.NumGC
+	stats.Memory.PauseTotal = time.Duration(memory.PauseTotalNs).String()
+	s.writeJSON(w, http.StatusOK, stats)
+}
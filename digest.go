@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestCount is one line of a digest's top N lists
+type digestCount struct {
+	Name  string
+	Label string
+	Count int
+}
+
+// topCounts sorts counts, most frequent first (alphabetically for ties), and keeps the top n
+func topCounts(counts map[string]*digestCount, n int) []*digestCount {
+	var result []*digestCount
+	for _, count := range counts {
+		result = append(result, count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+func incrementCount(counts map[string]*digestCount, name string, label string, by int) {
+	count, found := counts[name]
+	if !found {
+		count = &digestCount{Name: name, Label: label}
+		counts[name] = count
+	}
+	count.Count += by
+}
+
+// RenderDigest writes a markdown report of the resources harvested in the day ("daily") or
+// week ("weekly") up to now: how many there were, the top domains, the most shared URLs and the
+// top hashtags, at most top of each. Shares come from the -dedupe index when there is one,
+// otherwise each stored resource counts once. Paywalled URLs are marked as such, or left out
+// of the most shared URLs if skipPaywalled is set.
+func (storage *HarvestedResourceStorage) RenderDigest(period string, top int, skipPaywalled bool, now time.Time) (string, error) {
+	var since time.Time
+	switch period {
+	case "daily":
+		since = now.AddDate(0, 0, -1)
+	case "weekly":
+		since = now.AddDate(0, 0, -7)
+	default:
+		return "", fmt.Errorf("unknown digest period %q", period)
+	}
+
+	seen, err := NewSeenResourcesIndex(filepath.Join(storage.basePath, seenResourcesIndexFile))
+	if err != nil {
+		return "", err
+	}
+
+	domains := make(map[string]*digestCount)
+	urls := make(map[string]*digestCount)
+	hashtags := make(map[string]*digestCount)
+	tweets := make(map[string]bool)
+	resources := 0
+	for _, document := range storage.StoredDocuments() {
+		// documents are most recent first
+		if document.HarvestedAt.Before(since) {
+			break
+		}
+		resources++
+		if tweetID := document.Field("tweetID"); tweetID != "" {
+			tweets[tweetID] = true
+		}
+
+		shares := seen.Hits(document.Field("cleanedURL"))
+		if shares == 0 {
+			shares = 1
+		}
+		if url := document.URL(); url != "" {
+			switch {
+			case !document.IsPaywalled():
+				incrementCount(urls, url, document.Title(), shares)
+			case !skipPaywalled:
+				incrementCount(urls, url, document.Title()+" (paywalled)", shares)
+			}
+		}
+		if domain := destinationDomain(document.Field("finalURL")); domain != "" {
+			incrementCount(domains, domain, domain, shares)
+		}
+		for _, hashtag := range document.Fields("hashtags") {
+			incrementCount(hashtags, strings.ToLower(hashtag), "#"+hashtag, 1)
+		}
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "# Harvest digest, %s\n\n", now.Format("January 2, 2006"))
+	fmt.Fprintf(&report, "%d resources from %d tweets across %d domains, harvested between %s and %s.\n",
+		resources, len(tweets), len(domains), since.Format("2006-01-02 15:04"), now.Format("2006-01-02 15:04"))
+
+	report.WriteString("\n## Top domains\n\n")
+	for i, domain := range topCounts(domains, top) {
+		fmt.Fprintf(&report, "%d. %s (%d)\n", i+1, domain.Label, domain.Count)
+	}
+	report.WriteString("\n## Most shared URLs\n\n")
+	for i, url := range topCounts(urls, top) {
+		fmt.Fprintf(&report, "%d. [%s](%s) (%d)\n", i+1, url.Label, url.Name, url.Count)
+	}
+	report.WriteString("\n## Top hashtags\n\n")
+	for i, hashtag := range topCounts(hashtags, top) {
+		fmt.Fprintf(&report, "%d. %s (%d)\n", i+1, hashtag.Label, hashtag.Count)
+	}
+	return report.String(), nil
+}
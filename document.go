@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DocumentTemplateData is what a -frontmatter-template is executed with for each stored resource
+type DocumentTemplateData struct {
+	Slug        string
+	OriginalURL string
+	FinalURL    string
+	ResolvedURL string
+	CleanedURL  string
+	Text        string
+	HarvestedAt time.Time
+	Provenance  *Provenance
+	// everything the provenance and enrichment stages would have added to the front matter
+	FrontMatter map[string]interface{}
+	Sections    []string
+	// the resource as serialized by the default template
+	Serialized string
+}
+
+var documentTemplateFuncs = template.FuncMap{
+	"yaml": func(value interface{}) (string, error) {
+		data, err := yaml.Marshal(value)
+		return strings.TrimSuffix(string(data), "\n"), err
+	},
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// UseDocumentTemplate makes the storage write documents with the Go template at path instead of
+// the default serialization. The template is executed with DocumentTemplateData and can use the
+// yaml, json and join functions, e.g. `title: {{ index .FrontMatter "title" | yaml }}`.
+func (storage *HarvestedResourceStorage) UseDocumentTemplate(path string) error {
+	tmpl, err := parseDocumentTemplate(path)
+	if err != nil {
+		return err
+	}
+	storage.documentTemplate = tmpl
+	return nil
+}
+
+func parseDocumentTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(documentTemplateFuncs).ParseFiles(path)
+}
+
+// composeDocument turns a serialized resource and what the enrichment stages found into the
+// document that gets stored
+func (storage *HarvestedResourceStorage) composeDocument(data *DocumentTemplateData) (string, error) {
+	if storage.documentTemplate != nil {
+		var document strings.Builder
+		err := storage.documentTemplate.Execute(&document, data)
+		return document.String(), err
+	}
+
+	document, err := addFrontMatter(data.Serialized, data.FrontMatter)
+	for _, section := range data.Sections {
+		document += "\n" + section + "\n"
+	}
+	return document, err
+}
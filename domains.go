@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+)
+
+// OnlyDomains makes the storage skip resources whose destination isn't on one of domains
+// (or their subdomains)
+func (storage *HarvestedResourceStorage) OnlyDomains(domains filter.DomainList) {
+	storage.onlyDomains = domains
+}
+
+// ignoreDomain returns true, with a reason, if destination isn't on an allowed domain
+func (storage *HarvestedResourceStorage) ignoreDomain(destination *url.URL) (bool, string) {
+	if len(storage.onlyDomains) == 0 {
+		return false, ""
+	}
+	if destination == nil {
+		return true, "No destination to check the domain of"
+	}
+	if !storage.onlyDomains.Matches(destination.Hostname()) {
+		return true, fmt.Sprintf("Domain `%s` is not allowed", destination.Hostname())
+	}
+	return false, ""
+}
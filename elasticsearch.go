@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// elasticsearchFlushInterval is the longest a resource waits in a partial batch
+const elasticsearchFlushInterval = 10 * time.Second
+
+// ElasticsearchIndexer indexes stored resources into an Elasticsearch or OpenSearch cluster
+// through the bulk API, batchSize documents at a time
+type ElasticsearchIndexer struct {
+	client    *http.Client
+	logger    *zap.Logger
+	bulkURL   string
+	index     string
+	batchSize int
+	mutex     sync.Mutex
+	batch     bytes.Buffer
+	batched   int
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// NewElasticsearchIndexer indexes into index on the cluster at clusterURL; partial batches are
+// sent every elasticsearchFlushInterval so quiet streams still show up promptly
+func NewElasticsearchIndexer(logger *zap.Logger, clusterURL string, index string, batchSize int) *ElasticsearchIndexer {
+	result := new(ElasticsearchIndexer)
+	result.client = &http.Client{Timeout: time.Minute}
+	result.logger = logger
+	result.bulkURL = strings.TrimSuffix(clusterURL, "/") + "/_bulk"
+	result.index = index
+	result.batchSize = batchSize
+	result.stop = make(chan struct{})
+
+	result.stopped.Add(1)
+	go func() {
+		defer result.stopped.Done()
+		ticker := time.NewTicker(elasticsearchFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := result.Flush(); err != nil {
+					logger.Error("Unable to index resources in Elasticsearch", zap.Error(err))
+				}
+			case <-result.stop:
+				return
+			}
+		}
+	}()
+	return result
+}
+
+// WriteResource implements ResourceWriter, adding the resource to the current batch and
+// sending the batch once it's full
+func (e *ElasticsearchIndexer) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	action := map[string]interface{}{"index": map[string]string{"_index": e.index, "_id": resource.Slug}}
+	actionLine, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+	documentLine, err := json.Marshal(newResourceRecord(resource))
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	e.batch.Write(actionLine)
+	e.batch.WriteByte('\n')
+	e.batch.Write(documentLine)
+	e.batch.WriteByte('\n')
+	e.batched++
+	full := e.batched >= e.batchSize
+	e.mutex.Unlock()
+
+	if full {
+		return e.Flush()
+	}
+	return nil
+}
+
+// Queued implements QueuedWriter, counting the resources batched but not sent yet
+func (e *ElasticsearchIndexer) Queued() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.batched
+}
+
+// Flush sends the current batch, if there's anything in it
+func (e *ElasticsearchIndexer) Flush() error {
+	e.mutex.Lock()
+	if e.batched == 0 {
+		e.mutex.Unlock()
+		return nil
+	}
+	body := make([]byte, e.batch.Len())
+	copy(body, e.batch.Bytes())
+	count := e.batched
+	e.batch.Reset()
+	e.batched = 0
+	e.mutex.Unlock()
+
+	resp, err := e.client.Post(e.bulkURL, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", e.bulkURL, resp.Status)
+	}
+
+	var result elasticsearchBulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.Errors {
+		failed := 0
+		reason := ""
+		for _, item := range result.Items {
+			for _, outcome := range item {
+				if outcome.Status >= 300 {
+					failed++
+					if reason == "" {
+						reason = outcome.Error.Type + ": " + outcome.Error.Reason
+					}
+				}
+			}
+		}
+		return fmt.Errorf("%d of %d resources weren't indexed, e.g. %s", failed, count, reason)
+	}
+	return nil
+}
+
+// Close sends what's left in the batch
+func (e *ElasticsearchIndexer) Close() error {
+	close(e.stop)
+	e.stopped.Wait()
+	return e.Flush()
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// EmailDigestSubjectData is what the -email-subject template is executed with
+type EmailDigestSubjectData struct {
+	Count int
+	Since time.Time
+	Until time.Time
+}
+
+// EmailDigestSender collects stored resources and mails them to a list of recipients as a
+// digest every interval; nothing is sent for an interval without resources
+type EmailDigestSender struct {
+	logger     *zap.Logger
+	server     string
+	auth       smtp.Auth
+	from       string
+	recipients []string
+	subject    *template.Template
+	mutex      sync.Mutex
+	pending    []*ResourceRecord
+	since      time.Time
+	stop       chan struct{}
+	stopped    sync.WaitGroup
+}
+
+// NewEmailDigestSender sends digests through the SMTP server at server (host:port), logging in
+// with username and password if a username is given
+func NewEmailDigestSender(logger *zap.Logger, server string, username string, password string, from string, recipients []string, subject string, interval time.Duration) (*EmailDigestSender, error) {
+	subjectTemplate, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(EmailDigestSender)
+	result.logger = logger
+	result.server = server
+	if username != "" {
+		host, _, err := net.SplitHostPort(server)
+		if err != nil {
+			return nil, err
+		}
+		result.auth = smtp.PlainAuth("", username, password, host)
+	}
+	result.from = from
+	result.recipients = recipients
+	result.subject = subjectTemplate
+	result.since = time.Now()
+	result.stop = make(chan struct{})
+
+	result.stopped.Add(1)
+	go func() {
+		defer result.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := result.Send(); err != nil {
+					logger.Error("Unable to send email digest", zap.Error(err))
+				}
+			case <-result.stop:
+				return
+			}
+		}
+	}()
+	return result, nil
+}
+
+// WriteResource implements ResourceWriter, adding the resource to the next digest
+func (e *EmailDigestSender) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.pending = append(e.pending, newResourceRecord(resource))
+	return nil
+}
+
+// Queued implements QueuedWriter, counting the resources waiting for the next digest
+func (e *EmailDigestSender) Queued() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.pending)
+}
+
+// Send mails the resources collected since the last digest, if there are any
+func (e *EmailDigestSender) Send() error {
+	e.mutex.Lock()
+	resources, since, until := e.pending, e.since, time.Now()
+	e.pending = nil
+	e.since = until
+	e.mutex.Unlock()
+
+	if len(resources) == 0 {
+		return nil
+	}
+
+	var subject strings.Builder
+	if err := e.subject.Execute(&subject, EmailDigestSubjectData{Count: len(resources), Since: since, Until: until}); err != nil {
+		return err
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", e.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(e.recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", removeNewLinesRegEx.ReplaceAllString(subject.String(), " "))
+	fmt.Fprintf(&message, "Date: %s\r\n", until.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, resource := range resources {
+		parts := newChatMessageParts(resource)
+		fmt.Fprintf(&message, "%s\r\n%s\r\n", parts.Title, parts.URL)
+		if footer := parts.footer(); footer != "" {
+			fmt.Fprintf(&message, "%s\r\n", footer)
+		}
+		message.WriteString("\r\n")
+	}
+
+	err := smtp.SendMail(e.server, e.auth, e.from, e.recipients, []byte(message.String()))
+	if err != nil {
+		// keep the resources for the next digest rather than losing them
+		e.mutex.Lock()
+		e.pending = append(resources, e.pending...)
+		e.since = since
+		e.mutex.Unlock()
+	}
+	return err
+}
+
+// Close sends what's been collected since the last digest
+func (e *EmailDigestSender) Close() error {
+	close(e.stop)
+	e.stopped.Wait()
+	return e.Send()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// EnrichedResource collects what the enrichment stages add to a stored resource: fields for
+// its front matter, sections to append to its body and attachments stored next to it.
+// ArticleText is the main text of the destination, available to later stages once article
+// extraction has run.
+type EnrichedResource struct {
+	Slug        string
+	FrontMatter map[string]interface{}
+	Sections    []string
+	Attachments []Attachment
+	ArticleText string
+}
+
+// Attachment is a file stored under its own key alongside a resource's document
+type Attachment struct {
+	Key  string
+	Data []byte
+}
+
+// PageEnricher is an enrichment stage that derives information from a resource's destination
+type PageEnricher interface {
+	EnrichResource(page *FetchedPage, resource *EnrichedResource)
+}
+
+// DestinationEnricher is an enrichment stage that only needs the destination's URL
+type DestinationEnricher interface {
+	EnrichDestination(destination *url.URL, resource *EnrichedResource)
+}
+
+// EnrichWith makes the storage download the destination of every resource it stores and run
+// it through the given enrichment stages
+func (storage *HarvestedResourceStorage) EnrichWith(fetcher *PageFetcher, enrichers []PageEnricher) {
+	storage.fetcher = fetcher
+	storage.enrichers = enrichers
+}
+
+// EnrichDestinationsWith runs the given URL-only enrichment stages on every resource stored
+func (storage *HarvestedResourceStorage) EnrichDestinationsWith(enrichers []DestinationEnricher) {
+	storage.destinationEnrichers = enrichers
+}
+
+// enrich runs the enrichment stages on destination, fetching it unless page already is it
+func (storage *HarvestedResourceStorage) enrich(ctx context.Context, destination *url.URL, page *FetchedPage, resource *EnrichedResource) {
+	if destination == nil {
+		return
+	}
+	for _, enricher := range storage.destinationEnrichers {
+		enricher.EnrichDestination(destination, resource)
+	}
+	if len(storage.enrichers) == 0 {
+		return
+	}
+
+	if page == nil {
+		if page = storage.fetchDestination(ctx, destination); page == nil {
+			return
+		}
+	}
+	for _, enricher := range storage.enrichers {
+		enricher.EnrichResource(page, resource)
+	}
+}
+
+// fetchDestination downloads destination, or returns nil if it can't be
+func (storage *HarvestedResourceStorage) fetchDestination(ctx context.Context, destination *url.URL) *FetchedPage {
+	if destination == nil || storage.fetcher == nil {
+		return nil
+	}
+	_, span := startSpan(ctx, "fetch", urlHostAttribute("destination", destination))
+	page, err := storage.fetcher.Fetch(destination)
+	if page != nil {
+		span.SetAttributes(attribute.Int("status", page.StatusCode))
+	}
+	endSpan(span, err)
+	if err != nil {
+		storage.logger.Warn("Unable to fetch destination", zap.String("url", destination.String()), zap.Error(err))
+		return nil
+	}
+	return page
+}
+
+// metadataEnricher records the page's title and description, plus its OpenGraph and Twitter
+// Card properties
+type metadataEnricher struct{}
+
+func (metadataEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	doc, err := page.Document()
+	if err != nil {
+		return
+	}
+
+	if title := strings.TrimSpace(doc.Find("head title").First().Text()); title != "" {
+		resource.FrontMatter["title"] = title
+	}
+
+	openGraph := make(map[string]string)
+	twitterCard := make(map[string]string)
+	doc.Find("meta").Each(func(i int, meta *goquery.Selection) {
+		content := strings.TrimSpace(meta.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		name := strings.ToLower(meta.AttrOr("property", meta.AttrOr("name", "")))
+		switch {
+		case name == "description":
+			resource.FrontMatter["description"] = content
+		case strings.HasPrefix(name, "og:"):
+			openGraph[strings.TrimPrefix(name, "og:")] = content
+		case strings.HasPrefix(name, "twitter:"):
+			twitterCard[strings.TrimPrefix(name, "twitter:")] = content
+		}
+	})
+	if len(openGraph) > 0 {
+		resource.FrontMatter["openGraph"] = openGraph
+	}
+	if len(twitterCard) > 0 {
+		resource.FrontMatter["twitterCard"] = twitterCard
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+)
+
+// the v1.1 entities anaconda decodes don't include symbols, so we find cashtags ourselves
+var cashtagRegEx = regexp.MustCompile(`(?:^|[^\w$])\$([A-Za-z][A-Za-z0-9_]{0,5}(?:\.[A-Za-z]{1,2})?)\b`)
+
+// tweetFullText returns the untruncated text of tweet, as streamed tweets over 140 characters
+// only carry the full text in their extended_tweet
+func tweetFullText(tweet *anaconda.Tweet) (string, anaconda.Entities) {
+	if tweet.ExtendedTweet.FullText != "" {
+		return tweet.ExtendedTweet.FullText, tweet.ExtendedTweet.Entities
+	}
+	if tweet.FullText != "" {
+		return tweet.FullText, tweet.Entities
+	}
+	return tweet.Text, tweet.Entities
+}
+
+// tweetTags returns the distinct hashtags, @mentions and $cashtags in tweet, without their prefix
+func tweetTags(tweet *anaconda.Tweet) (hashtags []string, mentions []string, cashtags []string) {
+	text, entities := tweetFullText(tweet)
+	for _, hashtag := range entities.Hashtags {
+		hashtags = appendDistinct(hashtags, hashtag.Text)
+	}
+	for _, mention := range entities.User_mentions {
+		mentions = appendDistinct(mentions, mention.Screen_name)
+	}
+	for _, match := range cashtagRegEx.FindAllStringSubmatch(text, -1) {
+		cashtags = appendDistinct(cashtags, strings.ToUpper(match[1]))
+	}
+	return hashtags, mentions, cashtags
+}
+
+func appendDistinct(list []string, value string) []string {
+	for _, existing := range list {
+		if strings.EqualFold(existing, value) {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxNamedEntitiesPerType keeps the front matter readable for long articles
+const maxNamedEntitiesPerType = 10
+
+// NamedEntities are the people, organizations and locations mentioned in a text
+type NamedEntities struct {
+	People        []string `yaml:"people,omitempty"`
+	Organizations []string `yaml:"organizations,omitempty"`
+	Locations     []string `yaml:"locations,omitempty"`
+}
+
+var (
+	// capitalized words, optionally joined by the lowercase connectors names tend to contain
+	namedEntityRegEx        = regexp.MustCompile(`\b[A-Z][\p{L}'&.-]*(?:\s+(?:(?:of|de|la|van|von|and|&)\s+)?[A-Z][\p{L}'&.-]*)*`)
+	namedEntityAcronym      = regexp.MustCompile(`^[A-Z]{2,6}$`)
+	personTitles            = stopWordSet(`mr mr. mrs mrs. ms ms. dr dr. prof prof. president senator governor mayor ceo founder chairman minister secretary judge rep. sen. gov. sir`)
+	organizationMarkers     = stopWordSet(`inc inc. corp corp. corporation ltd ltd. llc plc co co. company group university college institute bank association agency foundation council committee department ministry party news times post journal labs technologies systems`)
+	commonFirstNames        = stopWordSet(`james john robert michael william david richard joseph thomas charles christopher daniel matthew anthony mark donald steven paul andrew joshua kevin brian george edward ronald timothy jason jeffrey ryan jacob gary nicholas eric jonathan stephen larry justin scott brandon benjamin samuel frank gregory alexander patrick jack dennis jerry tyler aaron jose adam henry nathan peter zachary kyle elon bill jeff tim satya sundar mary patricia jennifer linda elizabeth barbara susan jessica sarah karen nancy lisa betty margaret sandra ashley kimberly emily donna michelle dorothy carol amanda melissa deborah stephanie rebecca sharon laura cynthia kathleen amy angela shirley anna brenda pamela emma nicole helen samantha katherine christine debra rachel carolyn janet catherine maria heather diane ruth julie olivia joyce virginia victoria kelly lauren christina joan evelyn judith megan andrea cheryl hannah jacqueline martha gloria teresa ann sara madison frances kathryn janice jean abigail alice judy sophia grace denise amber doris marilyn danielle beverly isabella theresa diana natalie brittany charlotte marie kayla alexis lori`)
+	knownLocations          = stopWordSet(`afghanistan africa alabama alaska america argentina arizona asia atlanta australia austin austria bangalore bangladesh beijing belgium berlin boston brazil brussels california canada chicago chile china colorado colombia dallas delhi denmark denver dubai egypt england europe finland florida france georgia germany greece hawaii hollywood houston india indonesia iran iraq ireland israel istanbul italy japan jerusalem kenya korea london los madrid manhattan massachusetts melbourne mexico miami michigan moscow mumbai netherlands nevada nigeria norway ohio ontario oregon pakistan paris philippines poland portugal russia scotland seattle seoul shanghai singapore spain stockholm sweden switzerland sydney syria taiwan texas thailand tokyo toronto turkey uk ukraine usa utah vancouver vietnam virginia wales washington zurich`)
+	knownMultiWordLocations = map[string]bool{"new york": true, "san francisco": true, "los angeles": true, "hong kong": true, "united states": true,
+		"united kingdom": true, "silicon valley": true, "new zealand": true, "south africa": true, "south korea": true, "north korea": true,
+		"saudi arabia": true, "new jersey": true, "new delhi": true, "las vegas": true, "san diego": true, "washington dc": true}
+)
+
+// extractNamedEntities finds names with a few heuristics (capitalization, titles, company suffixes,
+// a small gazetteer and list of first names); it's no replacement for a trained model but it's
+// fast, needs nothing external and catches the names that matter most in news articles
+func extractNamedEntities(text string) *NamedEntities {
+	counts := map[string]map[string]int{"person": {}, "organization": {}, "location": {}}
+	for _, span := range namedEntityRegEx.FindAllStringIndex(text, -1) {
+		name := strings.TrimRight(text[span[0]:span[1]], ".-'&")
+		words := strings.Fields(name)
+		lowerName := strings.ToLower(name)
+		lowerFirst := strings.ToLower(words[0])
+		lowerLast := strings.ToLower(words[len(words)-1])
+		before := strings.TrimRight(text[:span[0]], " \t\n")
+		previousWord := strings.ToLower(before[strings.LastIndexAny(before, " \t\n")+1:])
+
+		switch {
+		case knownMultiWordLocations[lowerName] || (len(words) == 1 && knownLocations[lowerName]):
+			counts["location"][name]++
+		case organizationMarkers[lowerLast] && len(words) > 1, namedEntityAcronym.MatchString(name) && !knownLocations[lowerName]:
+			counts["organization"][name]++
+		case personTitles[lowerFirst] && len(words) > 1:
+			counts["person"][strings.Join(words[1:], " ")]++
+		case len(words) >= 2 && len(words) <= 3 && (personTitles[previousWord] || commonFirstNames[lowerFirst]):
+			counts["person"][name]++
+		}
+	}
+
+	top := func(names map[string]int) []string {
+		result := make([]string, 0, len(names))
+		for name := range names {
+			result = append(result, name)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if names[result[i]] != names[result[j]] {
+				return names[result[i]] > names[result[j]]
+			}
+			return result[i] < result[j]
+		})
+		if len(result) > maxNamedEntitiesPerType {
+			result = result[:maxNamedEntitiesPerType]
+		}
+		return result
+	}
+	return &NamedEntities{
+		People:        top(counts["person"]),
+		Organizations: top(counts["organization"]),
+		Locations:     top(counts["location"]),
+	}
+}
+
+// namedEntityEnricher records who and what the extracted article text talks about, so it has to
+// run after article (or PDF) extraction
+type namedEntityEnricher struct{}
+
+func (namedEntityEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	if resource.ArticleText == "" {
+		return
+	}
+	entities := extractNamedEntities(resource.ArticleText)
+	if len(entities.People)+len(entities.Organizations)+len(entities.Locations) > 0 {
+		resource.FrontMatter["entities"] = entities
+	}
+}
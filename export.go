@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ResourceRecord is the flat form of a stored resource that the export outputs write
+type ResourceRecord struct {
+	Slug        string                 `json:"slug"`
+	OriginalURL string                 `json:"originalURL"`
+	FinalURL    string                 `json:"finalURL"`
+	ResolvedURL string                 `json:"resolvedURL"`
+	CleanedURL  string                 `json:"cleanedURL"`
+	Text        string                 `json:"text"`
+	HarvestedAt time.Time              `json:"harvestedAt"`
+	Query       string                 `json:"query,omitempty"`
+	Timeline    string                 `json:"timeline,omitempty"`
+	List        string                 `json:"list,omitempty"`
+	TweetID     string                 `json:"tweetID,omitempty"`
+	Author      string                 `json:"author,omitempty"`
+	TweetedAt   string                 `json:"tweetedAt,omitempty"`
+	FrontMatter map[string]interface{} `json:"frontMatter,omitempty"`
+}
+
+func newResourceRecord(resource *DocumentTemplateData) *ResourceRecord {
+	result := new(ResourceRecord)
+	result.Slug = resource.Slug
+	result.OriginalURL = resource.OriginalURL
+	result.FinalURL = resource.FinalURL
+	result.ResolvedURL = resource.ResolvedURL
+	result.CleanedURL = resource.CleanedURL
+	result.Text = resource.Text
+	result.HarvestedAt = resource.HarvestedAt
+	result.FrontMatter = resource.FrontMatter
+	if provenance := resource.Provenance; provenance != nil {
+		result.Query = provenance.Query
+		result.Timeline = provenance.Timeline
+		result.List = provenance.List
+		if provenance.Tweet != nil {
+			result.TweetID = provenance.Tweet.IdStr
+			result.Author = provenance.Tweet.User.ScreenName
+			result.TweetedAt = provenance.Tweet.CreatedAt
+		}
+	}
+	return result
+}
+
+// JSONLinesWriter writes one JSON object per stored resource, for jq and ETL tooling
+type JSONLinesWriter struct {
+	mutex   sync.Mutex
+	output  io.WriteCloser
+	encoder *json.Encoder
+}
+
+// NewJSONLinesWriter writes to the file at path, appending to it if it exists, or to stdout if
+// path is "-"
+func NewJSONLinesWriter(path string) (*JSONLinesWriter, error) {
+	result := new(JSONLinesWriter)
+	if path == "-" {
+		result.output = os.Stdout
+	} else {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		result.output = file
+	}
+	result.encoder = json.NewEncoder(result.output)
+	return result, nil
+}
+
+// WriteResource implements ResourceWriter
+func (w *JSONLinesWriter) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.encoder.Encode(newResourceRecord(resource))
+}
+
+// Close closes the output file; stdout is left alone
+func (w *JSONLinesWriter) Close() error {
+	if w.output == os.Stdout {
+		return nil
+	}
+	return w.output.Close()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// RenderFeed renders the most recent count resources in the store as an RSS 2.0 ("rss") or
+// Atom ("atom") feed
+func (storage *HarvestedResourceStorage) RenderFeed(format string, title string, link string, count int) (string, error) {
+	feed := &feeds.Feed{
+		Title:       title,
+		Link:        &feeds.Link{Href: link},
+		Description: "Resources harvested from Twitter",
+		Updated:     time.Now(),
+	}
+
+	documents := storage.StoredDocuments()
+	if count > 0 && len(documents) > count {
+		documents = documents[:count]
+	}
+	for _, document := range documents {
+		item := &feeds.Item{
+			Title:       document.Title(),
+			Link:        &feeds.Link{Href: document.URL()},
+			Description: document.Field("description"),
+			Id:          document.URL(),
+			Created:     document.HarvestedAt,
+		}
+		if user := document.Field("user"); user != "" {
+			item.Author = &feeds.Author{Name: "@" + user}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	switch format {
+	case "rss":
+		return feed.ToRss()
+	case "atom":
+		return feed.ToAtom()
+	}
+	return "", fmt.Errorf("unknown feed format %q", format)
+}
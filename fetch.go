@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxFetchedPageSize caps how much of a destination we download, so giant files don't eat memory
+const maxFetchedPageSize = 10 * 1024 * 1024
+
+// FetchedPage is a destination downloaded once so that every enrichment stage can share it
+type FetchedPage struct {
+	URL        *url.URL
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Truncated  bool
+	FetchedAt  time.Time
+
+	// the request and response as they went over the wire, minus the response body
+	RawRequest        []byte
+	RawResponseHeader []byte
+
+	documentOnce sync.Once
+	document     *goquery.Document
+	documentErr  error
+}
+
+// ContentType returns the media type of the page without any parameters, e.g. "text/html"
+func (page *FetchedPage) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(page.Header.Get("Content-Type"))
+	if err != nil {
+		return http.DetectContentType(page.Body)
+	}
+	return mediaType
+}
+
+// IsHTML is true if the page can be parsed as an HTML document
+func (page *FetchedPage) IsHTML() bool {
+	contentType := page.ContentType()
+	return contentType == "text/html" || contentType == "application/xhtml+xml"
+}
+
+// Document parses the page as HTML, the first time it's asked for
+func (page *FetchedPage) Document() (*goquery.Document, error) {
+	page.documentOnce.Do(func() {
+		if !page.IsHTML() {
+			page.documentErr = fmt.Errorf("%s is %s, not HTML", page.URL, page.ContentType())
+			return
+		}
+		page.document, page.documentErr = goquery.NewDocumentFromReader(bytes.NewReader(page.Body))
+		if page.document != nil {
+			page.document.Url = page.URL
+		}
+	})
+	return page.document, page.documentErr
+}
+
+// PageFetcher downloads the destinations of harvested resources
+type PageFetcher struct {
+	client *http.Client
+	robots *RobotsTxtChecker
+}
+
+// NewPageFetcher creates a fetcher that gives up on a destination after timeout, sending its
+// requests through transport
+func NewPageFetcher(timeout time.Duration, transport http.RoundTripper) *PageFetcher {
+	result := new(PageFetcher)
+	result.client = &http.Client{Timeout: timeout, Transport: transport}
+	return result
+}
+
+// Fetch downloads the page at pageURL
+func (f *PageFetcher) Fetch(pageURL *url.URL) (*FetchedPage, error) {
+	if f.robots != nil && !f.robots.Allowed(pageURL) {
+		return nil, fmt.Errorf("%s is disallowed by robots.txt", pageURL)
+	}
+	req, err := http.NewRequest(http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	rawRequest, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedAt := time.Now()
+	resp, err := f.client.Do(req)
+	fetchHistogram.Observe(time.Since(fetchedAt).Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	rawResponseHeader, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFetchedPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &FetchedPage{URL: resp.Request.URL, StatusCode: resp.StatusCode, Header: resp.Header, Body: body,
+		FetchedAt: fetchedAt, RawRequest: rawRequest, RawResponseHeader: rawResponseHeader}
+	if len(body) > maxFetchedPageSize {
+		page.Body = body[:maxFetchedPageSize]
+		page.Truncated = true
+	}
+	return page, nil
+}
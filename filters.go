@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// TweetFilter decides whether a tweet should be skipped before any of its links are harvested
+type TweetFilter interface {
+	IgnoreTweet(tweet *anaconda.Tweet) (bool, string)
+}
+
+// TweetHarvester runs tweets past the configured filters and stores the resources in the rest
+type TweetHarvester struct {
+	storage      *HarvestedResourceStorage
+	logger       *zap.Logger
+	filters      []TweetFilter
+	spam         *SpamScorer
+	maxSpamScore float64
+	media        *MediaHarvester
+	detectLang   bool
+	sentiment    SentimentAnalyzer
+	summary      *HarvestSummary
+	limit        *RunLimit
+	rawTweets    *RawTweetLog
+	state        *HarvestState
+}
+
+// NewTweetHarvester creates a harvester that only stores tweets none of the filters ignore
+func NewTweetHarvester(storage *HarvestedResourceStorage, logger *zap.Logger, filters []TweetFilter) *TweetHarvester {
+	result := new(TweetHarvester)
+	result.storage = storage
+	result.logger = logger
+	result.filters = filters
+	return result
+}
+
+// ScoreSpam records a spam score for every harvested tweet and, if maxSpamScore is more than
+// zero, skips the tweets scoring at or above it
+func (h *TweetHarvester) ScoreSpam(maxSpamScore float64) {
+	h.spam = NewSpamScorer()
+	h.maxSpamScore = maxSpamScore
+}
+
+// HarvestMedia makes the harvester store the media attached to tweets too
+func (h *TweetHarvester) HarvestMedia(media *MediaHarvester) {
+	h.media = media
+}
+
+// DetectLanguage makes the harvester record the detected language of each tweet's text
+func (h *TweetHarvester) DetectLanguage() {
+	h.detectLang = true
+}
+
+// AnalyzeSentiment makes the harvester record the sentiment of each tweet's text
+func (h *TweetHarvester) AnalyzeSentiment(analyzer SentimentAnalyzer) {
+	h.sentiment = analyzer
+}
+
+// Harvest stores all resources in tweet unless a filter ignores it
+func (h *TweetHarvester) Harvest(tweet anaconda.Tweet, provenance *Provenance) {
+	if h.limit != nil && !h.limit.allowTweet() {
+		return
+	}
+	tweetsCounter.Inc()
+	// screened out tweets are done with too, and left out of backfills like harvested ones
+	if h.state != nil {
+		defer h.state.TweetHarvested(tweet.Id)
+	}
+	if h.rawTweets != nil {
+		h.rawTweets.Retain(&tweet)
+	}
+	ctx, span := startSpan(context.Background(), "harvest tweet", attribute.String("tweetID", tweet.IdStr),
+		attribute.String("user", tweet.User.ScreenName))
+	defer span.End()
+	if h.summary != nil {
+		h.summary.tweetHarvested()
+	}
+	for _, filter := range h.filters {
+		if ignore, reason := filter.IgnoreTweet(&tweet); ignore {
+			h.logger.Info("Ignored tweet", zap.String("tweetID", tweet.IdStr),
+				zap.String("user", tweet.User.ScreenName),
+				zap.String("reason", reason))
+			if h.summary != nil {
+				h.summary.tweetIgnored(reason)
+			}
+			return
+		}
+	}
+
+	tweetProvenance := Provenance{}
+	if provenance != nil {
+		tweetProvenance = *provenance
+	}
+	tweetProvenance.Tweet = &tweet
+
+	if h.spam != nil {
+		tweetProvenance.Spam = h.spam.Assess(&tweet)
+		if h.maxSpamScore > 0 && tweetProvenance.Spam.Score >= h.maxSpamScore {
+			h.logger.Info("Ignored tweet", zap.String("tweetID", tweet.IdStr),
+				zap.String("user", tweet.User.ScreenName),
+				zap.String("reason", "Likely spam"),
+				zap.Float64("spamScore", tweetProvenance.Spam.Score),
+				zap.Strings("spamSignals", tweetProvenance.Spam.Signals))
+			if h.summary != nil {
+				h.summary.tweetIgnored("Likely spam")
+			}
+			return
+		}
+	}
+	if h.detectLang {
+		text, _ := tweetFullText(&tweet)
+		// fall back on Twitter's own guess when the text is too short to tell
+		if tweetProvenance.Lang = detectLanguage(text); tweetProvenance.Lang == "" && tweet.Lang != "und" {
+			tweetProvenance.Lang = tweet.Lang
+		}
+	}
+	if h.sentiment != nil {
+		text, _ := tweetFullText(&tweet)
+		score, err := h.sentiment.AnalyzeSentiment(text)
+		if err != nil {
+			h.logger.Warn("Unable to analyze sentiment", zap.String("tweetID", tweet.IdStr), zap.Error(err))
+		} else {
+			tweetProvenance.Sentiment = newSentiment(score)
+		}
+	}
+	if h.media != nil {
+		tweetProvenance.Media = h.media.Harvest(&tweet, &tweetProvenance)
+	}
+	h.storage.SaveAllInText(ctx, tweet.Text, &tweetProvenance)
+}
+
+type languageList []string
+
+func (l *languageList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *languageList) Set(value string) error {
+	for _, lang := range strings.Split(value, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			*l = append(*l, lang)
+		}
+	}
+	return nil
+}
+
+func (l languageList) IgnoreTweet(tweet *anaconda.Tweet) (bool, string) {
+	for _, lang := range l {
+		if tweet.Lang == lang {
+			return false, ""
+		}
+	}
+	return true, fmt.Sprintf("Language `%s` not in `%s`", tweet.Lang, l.String())
+}
+
+// geoBoundingBox is given as west longitude, south latitude, east longitude, north latitude,
+// the same order the streaming API's locations parameter uses
+type geoBoundingBox struct {
+	set                      bool
+	west, south, east, north float64
+}
+
+func (b *geoBoundingBox) String() string {
+	if !b.set {
+		return ""
+	}
+	return b.streamLocations()
+}
+
+func (b *geoBoundingBox) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return fmt.Errorf("bounding box %q should be west,south,east,north", value)
+	}
+	var coords [4]float64
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return fmt.Errorf("bounding box %q: %v", value, err)
+		}
+		coords[i] = coord
+	}
+	b.west, b.south, b.east, b.north = coords[0], coords[1], coords[2], coords[3]
+	b.set = true
+	return nil
+}
+
+func (b geoBoundingBox) streamLocations() string {
+	return fmt.Sprintf("%g,%g,%g,%g", b.west, b.south, b.east, b.north)
+}
+
+// searchGeocode approximates the box with the circle around it, since the Search API only
+// takes a center and radius
+func (b geoBoundingBox) searchGeocode() string {
+	lat, lon := (b.south+b.north)/2, (b.west+b.east)/2
+	return fmt.Sprintf("%g,%g,%.1fkm", lat, lon, haversineKm(lat, lon, b.north, b.east))
+}
+
+func (b geoBoundingBox) contains(lon float64, lat float64) bool {
+	return lon >= b.west && lon <= b.east && lat >= b.south && lat <= b.north
+}
+
+func (b geoBoundingBox) IgnoreTweet(tweet *anaconda.Tweet) (bool, string) {
+	if tweet.Coordinates != nil {
+		if b.contains(tweet.Coordinates.Coordinates[0], tweet.Coordinates.Coordinates[1]) {
+			return false, ""
+		}
+		return true, fmt.Sprintf("Coordinates outside of `%s`", b.streamLocations())
+	}
+
+	// without exact coordinates we go by the center of the tweet's place, if it has one
+	polygons := tweet.Place.BoundingBox.Coordinates
+	if len(polygons) == 0 || len(polygons[0]) == 0 {
+		return true, "No location"
+	}
+	var lon, lat float64
+	for _, point := range polygons[0] {
+		lon += point[0] / float64(len(polygons[0]))
+		lat += point[1] / float64(len(polygons[0]))
+	}
+	if b.contains(lon, lat) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("Place `%s` outside of `%s`", tweet.Place.FullName, b.streamLocations())
+}
+
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat, dLon := toRadians(lat2-lat1), toRadians(lon2-lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// engagementFilter ignores tweets with fewer retweets or favorites than required; for retweets
+// the counts of the original tweet are used since the retweet itself is brand new
+type engagementFilter struct {
+	minRetweets  int
+	minFavorites int
+}
+
+func (f engagementFilter) IgnoreTweet(tweet *anaconda.Tweet) (bool, string) {
+	original := tweet
+	if tweet.RetweetedStatus != nil {
+		original = tweet.RetweetedStatus
+	}
+	if original.RetweetCount < f.minRetweets {
+		return true, fmt.Sprintf("Only %d retweets, %d required", original.RetweetCount, f.minRetweets)
+	}
+	if original.FavoriteCount < f.minFavorites {
+		return true, fmt.Sprintf("Only %d favorites, %d required", original.FavoriteCount, f.minFavorites)
+	}
+	return false, ""
+}
+
+// userFilter skips tweets by blocked users and, when there's an allowlist, by anyone not on it;
+// users are given as screen names (with or without @) or user IDs
+type userFilter struct {
+	only    map[string]bool
+	blocked map[string]bool
+}
+
+func newUserFilter(only []string, blocked []string) userFilter {
+	normalize := func(users []string) map[string]bool {
+		result := make(map[string]bool)
+		for _, user := range users {
+			result[strings.ToLower(strings.TrimPrefix(user, "@"))] = true
+		}
+		return result
+	}
+	return userFilter{only: normalize(only), blocked: normalize(blocked)}
+}
+
+func (f userFilter) matches(users map[string]bool, tweet *anaconda.Tweet) bool {
+	return users[strings.ToLower(tweet.User.ScreenName)] || users[tweet.User.IdStr]
+}
+
+func (f userFilter) IgnoreTweet(tweet *anaconda.Tweet) (bool, string) {
+	if f.matches(f.blocked, tweet) {
+		return true, fmt.Sprintf("User @%s is blocked", tweet.User.ScreenName)
+	}
+	if len(f.only) > 0 && !f.matches(f.only, tweet) {
+		return true, fmt.Sprintf("User @%s is not in the allowed users", tweet.User.ScreenName)
+	}
+	return false, ""
+}
+
+// authorFilter skips tweets by unverified accounts or accounts with too few followers
+type authorFilter struct {
+	onlyVerified bool
+	minFollowers int
+}
+
+func (f authorFilter) IgnoreTweet(tweet *anaconda.Tweet) (bool, string) {
+	if f.onlyVerified && !tweet.User.Verified {
+		return true, fmt.Sprintf("User @%s is not verified", tweet.User.ScreenName)
+	}
+	if tweet.User.FollowersCount < f.minFollowers {
+		return true, fmt.Sprintf("User @%s only has %d followers, %d required", tweet.User.ScreenName, tweet.User.FollowersCount, f.minFollowers)
+	}
+	return false, ""
+}
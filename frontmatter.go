@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const frontMatterDelimiter = "---"
+
+// splitFrontMatter separates a serialized document into its YAML front matter (without the
+// delimiters) and its body; found is false when the document has no front matter
+func splitFrontMatter(document string) (frontMatter string, body string, found bool) {
+	opening := frontMatterDelimiter + "\n"
+	if !strings.HasPrefix(document, opening) {
+		return "", document, false
+	}
+	rest := document[len(opening):]
+	closing := strings.Index("\n"+rest, "\n"+frontMatterDelimiter)
+	if closing < 0 {
+		return "", document, false
+	}
+	body = strings.TrimPrefix(rest[closing+len(frontMatterDelimiter):], "\n")
+	return rest[:closing], body, true
+}
+
+// addFrontMatter merges fields into the YAML front matter of a serialized document,
+// creating the front matter block if the document doesn't have one; fields the front matter
+// already has are left as they are so the YAML never ends up with duplicate keys
+func addFrontMatter(document string, fields map[string]interface{}) (string, error) {
+	if len(fields) == 0 {
+		return document, nil
+	}
+
+	opening := frontMatterDelimiter + "\n"
+	frontMatter, body, found := splitFrontMatter(document)
+	if found {
+		existing := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(frontMatter), &existing); err != nil {
+			return document, err
+		}
+		missing := make(map[string]interface{})
+		for name, value := range fields {
+			if _, duplicate := existing[name]; !duplicate {
+				missing[name] = value
+			}
+		}
+		if len(missing) == 0 {
+			return document, nil
+		}
+		fields = missing
+	}
+
+	data, err := yaml.Marshal(fields)
+	if err != nil {
+		return document, err
+	}
+	if !found {
+		return opening + string(data) + opening + document, nil
+	}
+	return opening + frontMatter + string(data) + opening + body, nil
+}
+
+// readFrontMatter parses the front matter of a serialized document and returns it with the body
+func readFrontMatter(document string) (map[string]interface{}, string, error) {
+	fields := make(map[string]interface{})
+	frontMatter, body, found := splitFrontMatter(document)
+	if !found {
+		return fields, body, nil
+	}
+	if err := yaml.Unmarshal([]byte(frontMatter), &fields); err != nil {
+		return nil, body, err
+	}
+	return fields, body, nil
+}
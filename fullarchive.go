@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"go.uber.org/zap"
+)
+
+// fullArchiveSearchURL is the v2 full-archive search endpoint, which Academic and paid access
+// can call; with max_results it returns up to 500 tweets a page
+const fullArchiveSearchURL = "https://api.twitter.com/2/tweets/search/all"
+
+// fullArchivePageInterval keeps to the endpoint's limit of one request a second
+const fullArchivePageInterval = time.Second
+
+type fullArchiveTweet struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+	Lang      string `json:"lang"`
+	AuthorID  string `json:"author_id"`
+	Entities  struct {
+		URLs []struct {
+			URL         string `json:"url"`
+			ExpandedURL string `json:"expanded_url"`
+		} `json:"urls"`
+	} `json:"entities"`
+	PublicMetrics struct {
+		RetweetCount int `json:"retweet_count"`
+		LikeCount    int `json:"like_count"`
+	} `json:"public_metrics"`
+}
+
+type fullArchivePage struct {
+	Data     []fullArchiveTweet `json:"data"`
+	Includes struct {
+		Users []struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+			Name     string `json:"name"`
+		} `json:"users"`
+	} `json:"includes"`
+	Meta struct {
+		NextToken   string `json:"next_token"`
+		ResultCount int    `json:"result_count"`
+	} `json:"meta"`
+}
+
+// FullArchiveSearch is a Source of the tweets one or more queries find in Twitter's full
+// archive, rather than the last 7 days the standard search covers, between a start and end
+// time; it pages through each query's results with the v2 API's next_token
+type FullArchiveSearch struct {
+	bearerToken string
+	logger      *zap.Logger
+	queries     []string
+	startTime   time.Time
+	endTime     time.Time
+	maxRetries  int
+	client      *http.Client
+}
+
+// NewFullArchiveSearch prepares a search for the given queries with the app's bearer token;
+// a zero startTime or endTime leaves it to Twitter's default (30 days before the end, and now)
+func NewFullArchiveSearch(bearerToken string, logger *zap.Logger, queries []string, startTime time.Time, endTime time.Time, maxRetries int) *FullArchiveSearch {
+	result := new(FullArchiveSearch)
+	result.bearerToken = bearerToken
+	result.logger = logger
+	result.queries = queries
+	result.startTime = startTime
+	result.endTime = endTime
+	result.maxRetries = maxRetries
+	result.client = &http.Client{Timeout: 30 * time.Second}
+	return result
+}
+
+// Start implements Source, running each query through all its pages once
+func (s *FullArchiveSearch) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		for _, query := range s.queries {
+			if ctx.Err() != nil {
+				return
+			}
+			s.search(ctx, query, items)
+		}
+	}()
+	return items
+}
+
+func (s *FullArchiveSearch) search(ctx context.Context, query string, items chan<- Item) {
+	v := url.Values{}
+	v.Set("query", query)
+	v.Set("max_results", "500")
+	v.Set("tweet.fields", "created_at,lang,author_id,entities,public_metrics")
+	v.Set("expansions", "author_id")
+	v.Set("user.fields", "username,name")
+	if !s.startTime.IsZero() {
+		v.Set("start_time", s.startTime.UTC().Format(time.RFC3339))
+	}
+	if !s.endTime.IsZero() {
+		v.Set("end_time", s.endTime.UTC().Format(time.RFC3339))
+	}
+
+	for pages := 1; ; pages++ {
+		page, err := s.page(ctx, v)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("Unable to search Twitter's full archive", zap.String("query", query), zap.Int("page", pages), zap.Error(err))
+			}
+			return
+		}
+		s.logger.Debug("Searched Twitter's full archive", zap.String("query", query), zap.Int("page", pages),
+			zap.Int("tweets", page.Meta.ResultCount))
+		for _, tweet := range fullArchiveTweets(page) {
+			select {
+			case items <- Item{Tweet: tweet, Provenance: &Provenance{Query: query}}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if page.Meta.NextToken == "" {
+			return
+		}
+		v.Set("next_token", page.Meta.NextToken)
+		select {
+		case <-time.After(fullArchivePageInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// page gets one page of results, waiting out the rate limit window when it's reached and
+// backing off on server errors
+func (s *FullArchiveSearch) page(ctx context.Context, v url.Values) (*fullArchivePage, error) {
+	backoff := 5 * time.Second
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, fullArchiveSearchURL+"?"+v.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken)
+		resp, err := s.client.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		wait := backoff
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			page := new(fullArchivePage)
+			if err := json.Unmarshal(body, page); err != nil {
+				return nil, err
+			}
+			return page, nil
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait = time.Minute
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-Rate-Limit-Reset"), 10, 64); err == nil {
+				wait = time.Until(time.Unix(reset, 0)) + time.Second
+			}
+			s.logger.Info("Twitter rate limit reached, waiting for next window", zap.String("endpoint", "/2/tweets/search/all"),
+				zap.Duration("wait", wait))
+			attempt--
+		case resp.StatusCode < http.StatusInternalServerError || attempt >= s.maxRetries:
+			return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+		default:
+			s.logger.Warn("Twitter API request failed, retrying", zap.String("endpoint", "/2/tweets/search/all"),
+				zap.Int("attempt", attempt+1),
+				zap.Duration("retryAfter", wait),
+				zap.String("status", resp.Status))
+			backoff *= 2
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fullArchiveTweets turns the page's v2 tweets into the v1.1 ones the harvester takes, with
+// their t.co links expanded in the text
+func fullArchiveTweets(page *fullArchivePage) []*anaconda.Tweet {
+	users := make(map[string]anaconda.User)
+	for _, user := range page.Includes.Users {
+		users[user.ID] = anaconda.User{IdStr: user.ID, ScreenName: user.Username, Name: user.Name}
+	}
+	var result []*anaconda.Tweet
+	for _, data := range page.Data {
+		tweet := new(anaconda.Tweet)
+		tweet.IdStr = data.ID
+		tweet.Id, _ = strconv.ParseInt(data.ID, 10, 64)
+		tweet.Text = data.Text
+		tweet.FullText = data.Text
+		tweet.Lang = data.Lang
+		tweet.User = users[data.AuthorID]
+		tweet.RetweetCount = data.PublicMetrics.RetweetCount
+		tweet.FavoriteCount = data.PublicMetrics.LikeCount
+		if createdAt, err := time.Parse(time.RFC3339, data.CreatedAt); err == nil {
+			tweet.CreatedAt = createdAt.Format(time.RubyDate)
+		}
+		for _, link := range data.Entities.URLs {
+			if link.ExpandedURL != "" {
+				tweet.Text = strings.Replace(tweet.Text, link.URL, link.ExpandedURL, -1)
+			}
+		}
+		result = append(result, tweet)
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const graphQLSchema = `
+schema {
+	query: Query
+	subscription: Subscription
+}
+
+type Query {
+	# stored resources, most recently harvested first
+	resources(first: Int = 20, text: String, domain: String, user: String, hashtag: String): [Resource!]!
+	resource(slug: String!): Resource
+}
+
+type Subscription {
+	# resources as they're stored
+	resourceHarvested: Resource!
+}
+
+type Resource {
+	slug: String!
+	url: String!
+	title: String!
+	description: String
+	finalURL: String
+	cleanedURL: String
+	harvestedAt: String!
+	user: String
+	tweetID: String
+	query: String
+	hashtags: [String!]!
+	keywords: [String!]!
+	body: String!
+}
+`
+
+type graphQLResolver struct {
+	storage     *HarvestedResourceStorage
+	broadcaster *ResourceBroadcaster
+}
+
+func newGraphQLSchema(storage *HarvestedResourceStorage, broadcaster *ResourceBroadcaster) *graphql.Schema {
+	return graphql.MustParseSchema(graphQLSchema, &graphQLResolver{storage: storage, broadcaster: broadcaster})
+}
+
+func (r *graphQLResolver) Resources(args struct {
+	First   int32
+	Text    *string
+	Domain  *string
+	User    *string
+	Hashtag *string
+}) []*resourceResolver {
+	filter := &StoredDocumentFilter{
+		Text:    stringValue(args.Text),
+		Domain:  stringValue(args.Domain),
+		User:    stringValue(args.User),
+		Hashtag: stringValue(args.Hashtag),
+		Limit:   int(args.First),
+	}
+
+	var result []*resourceResolver
+	for _, document := range r.storage.FindStoredDocuments(filter) {
+		result = append(result, &resourceResolver{document})
+	}
+	return result
+}
+
+func (r *graphQLResolver) Resource(args struct{ Slug string }) (*resourceResolver, error) {
+	if !isDocumentKey(args.Slug) || !r.storage.diskv.Has(args.Slug) {
+		return nil, nil
+	}
+	document, err := r.storage.StoredDocument(args.Slug)
+	if err != nil {
+		return nil, err
+	}
+	return &resourceResolver{document}, nil
+}
+
+func (r *graphQLResolver) ResourceHarvested(ctx context.Context) <-chan *resourceResolver {
+	subscriber := r.broadcaster.Subscribe()
+	result := make(chan *resourceResolver)
+	go func() {
+		defer close(result)
+		defer r.broadcaster.Unsubscribe(subscriber)
+		for {
+			select {
+			case document := <-subscriber:
+				select {
+				case result <- &resourceResolver{document}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return result
+}
+
+type resourceResolver struct {
+	document *StoredDocument
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func (r *resourceResolver) Slug() string  { return r.document.Key }
+func (r *resourceResolver) URL() string   { return r.document.URL() }
+func (r *resourceResolver) Title() string { return r.document.Title() }
+func (r *resourceResolver) Description() *string {
+	return optionalString(r.document.Field("description"))
+}
+func (r *resourceResolver) FinalURL() *string { return optionalString(r.document.Field("finalURL")) }
+func (r *resourceResolver) CleanedURL() *string {
+	return optionalString(r.document.Field("cleanedURL"))
+}
+func (r *resourceResolver) HarvestedAt() string { return r.document.HarvestedAt.Format(time.RFC3339) }
+func (r *resourceResolver) User() *string       { return optionalString(r.document.Field("user")) }
+func (r *resourceResolver) TweetID() *string    { return optionalString(r.document.Field("tweetID")) }
+func (r *resourceResolver) Query() *string      { return optionalString(r.document.Field("query")) }
+func (r *resourceResolver) Hashtags() []string  { return nonNilStrings(r.document.Fields("hashtags")) }
+func (r *resourceResolver) Keywords() []string  { return nonNilStrings(r.document.Fields("keywords")) }
+func (r *resourceResolver) Body() string        { return r.document.Body }
+
+// nonNilStrings makes missing lists empty, as non-null GraphQL lists must be
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
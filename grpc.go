@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/harvestpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcHarvester implements the Harvester gRPC service in harvestpb/harvest.proto
+type grpcHarvester struct {
+	storage     *HarvestedResourceStorage
+	broadcaster *ResourceBroadcaster
+	adminToken  string
+}
+
+func newResourceMessage(document *StoredDocument, withBody bool) *harvestpb.Resource {
+	result := &harvestpb.Resource{
+		Slug:            document.Key,
+		Url:             document.URL(),
+		Title:           document.Title(),
+		FinalUrl:        document.Field("finalURL"),
+		CleanedUrl:      document.Field("cleanedURL"),
+		HarvestedAtUnix: document.HarvestedAt.Unix(),
+		User:            document.Field("user"),
+		TweetId:         document.Field("tweetID"),
+		Query:           document.Field("query"),
+		Hashtags:        document.Fields("hashtags"),
+	}
+	if withBody {
+		result.Body = document.Body
+	}
+	return result
+}
+
+// SubmitText fetches what the text links to and writes it to the store, so like the REST API's
+// harvest it requires the admin token, as "authorization: Bearer <token>" metadata
+func (g *grpcHarvester) SubmitText(ctx context.Context, request *harvestpb.SubmitTextRequest) (*harvestpb.SubmitTextResponse, error) {
+	if g.adminToken == "" {
+		return nil, status.Error(codes.PermissionDenied, "submitting text requires the server's admin token to be set")
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(g.adminToken)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "a valid admin token is required")
+	}
+	return &harvestpb.SubmitTextResponse{Slugs: g.storage.SaveAllInText(ctx, request.GetText(), nil)}, nil
+}
+
+func (g *grpcHarvester) StreamHarvested(request *harvestpb.StreamHarvestedRequest, stream harvestpb.Harvester_StreamHarvestedServer) error {
+	subscriber := g.broadcaster.Subscribe()
+	defer g.broadcaster.Unsubscribe(subscriber)
+	for {
+		select {
+		case document := <-subscriber:
+			if err := stream.Send(newResourceMessage(document, true)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (g *grpcHarvester) QueryResources(ctx context.Context, request *harvestpb.QueryResourcesRequest) (*harvestpb.QueryResourcesResponse, error) {
+	filter := &StoredDocumentFilter{
+		Text:    request.GetText(),
+		Domain:  request.GetDomain(),
+		User:    request.GetUser(),
+		Hashtag: request.GetHashtag(),
+		Limit:   int(request.GetLimit()),
+	}
+	if request.GetSinceUnix() > 0 {
+		filter.Since = time.Unix(request.GetSinceUnix(), 0)
+	}
+
+	response := new(harvestpb.QueryResourcesResponse)
+	for _, document := range g.storage.FindStoredDocuments(filter) {
+		response.Resources = append(response.Resources, newResourceMessage(document, true))
+	}
+	return response, nil
+}
+
+// ServeGRPC serves the Harvester gRPC service on addr (e.g. ":9090") until it fails; SubmitText
+// requires adminToken, and is refused without one
+func ServeGRPC(storage *HarvestedResourceStorage, broadcaster *ResourceBroadcaster, logger *zap.Logger, addr string, adminToken string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	harvestpb.RegisterHarvesterServer(server, &grpcHarvester{storage: storage, broadcaster: broadcaster, adminToken: adminToken})
+	logger.Info("Serving gRPC", zap.String("addr", addr))
+	return server.Serve(listener)
+}
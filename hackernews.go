@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// hackerNewsAPI is the Firebase API Hacker News publishes its stories and comments through
+const hackerNewsAPI = "https://hacker-news.firebaseio.com/v0"
+
+// hackerNewsMaxComments is the most comments of a story that are looked at for links
+const hackerNewsMaxComments = 200
+
+type hackerNewsItem struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Text        string `json:"text"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+	Kids        []int  `json:"kids"`
+	Deleted     bool   `json:"deleted"`
+	Dead        bool   `json:"dead"`
+}
+
+// HackerNews is a Source of the stories on one of Hacker News' lists (new, top or best) whose
+// title or text mention one of its keywords (or of all of them, without keywords), and of the
+// links in their comments; the lists are polled every interval
+type HackerNews struct {
+	list     string
+	keywords []string
+	interval time.Duration
+	logger   *zap.Logger
+	client   *http.Client
+	seen     map[int]bool
+}
+
+// NewHackerNews prepares polling list, keeping the stories that contain one of keywords,
+// regardless of case
+func NewHackerNews(list string, keywords []string, interval time.Duration, timeout time.Duration, logger *zap.Logger) *HackerNews {
+	result := new(HackerNews)
+	result.list = list
+	for _, keyword := range keywords {
+		result.keywords = append(result.keywords, strings.ToLower(keyword))
+	}
+	result.interval = interval
+	result.logger = logger
+	result.client = &http.Client{Timeout: timeout}
+	result.seen = make(map[int]bool)
+	return result
+}
+
+// Start implements Source, polling the list until ctx is done
+func (h *HackerNews) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			if err := h.poll(ctx, items); err != nil && ctx.Err() == nil {
+				h.logger.Error("Unable to poll Hacker News", zap.String("list", h.list), zap.Error(err))
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return items
+}
+
+func (h *HackerNews) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, hackerNewsAPI+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// poll sends on the matching stories that weren't on the list the last time
+func (h *HackerNews) poll(ctx context.Context, items chan<- Item) error {
+	var ids []int
+	if err := h.get(ctx, "/"+h.list+"stories.json", &ids); err != nil {
+		return err
+	}
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		seen[id] = true
+		if h.seen[id] {
+			continue
+		}
+		if err := h.story(ctx, id, items); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			h.logger.Warn("Unable to get Hacker News story", zap.Int("id", id), zap.Error(err))
+		}
+	}
+	h.seen = seen
+	return nil
+}
+
+func hackerNewsItemURL(id int) string {
+	return "https://news.ycombinator.com/item?id=" + strconv.Itoa(id)
+}
+
+// story sends on the story with id, if it matches, and its comments that have links
+func (h *HackerNews) story(ctx context.Context, id int, items chan<- Item) error {
+	var story hackerNewsItem
+	if err := h.get(ctx, "/item/"+strconv.Itoa(id)+".json", &story); err != nil {
+		return err
+	}
+	if story.Type != "story" || story.Deleted || story.Dead {
+		return nil
+	}
+	keyword, matches := h.match(story.Title + " " + story.Text)
+	if !matches {
+		return nil
+	}
+
+	provenance := func(item *hackerNewsItem) *Provenance {
+		return &Provenance{Source: "hackernews", Author: item.By, PostURL: hackerNewsItemURL(item.ID), Query: keyword,
+			Score: story.Score, Comments: story.Descendants}
+	}
+	text := story.Title
+	if story.URL != "" {
+		text += " " + story.URL
+	}
+	if story.Text != "" {
+		text += "\n\n" + htmlPostText(story.Text, "")
+	}
+	if !h.send(ctx, items, Item{Text: text, Provenance: provenance(&story)}) {
+		return ctx.Err()
+	}
+
+	// comments are looked at breadth first, so the top level ones are there if it's cut short
+	kids := story.Kids
+	for looked := 0; len(kids) > 0 && looked < hackerNewsMaxComments; looked++ {
+		var comment hackerNewsItem
+		if err := h.get(ctx, "/item/"+strconv.Itoa(kids[0])+".json", &comment); err != nil {
+			return err
+		}
+		kids = append(kids[1:], comment.Kids...)
+		if comment.Deleted || comment.Dead || !strings.Contains(comment.Text, "href=") {
+			continue
+		}
+		if !h.send(ctx, items, Item{Text: htmlPostText(comment.Text, ""), Provenance: provenance(&comment)}) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// match returns the keyword text contains, or true if there are no keywords
+func (h *HackerNews) match(text string) (string, bool) {
+	if len(h.keywords) == 0 {
+		return "", true
+	}
+	text = strings.ToLower(text)
+	for _, keyword := range h.keywords {
+		if strings.Contains(text, keyword) {
+			return keyword, true
+		}
+	}
+	return "", false
+}
+
+func (h *HackerNews) send(ctx context.Context, items chan<- Item, item Item) bool {
+	select {
+	case items <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
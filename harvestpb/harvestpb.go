@@ -0,0 +1,5 @@
+// Package harvestpb has the Go types and gRPC bindings for harvest.proto, generated by
+// protoc-gen-go (with plugins=grpc) from the github.com/golang/protobuf pinned in Gopkg.toml.
+package harvestpb
+
+//go:generate protoc --go_out=plugins=grpc:. harvest.proto
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// defaultStaleStreamAfter is how long the filter stream may go without a message before
+// /healthz reports the harvester as wedged
+const defaultStaleStreamAfter = 10 * time.Minute
+
+type healthReport struct {
+	Status  string        `json:"status"`
+	Problem string        `json:"problem,omitempty"`
+	Stream  *StreamStatus `json:"stream,omitempty"`
+	Storage struct {
+		Writable bool   `json:"writable"`
+		Error    string `json:"error,omitempty"`
+	} `json:"storage"`
+}
+
+// CheckWritable makes sure a file can be created (and removed) in the storage directory
+func (storage *HarvestedResourceStorage) CheckWritable() error {
+	if err := os.MkdirAll(storage.basePath, 0755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(storage.basePath, ".healthz-")
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString("ok")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(file.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// ExpectTweetsWithin makes /healthz fail when nothing has arrived on the filter stream for
+// longer than staleAfter, however often it reconnected; 0 stops checking, for quiet queries
+func (s *HarvesterServer) ExpectTweetsWithin(staleAfter time.Duration) {
+	s.streamMutex.Lock()
+	defer s.streamMutex.Unlock()
+	s.staleAfter = staleAfter
+}
+
+// routeHealth adds the watchdog endpoints:
+//
+//	GET /healthz  fails when the harvester is wedged: storage isn't writable or the stream
+//	              has gone quiet for too long, so it should be restarted
+//	GET /readyz   fails until the stream is connected, or when storage isn't writable
+//
+// Both answer 200 or 503 with the same JSON report.
+func (s *HarvesterServer) routeHealth() {
+	s.router.HandleFunc("/healthz", s.healthz).Methods(http.MethodGet)
+	s.router.HandleFunc("/readyz", s.readyz).Methods(http.MethodGet)
+}
+
+func (s *HarvesterServer) healthReport() *healthReport {
+	report := new(healthReport)
+	report.Status = "ok"
+	if err := s.storage.CheckWritable(); err != nil {
+		report.Storage.Error = err.Error()
+		report.Problem = "storage isn't writable"
+	} else {
+		report.Storage.Writable = true
+	}
+	s.streamMutex.Lock()
+	stream := s.stream
+	s.streamMutex.Unlock()
+	if stream != nil {
+		status := stream.Status()
+		report.Stream = &status
+	}
+	return report
+}
+
+func (s *HarvesterServer) healthz(w http.ResponseWriter, r *http.Request) {
+	report := s.healthReport()
+	s.streamMutex.Lock()
+	staleAfter := s.staleAfter
+	s.streamMutex.Unlock()
+	if report.Problem == "" && report.Stream != nil && staleAfter > 0 {
+		// reconnecting doesn't count, or a stream that keeps dropping would look healthy;
+		// before anything arrived, count from when the stream started
+		lastActivity := report.Stream.LastMessageAt
+		if lastActivity.IsZero() {
+			lastActivity = report.Stream.StartedAt
+		}
+		if time.Since(lastActivity) > staleAfter {
+			report.Problem = "nothing received from the stream since " + lastActivity.Format(time.RFC3339)
+		}
+	}
+	s.writeHealthReport(w, report)
+}
+
+func (s *HarvesterServer) readyz(w http.ResponseWriter, r *http.Request) {
+	report := s.healthReport()
+	if report.Problem == "" && report.Stream != nil && !report.Stream.Connected {
+		report.Problem = "stream isn't connected"
+	}
+	s.writeHealthReport(w, report)
+}
+
+func (s *HarvesterServer) writeHealthReport(w http.ResponseWriter, report *healthReport) {
+	if report.Problem != "" {
+		report.Status = "unavailable"
+		s.writeJSON(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, report)
+}
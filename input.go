@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+	"go.uber.org/zap"
+)
+
+// InputLines is a Source of the lines of a file, or of stdin: a line that's a tweet's JSON
+// (one of a JSONL of tweets) is harvested like a tweet, any other line as text
+type InputLines struct {
+	path   string
+	logger *zap.Logger
+}
+
+// NewInputLines reads the lines of the file at path, or of stdin if path is "-"
+func NewInputLines(path string, logger *zap.Logger) *InputLines {
+	result := new(InputLines)
+	result.path = path
+	result.logger = logger
+	return result
+}
+
+// Start implements Source, reading lines until the input ends or ctx is done
+func (in *InputLines) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		var input io.Reader = os.Stdin
+		if in.path != "-" {
+			file, err := os.Open(in.path)
+			if err != nil {
+				in.logger.Error("Unable to open input", zap.String("input", in.path), zap.Error(err))
+				return
+			}
+			defer file.Close()
+			input = file
+		}
+
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			select {
+			case items <- in.item(line, lineNumber):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			in.logger.Error("Unable to read input", zap.String("input", in.path), zap.Error(err))
+		}
+	}()
+	return items
+}
+
+func (in *InputLines) item(line string, lineNumber int) Item {
+	if strings.HasPrefix(line, "{") {
+		var tweet anaconda.Tweet
+		if err := json.Unmarshal([]byte(line), &tweet); err == nil && tweet.IdStr != "" {
+			return Item{Tweet: &tweet}
+		}
+		in.logger.Debug("Input line isn't a tweet, harvesting it as text", zap.String("input", in.path), zap.Int("line", lineNumber))
+	}
+	return Item{Text: line, Provenance: &Provenance{Source: "input", Channel: in.path}}
+}
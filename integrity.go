@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// addIntegrityHashes records the SHA-256 of document's body and of each of its attachments in
+// its front matter; the front matter itself can't be covered since it holds the hashes
+func addIntegrityHashes(document string, attachments []Attachment) (string, error) {
+	_, body, _ := splitFrontMatter(document)
+	fields := map[string]interface{}{"contentSHA256": sha256Hex([]byte(body))}
+	if len(attachments) > 0 {
+		hashes := make(map[string]string)
+		for _, attachment := range attachments {
+			hashes[attachment.Key] = sha256Hex(attachment.Data)
+		}
+		fields["attachmentsSHA256"] = hashes
+	}
+	return addFrontMatter(document, fields)
+}
+
+// isDocumentKey tells stored documents apart from attachments (which have extensions) and the
+// storage's own state files (which start with a dot)
+func isDocumentKey(key string) bool {
+	return !strings.HasPrefix(key, ".") && filepath.Ext(key) == ""
+}
+
+// ReadDocument reads the document stored under key and, if it has integrity hashes, checks
+// that neither it nor its attachments were corrupted or tampered with
+func (storage *HarvestedResourceStorage) ReadDocument(key string) (string, error) {
+	data, err := storage.diskv.Read(key)
+	if err != nil {
+		return "", err
+	}
+	document := string(data)
+
+	fields, body, err := readFrontMatter(document)
+	if err != nil {
+		return document, fmt.Errorf("%s: unreadable front matter: %v", key, err)
+	}
+	if expected, found := fields["contentSHA256"]; found && expected != sha256Hex([]byte(body)) {
+		return document, fmt.Errorf("%s: content doesn't match its SHA-256", key)
+	}
+	attachments, _ := fields["attachmentsSHA256"].(map[interface{}]interface{})
+	for attachmentKey, expected := range attachments {
+		name := fmt.Sprint(attachmentKey)
+		attachment, err := storage.diskv.Read(name)
+		if err != nil {
+			return document, fmt.Errorf("%s: attachment %s: %v", key, name, err)
+		}
+		if expected != sha256Hex(attachment) {
+			return document, fmt.Errorf("%s: attachment %s doesn't match its SHA-256", key, name)
+		}
+	}
+	return document, nil
+}
+
+// VerifyAll reads every stored document, logging the ones that fail verification, and returns
+// how many documents were checked and how many failed
+func (storage *HarvestedResourceStorage) VerifyAll() (int, int) {
+	checked, failed := 0, 0
+	for key := range storage.diskv.Keys(nil) {
+		if !isDocumentKey(key) {
+			continue
+		}
+		checked++
+		if _, err := storage.ReadDocument(key); err != nil {
+			failed++
+			storage.logger.Error("Verification failed", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return checked, failed
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/Shopify/sarama"
+)
+
+// KafkaPublisher publishes each stored resource as a JSON message to a Kafka topic, keyed by
+// slug so updates to a resource land in the same partition
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// NewKafkaPublisher connects to the brokers and publishes to topic, waiting for every in-sync
+// replica to acknowledge each message
+func NewKafkaPublisher(brokers []string, topic string) (*KafkaPublisher, error) {
+	config := sarama.NewConfig()
+	config.ClientID = "content-harvester-twitter"
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	result := new(KafkaPublisher)
+	result.producer = producer
+	result.topic = topic
+	return result, nil
+}
+
+// WriteResource implements ResourceWriter
+func (k *KafkaPublisher) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	value, err := json.Marshal(newResourceRecord(resource))
+	if err != nil {
+		return err
+	}
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: k.topic,
+		Key:   sarama.StringEncoder(resource.Slug),
+		Value: sarama.ByteEncoder(value),
+	})
+	return err
+}
+
+// Close closes the producer
+func (k *KafkaPublisher) Close() error {
+	return k.producer.Close()
+}
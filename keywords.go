@@ -0,0 +1,108 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	keywordSentenceRegEx = regexp.MustCompile(`[.,;:!?()\[\]"“”‘’\n\t]+`)
+	keywordWordRegEx     = regexp.MustCompile(`[\p{L}\p{N}'-]+`)
+	keywordNumberRegEx   = regexp.MustCompile(`^[\p{N}.,-]+$`)
+)
+
+// maxKeywordPhraseWords keeps RAKE from promoting whole clauses to keywords
+const maxKeywordPhraseWords = 3
+
+var keywordStopWords = stopWordSet(`a about above after again against all also am an and any are as at be because been
+before being below between both but by can could did do does doing down during each few for from further had has
+have having he her here hers herself him himself his how i if in into is it its itself just me more most my
+myself no nor not now of off on once only or other our ours ourselves out over own same she should so some such
+than that the their theirs them themselves then there these they this those through to too under until up very
+was we were what when where which while who whom why will with would you your yours yourself yourselves said says
+new one two like get got make made many much may might must us via per yet however still well even back way`)
+
+func stopWordSet(words string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		result[word] = true
+	}
+	return result
+}
+
+// extractKeywords ranks the phrases in text with RAKE (Rapid Automatic Keyword Extraction) and
+// returns the top count of them; unlike TF-IDF it works on a single document, no corpus needed
+func extractKeywords(text string, count int) []string {
+	var phrases [][]string
+	for _, sentence := range keywordSentenceRegEx.Split(strings.ToLower(text), -1) {
+		var phrase []string
+		for _, word := range keywordWordRegEx.FindAllString(sentence, -1) {
+			if keywordStopWords[word] || keywordNumberRegEx.MatchString(word) || len(word) < 3 {
+				if len(phrase) > 0 {
+					phrases = append(phrases, phrase)
+				}
+				phrase = nil
+				continue
+			}
+			phrase = append(phrase, word)
+		}
+		if len(phrase) > 0 {
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	frequency := make(map[string]float64)
+	degree := make(map[string]float64)
+	for _, phrase := range phrases {
+		if len(phrase) > maxKeywordPhraseWords {
+			continue
+		}
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += float64(len(phrase))
+		}
+	}
+
+	scores := make(map[string]float64)
+	for _, phrase := range phrases {
+		if len(phrase) > maxKeywordPhraseWords {
+			continue
+		}
+		var score float64
+		for _, word := range phrase {
+			score += degree[word] / frequency[word]
+		}
+		scores[strings.Join(phrase, " ")] = score
+	}
+
+	keywords := make([]string, 0, len(scores))
+	for keyword := range scores {
+		keywords = append(keywords, keyword)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if scores[keywords[i]] != scores[keywords[j]] {
+			return scores[keywords[i]] > scores[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	if len(keywords) > count {
+		keywords = keywords[:count]
+	}
+	return keywords
+}
+
+// keywordEnricher records the top keywords of the extracted article text, so it has to run
+// after article (or PDF) extraction
+type keywordEnricher struct {
+	count int
+}
+
+func (e keywordEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	if resource.ArticleText == "" {
+		return
+	}
+	if keywords := extractKeywords(resource.ArticleText, e.count); len(keywords) > 0 {
+		resource.FrontMatter["keywords"] = keywords
+	}
+}
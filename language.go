@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/abadojack/whatlanggo"
+)
+
+// detectLanguage returns the ISO 639-1 code of the language text is written in, or "" if the
+// text doesn't say enough about it (short tweets often don't)
+func detectLanguage(text string) string {
+	info := whatlanggo.Detect(text)
+	if !info.IsReliable() {
+		return ""
+	}
+	return info.Lang.Iso6391()
+}
+
+// articleLanguageEnricher records the language of the extracted article text, so it has to run
+// after article (or PDF) extraction
+type articleLanguageEnricher struct{}
+
+func (articleLanguageEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	if resource.ArticleText == "" {
+		return
+	}
+	if lang := detectLanguage(resource.ArticleText); lang != "" {
+		resource.FrontMatter["articleLang"] = lang
+	}
+}
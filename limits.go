@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RunLimit ends a run after a number of tweets or a length of time, whichever comes first;
+// zero means no limit
+type RunLimit struct {
+	mutex     sync.Mutex
+	maxTweets int
+	tweets    int
+	once      sync.Once
+	done      chan struct{}
+}
+
+// NewRunLimit starts counting maxDuration from now
+func NewRunLimit(maxTweets int, maxDuration time.Duration) *RunLimit {
+	result := new(RunLimit)
+	result.maxTweets = maxTweets
+	result.done = make(chan struct{})
+	if maxDuration > 0 {
+		time.AfterFunc(maxDuration, result.Stop)
+	}
+	return result
+}
+
+// Done is closed once the limit is reached
+func (l *RunLimit) Done() <-chan struct{} {
+	return l.done
+}
+
+// Context returns a context that's done once the limit is reached, for the sources to stop by
+func (l *RunLimit) Context() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-l.done
+		cancel()
+	}()
+	return ctx
+}
+
+// Stop ends the run now
+func (l *RunLimit) Stop() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// allowTweet counts a tweet and returns false once the run shouldn't take any more
+func (l *RunLimit) allowTweet() bool {
+	select {
+	case <-l.done:
+		return false
+	default:
+	}
+	if l.maxTweets == 0 {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.tweets >= l.maxTweets {
+		return false
+	}
+	l.tweets++
+	if l.tweets == l.maxTweets {
+		l.Stop()
+	}
+	return true
+}
+
+// LimitTo stops harvesting tweets once limit is reached
+func (h *TweetHarvester) LimitTo(limit *RunLimit) {
+	h.limit = limit
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"go.uber.org/zap"
+)
+
+// HarvestList stores every link shared by the members of a Twitter List, identified either as
+// owner/slug or by its numeric ID
+func HarvestList(ctx context.Context, scheduler *twitter.RateLimitScheduler, tweets *TweetHarvester, logger *zap.Logger, list string) {
+	provenance := &Provenance{List: list}
+	var page timelinePage
+	if listID, err := strconv.ParseInt(list, 10, 64); err == nil {
+		page = func(api *anaconda.TwitterApi, v url.Values) ([]anaconda.Tweet, error) {
+			return api.GetListTweets(listID, true, v)
+		}
+	} else {
+		owner, slug := splitListName(list)
+		if owner == "" || slug == "" {
+			logger.Error("Lists should be given as owner/slug or as a list ID", zap.String("list", list))
+			return
+		}
+		page = func(api *anaconda.TwitterApi, v url.Values) ([]anaconda.Tweet, error) {
+			return api.GetListTweetsBySlug(slug, owner, true, v)
+		}
+	}
+
+	err := walkTimeline(ctx, scheduler, "lists", "/lists/statuses", page, func(tweet anaconda.Tweet) {
+		tweets.Harvest(tweet, provenance)
+	})
+	if err != nil {
+		logger.Error("Unable to read list timeline", zap.String("list", list), zap.Error(err))
+	}
+}
+
+func splitListName(list string) (string, string) {
+	parts := strings.SplitN(list, "/", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return strings.TrimPrefix(parts[0], "@"), parts[1]
+}
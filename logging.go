@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LogOptions configure the logger every part of the harvester shares
+type LogOptions struct {
+	Level  string
+	Format string
+	// File is written to instead of stderr when it's set, rotated once it's MaxSize megabytes
+	File       string
+	MaxSize    int
+	MaxBackups int
+	MaxAge     int
+}
+
+// NewLogger builds a logger as NewProduction does (JSON on stderr, callers, stack traces for
+// errors) unless the options say otherwise; console is the human readable format for local
+// debugging
+func NewLogger(options LogOptions) (*zap.Logger, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(options.Level)); err != nil {
+		return nil, err
+	}
+
+	var encoder zapcore.Encoder
+	switch options.Format {
+	case "", "json":
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case "console":
+		config := zap.NewDevelopmentEncoderConfig()
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		if options.File == "" {
+			config.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		encoder = zapcore.NewConsoleEncoder(config)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected json or console", options.Format)
+	}
+
+	output := zapcore.Lock(os.Stderr)
+	if options.File != "" {
+		output = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   options.File,
+			MaxSize:    options.MaxSize,
+			MaxBackups: options.MaxBackups,
+			MaxAge:     options.MaxAge,
+		})
+	}
+	return zap.New(zapcore.NewCore(encoder, output, level), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
@@ -1,38 +1,323 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"text/template"
 	"time"
 
 	"github.com/ChimeraCoder/anaconda"
 	"github.com/coreos/pkg/flagutil"
 	"github.com/peterbourgon/diskv"
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"github.com/shah/content-harvester-twitter/pkg/store"
+	"github.com/shah/content-harvester-twitter/pkg/transport"
 	"github.com/shah/content-harvester-utils"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 // HarvestedResourceStorage is the database for harvested resources
 type HarvestedResourceStorage struct {
-	basePath         string
-	diskv            *diskv.Diskv
-	logger           *zap.Logger
-	contentHarvester *harvester.ContentHarvester
-	markdown         map[*harvester.HarvestedResourceKeys]*strings.Builder
-	serializer       harvester.HarvestedResourcesSerializer
+	basePath             string
+	layout               *store.Layout
+	diskv                *diskv.Diskv
+	compression          diskv.Compression
+	logger               *zap.Logger
+	contentHarvester     *harvester.ContentHarvester
+	markdown             map[*harvester.HarvestedResourceKeys]*strings.Builder
+	serializer           harvester.HarvestedResourcesSerializer
+	seen                 *SeenResourcesIndex
+	seenBefore           *BloomFilter
+	seenBeforeSaved      time.Time
+	manifest             *store.Manifest
+	slugs                *slugClaims
+	fetcher              *PageFetcher
+	resolveCanonical     bool
+	enrichers            []PageEnricher
+	destinationEnrichers []DestinationEnricher
+	contentTypes         *ContentTypeFilter
+	onlyDomains          filter.DomainList
+	domainCategories     *filter.DomainCategories
+	excludedCategories   map[string]bool
+	screeners            []URLScreener
+	dropUnsafe           bool
+	integrityHashes      bool
+	documentTemplate     *template.Template
+	outputFormat         string
+	writer               ResourceWriter
+	asyncWriter          *AsyncResourceWriter
+	writeRetries         int
+	deadLetters          *store.DeadLetters
+	outputs              []ResourceWriter
+	csvLog               *CSVHarvestLog
+	ignoreObservers      []IgnoreObserver
+	saving               sync.Mutex
+	summary              *HarvestSummary
+	dryRun               io.Writer
+	diagnoser            *URLErrorDiagnoser
+	recordURLErrors      bool
 }
 
-// SaveAllInText all harvested resources into the database
-func (storage *HarvestedResourceStorage) SaveAllInText(text string) {
-	r := storage.contentHarvester.HarvestResources(text)
+// RecordIntegrityHashes makes the storage record SHA-256 hashes of every document it stores
+// and of the document's attachments, which ReadDocument then verifies
+func (storage *HarvestedResourceStorage) RecordIntegrityHashes() {
+	storage.integrityHashes = true
+}
+
+// FilterContentTypes makes the storage skip resources whose destination has a denied content
+// type or, when allow isn't empty, a content type that's not in it
+func (storage *HarvestedResourceStorage) FilterContentTypes(allow contentTypeList, deny contentTypeList) {
+	storage.contentTypes = &ContentTypeFilter{fetcher: storage.fetcher, allow: allow, deny: deny}
+}
+
+// DeduplicateResources makes the storage skip resources whose cleaned URL was already stored,
+// counting the repeats in an index kept in the storage directory instead
+func (storage *HarvestedResourceStorage) DeduplicateResources() error {
+	seen, err := NewSeenResourcesIndex(filepath.Join(storage.basePath, seenResourcesIndexFile))
+	if err != nil {
+		return err
+	}
+	storage.seen = seen
+	return nil
+}
+
+// DeduplicateAcrossRuns makes the storage skip resources harvested by any previous run sharing
+// the Bloom filter persisted at path
+func (storage *HarvestedResourceStorage) DeduplicateAcrossRuns(path string, capacity int) error {
+	seenBefore, err := NewBloomFilter(path, capacity, 0.001)
+	if err != nil {
+		return err
+	}
+	storage.seenBefore = seenBefore
+	storage.seenBeforeSaved = time.Now()
+	return nil
+}
+
+// Close persists any state the storage keeps in memory
+func (storage *HarvestedResourceStorage) Close() {
+	if storage.asyncWriter != nil {
+		storage.asyncWriter.Close()
+	}
+	if storage.csvLog != nil {
+		if err := storage.csvLog.Close(); err != nil {
+			storage.logger.Error("Unable to close CSV file", zap.Error(err))
+		}
+	}
+	for _, output := range storage.outputs {
+		if closer, ok := output.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				storage.logger.Error("Unable to close output", zap.Error(err))
+			}
+		}
+	}
+	if storage.dryRun != nil {
+		return
+	}
+	if storage.seen != nil {
+		if err := storage.seen.Save(); err != nil {
+			storage.logger.Error("Unable to save seen URLs index", zap.Error(err))
+		}
+	}
+	if storage.seenBefore != nil {
+		if err := storage.seenBefore.Save(); err != nil {
+			storage.logger.Error("Unable to save seen URLs filter", zap.Error(err))
+		}
+	}
+	if storage.manifest != nil {
+		if err := storage.manifest.Save(); err != nil {
+			storage.logger.Error("Unable to save manifest", zap.Error(err))
+		}
+	}
+}
+
+// DryRun makes the storage harvest, resolve and clean URLs as usual but only print to out what
+// it would save or ignore, without writing anything
+func (storage *HarvestedResourceStorage) DryRun(out io.Writer) {
+	storage.dryRun = out
+}
+
+// Provenance describes where the text being harvested came from
+type Provenance struct {
+	// Source, Channel, Author and PostURL say where items that aren't tweets come from, e.g.
+	// rss, the feed, the entry's author and its link
+	Source  string
+	Channel string
+	Author  string
+	PostURL string
+	// Score and Comments are the post's votes and comment count, where the source has them
+	Score     int
+	Comments  int
+	Query     string
+	Timeline  string
+	List      string
+	Tweet     *anaconda.Tweet
+	Spam      *SpamAssessment
+	Media     []string
+	Lang      string
+	Sentiment *Sentiment
+}
+
+// addFrontMatter records the provenance in the front matter fields of a stored resource
+func (provenance *Provenance) addFrontMatter(fields map[string]interface{}) {
+	if provenance == nil {
+		return
+	}
+	if provenance.Source != "" {
+		fields["source"] = provenance.Source
+	}
+	if provenance.Channel != "" {
+		fields["channel"] = provenance.Channel
+	}
+	if provenance.Author != "" {
+		fields["author"] = provenance.Author
+	}
+	if provenance.PostURL != "" {
+		fields["postURL"] = provenance.PostURL
+	}
+	if provenance.Score != 0 {
+		fields["score"] = provenance.Score
+	}
+	if provenance.Comments != 0 {
+		fields["comments"] = provenance.Comments
+	}
+	if provenance.Query != "" {
+		fields["query"] = provenance.Query
+	}
+	if provenance.Timeline != "" {
+		fields["timeline"] = provenance.Timeline
+	}
+	if provenance.List != "" {
+		fields["list"] = provenance.List
+	}
+	if provenance.Tweet != nil {
+		fields["tweetID"] = provenance.Tweet.IdStr
+		fields["user"] = provenance.Tweet.User.ScreenName
+		hashtags, mentions, cashtags := tweetTags(provenance.Tweet)
+		if len(hashtags) > 0 {
+			fields["hashtags"] = hashtags
+		}
+		if len(mentions) > 0 {
+			fields["mentions"] = mentions
+		}
+		if len(cashtags) > 0 {
+			fields["cashtags"] = cashtags
+		}
+	}
+	if provenance.Lang != "" {
+		fields["lang"] = provenance.Lang
+	}
+	if provenance.Sentiment != nil {
+		fields["sentimentScore"] = provenance.Sentiment.Score
+		fields["sentiment"] = provenance.Sentiment.Label
+	}
+	if len(provenance.Media) > 0 {
+		fields["media"] = provenance.Media
+	}
+	if provenance.Spam != nil {
+		fields["spamScore"] = math.Round(provenance.Spam.Score*100) / 100
+		if len(provenance.Spam.Signals) > 0 {
+			fields["spamSignals"] = provenance.Spam.Signals
+		}
+	}
+}
+
+// written records a resource that's been written to the store: only what's stored counts as
+// seen, so resources that failed are harvested again
+func (storage *HarvestedResourceStorage) written(resource *DocumentTemplateData, document string, attachments []Attachment) {
+	storage.slugs.claim(resource.Slug, resource.CleanedURL)
+	if storage.seen != nil {
+		storage.seen.Add(resource.CleanedURL, resource.Slug)
+	}
+	if storage.seenBefore != nil {
+		storage.seenBefore.Add(resource.CleanedURL)
+	}
+	resourcesCounter.WithLabelValues("saved").Inc()
+	if storage.summary != nil {
+		storage.summary.resourceSaved(resource.FinalURL)
+	}
+	domainResourcesCounter.WithLabelValues(destinationDomain(resource.FinalURL)).Inc()
+	if storage.manifest != nil {
+		storage.manifest.Add(manifestEntry(newStoredDocument(resource, document)))
+	}
+	for _, output := range storage.outputs {
+		if err := output.WriteResource(resource, document, attachments); err != nil {
+			storageWriteErrorsCounter.Inc()
+			storage.logger.Error("Unable to write resource to output", zap.String("slug", resource.Slug), zap.Error(err))
+		}
+	}
+}
+
+// saveIndexes saves the seen URLs index and, every now and then, the filter and manifest
+func (storage *HarvestedResourceStorage) saveIndexes() {
+	if storage.seen != nil {
+		if err := storage.seen.Save(); err != nil {
+			storage.logger.Error("Unable to save seen URLs index", zap.Error(err))
+		}
+	}
+	// the filter is a fixed size blob so we only write it out every now and then
+	if storage.seenBefore != nil && time.Since(storage.seenBeforeSaved) > time.Minute {
+		if err := storage.seenBefore.Save(); err != nil {
+			storage.logger.Error("Unable to save seen URLs filter", zap.Error(err))
+		}
+		storage.seenBeforeSaved = time.Now()
+	}
+	// like the filter, the manifest grows with the store
+	if storage.manifest != nil && time.Since(storage.manifest.SavedAt()) > time.Minute {
+		if err := storage.manifest.Save(); err != nil {
+			storage.logger.Error("Unable to save manifest", zap.Error(err))
+		}
+	}
+}
+
+// SaveAllInText all harvested resources into the database and returns the slugs they were stored under;
+// its spans are children of the one in ctx
+func (storage *HarvestedResourceStorage) SaveAllInText(ctx context.Context, text string, provenance *Provenance) []string {
+	ctx, span := startSpan(ctx, "save resources", attribute.String("query", provenanceQuery(provenance)))
+	defer span.End()
+
+	// resolving and diagnosing URLs takes a while, other texts are saved meanwhile
+	storage.saving.Lock()
+	contentHarvester := storage.contentHarvester
+	storage.saving.Unlock()
+	_, resolveSpan := startSpan(ctx, "resolve urls")
+	harvestStarted := time.Now()
+	r := contentHarvester.HarvestResources(text)
+	resolutionHistogram.Observe(time.Since(harvestStarted).Seconds())
+	resolveSpan.SetAttributes(attribute.Int("resources", len(r.Resources)))
+	resolveSpan.End()
+	if storage.summary != nil {
+		storage.summary.urlsFound(len(r.Resources))
+	}
+	reasons := storage.invalidURLReasons(r.Resources)
+
+	// the serializer collects into storage.markdown so only one text can be saved at a time
+	storage.saving.Lock()
+	defer storage.saving.Unlock()
+
+	var stored []string
+	storage.logInvalidURLs(text, provenance, r.Resources, reasons)
+	if storage.csvLog != nil && storage.dryRun == nil {
+		if err := storage.csvLog.Write(text, r.Resources, reasons); err != nil {
+			storage.logger.Error("Unable to write CSV rows", zap.String("source", text), zap.Error(err))
+		}
+	}
+	storage.observeHarvesterIgnores(text, provenance, r.Resources, reasons)
 
 	storage.markdown = make(map[*harvester.HarvestedResourceKeys]*strings.Builder)
 	r.Serialize(storage.serializer)
@@ -49,17 +334,184 @@ func (storage *HarvestedResourceStorage) SaveAllInText(text string) {
 		}
 
 		finalURL, resolvedURL, cleanedURL := res.GetURLs()
+		if ignore, reason := storage.ignoreDomain(finalURL); ignore {
+			storage.logger.Info("Ignored", zap.String("source", text),
+				zap.String("originalURLText", res.OriginalURLText()),
+				zap.String("reason", reason),
+				zap.String("finalURL", urlToString(finalURL)),
+			)
+			storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "filtered", reason)
+			continue
+		}
+		if ignore, reason := storage.ignoreCategory(finalURL); ignore {
+			storage.logger.Info("Ignored", zap.String("source", text),
+				zap.String("originalURLText", res.OriginalURLText()),
+				zap.String("reason", reason),
+				zap.String("finalURL", urlToString(finalURL)),
+			)
+			storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "filtered", reason)
+			continue
+		}
+		threat := storage.screen(finalURL)
+		if threat != "" && storage.dropUnsafe {
+			reason := fmt.Sprintf("Unsafe destination (%s)", threat)
+			storage.logger.Warn("Ignored", zap.String("source", text),
+				zap.String("originalURLText", res.OriginalURLText()),
+				zap.String("reason", reason),
+				zap.String("finalURL", urlToString(finalURL)),
+			)
+			storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "filtered", reason)
+			continue
+		}
+		// the canonical URL is the one the resource is known by, for the slug and dedupe too
+		var page *FetchedPage
+		var nonCanonicalURL *url.URL
+		if storage.resolveCanonical {
+			if page = storage.fetchDestination(ctx, finalURL); page != nil {
+				if canonical, _ := canonicalURL(page); canonical != nil && urlToString(canonical) != urlToString(cleanedURL) {
+					nonCanonicalURL, cleanedURL = cleanedURL, canonical
+				}
+			}
+		}
+		if storage.seen != nil {
+			seen, duplicate := storage.seen.Hit(urlToString(cleanedURL))
+			if duplicate {
+				storage.logger.Info("Duplicate", zap.String("source", text),
+					zap.String("originalURLText", res.OriginalURLText()),
+					zap.String("slug", seen.Slug),
+					zap.Int("hits", seen.Hits),
+					zap.String("cleanedURL", urlToString(cleanedURL)),
+				)
+				storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "duplicate", "Duplicate")
+				continue
+			}
+		}
+		if storage.seenBefore != nil && storage.seenBefore.Test(urlToString(cleanedURL)) {
+			storage.logger.Info("Harvested in a previous run", zap.String("source", text),
+				zap.String("originalURLText", res.OriginalURLText()),
+				zap.String("cleanedURL", urlToString(cleanedURL)),
+			)
+			storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "duplicate", "Harvested in a previous run")
+			continue
+		}
+
+		if storage.contentTypes != nil && finalURL != nil {
+			if ignore, reason := storage.contentTypes.IgnoreDestination(finalURL); ignore {
+				storage.logger.Info("Ignored", zap.String("source", text),
+					zap.String("originalURLText", res.OriginalURLText()),
+					zap.String("reason", reason),
+					zap.String("finalURL", urlToString(finalURL)),
+				)
+				storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "filtered", reason)
+				continue
+			}
+		}
+
+		// only resources that are going to be written reserve their slug
+		slug := storage.storageKey(keys.Slug(), urlToString(cleanedURL))
+		if slug != keys.Slug() {
+			storage.logger.Warn("Slug collision", zap.String("source", text),
+				zap.String("originalURLText", res.OriginalURLText()),
+				zap.String("slug", keys.Slug()),
+				zap.String("versionedSlug", slug),
+				zap.String("cleanedURL", urlToString(cleanedURL)),
+			)
+		}
+		storage.layout.Place(slug, destinationDomain(urlToString(finalURL)))
+		harvestedAt := time.Now()
+		enriched := &EnrichedResource{Slug: slug, FrontMatter: make(map[string]interface{})}
+		// enough to build feeds and reports from the store without going back to the harvester
+		enriched.FrontMatter["harvestedAt"] = harvestedAt.Format(time.RFC3339)
+		enriched.FrontMatter["finalURL"] = urlToString(finalURL)
+		enriched.FrontMatter["cleanedURL"] = urlToString(cleanedURL)
+		if nonCanonicalURL != nil {
+			enriched.FrontMatter["nonCanonicalURL"] = urlToString(nonCanonicalURL)
+		}
+		if threat != "" {
+			enriched.FrontMatter["unsafe"] = threat
+		}
+		provenance.addFrontMatter(enriched.FrontMatter)
+		enrichCtx, enrichSpan := startSpan(ctx, "enrich", attribute.String("slug", slug), urlHostAttribute("destination", finalURL))
+		storage.enrich(enrichCtx, finalURL, page, enriched)
+		enrichSpan.End()
+		resource := &DocumentTemplateData{
+			Slug:        slug,
+			OriginalURL: res.OriginalURLText(),
+			FinalURL:    urlToString(finalURL),
+			ResolvedURL: urlToString(resolvedURL),
+			CleanedURL:  urlToString(cleanedURL),
+			Text:        text,
+			HarvestedAt: harvestedAt,
+			Provenance:  provenance,
+			FrontMatter: enriched.FrontMatter,
+			Sections:    enriched.Sections,
+			Serialized:  markdown.String(),
+		}
+		document, fmErr := storage.composeDocument(resource)
+		if fmErr != nil {
+			if storage.dryRun != nil {
+				fmt.Fprintf(storage.dryRun, "Would fail to compose %s: %v\n", slug, fmErr)
+				storage.slugs.release(slug)
+			} else {
+				storage.storeFailed(resource, "", enriched.Attachments, "compose", 1, fmErr)
+			}
+			continue
+		}
+		if storage.integrityHashes {
+			if hashed, err := addIntegrityHashes(document, enriched.Attachments); err != nil {
+				storage.logger.Error("Unable to add integrity hashes", zap.String("source", text),
+					zap.String("slug", slug),
+					zap.Error(err))
+			} else {
+				document = hashed
+			}
+		}
+
+		if storage.dryRun != nil {
+			fmt.Fprintf(storage.dryRun, "Would save %s as %s\n", urlToString(cleanedURL), slug)
+			storage.slugs.claim(slug, urlToString(cleanedURL))
+			// the index isn't saved, but still tells of duplicates within the run
+			if storage.seen != nil {
+				storage.seen.Add(urlToString(cleanedURL), slug)
+			}
+			if storage.seenBefore != nil {
+				storage.seenBefore.Add(urlToString(cleanedURL))
+			}
+			if storage.summary != nil {
+				storage.summary.resourceSaved(urlToString(finalURL))
+			}
+			stored = append(stored, slug)
+			continue
+		}
+
 		storage.logger.Info("Saving", zap.String("source", text),
+			zap.String("query", provenanceQuery(provenance)),
 			zap.String("originalURLText", res.OriginalURLText()),
-			zap.String("slug", keys.Slug()),
+			zap.String("slug", slug),
 			zap.String("referredBy", resourceToString(res.ReferredByResource())),
 			zap.String("finalURL", urlToString(finalURL)),
 			zap.String("resolvedURL", urlToString(resolvedURL)),
 			zap.String("cleanedURL", urlToString(cleanedURL)),
 		)
 
-		storage.diskv.Write(keys.Slug(), []byte(markdown.String()))
+		_, writeSpan := startSpan(ctx, "write", attribute.String("slug", slug))
+		attempts, err := writeWithRetries(storage.writer, resource, document, enriched.Attachments, storage.writeRetries)
+		endSpan(writeSpan, err)
+		if err != nil {
+			storage.storeFailed(resource, document, enriched.Attachments, "write", attempts, err)
+			continue
+		}
+		// the async writer calls written itself once the resource is actually written
+		if storage.asyncWriter == nil {
+			storage.written(resource, document, enriched.Attachments)
+		}
+		stored = append(stored, slug)
+	}
+
+	if storage.dryRun == nil && storage.asyncWriter == nil {
+		storage.saveIndexes()
 	}
+	return stored
 
 	// for _, res := range r.Resources {
 	// 	isURLValid, isDestValid := res.IsValid()
@@ -127,26 +579,35 @@ func (storage *HarvestedResourceStorage) SaveAllInText(text string) {
 }
 
 // NewHarvestedResourceStorage that can persist harvested resources
-func NewHarvestedResourceStorage(contentHarvester *harvester.ContentHarvester, logger *zap.Logger, basePath string) *HarvestedResourceStorage {
+func NewHarvestedResourceStorage(contentHarvester *harvester.ContentHarvester, logger *zap.Logger, basePath string, layout *store.Layout, compression diskv.Compression) *HarvestedResourceStorage {
 	result := new(HarvestedResourceStorage)
 	result.contentHarvester = contentHarvester
 	result.logger = logger
 	result.basePath = basePath
+	result.layout = layout
+	result.compression = compression
+	result.slugs = newSlugClaims(slugClaimsCapacity)
 	tmpl, tmplErr := template.ParseFiles("../content-harvester-utils/serialize.md.tmpl")
 	if tmplErr != nil {
 		panic(tmplErr)
 	}
 
 	// Simplest transform function: put all the data files into the base dir.
-	flatTransform := func(s string) []string { return []string{} }
+	transform := func(s string) []string { return []string{} }
+	if layout != nil {
+		transform = layout.Transform
+	}
 
 	// Initialize a new diskv store, rooted at "my-data-dir", with a 1MB cache.
 	result.diskv = diskv.New(diskv.Options{
 		BasePath:     basePath,
-		Transform:    flatTransform,
+		Transform:    transform,
 		CacheSizeMax: 1024 * 1024,
+		Compression:  compression,
 	})
 
+	result.writer = diskvResourceWriter{diskv: result.diskv}
+
 	result.serializer = harvester.HarvestedResourcesSerializer{
 		GetKeys: func(hr *harvester.HarvestedResource) *harvester.HarvestedResourceKeys {
 			return harvester.CreateHarvestedResourceKeys(hr, func(random uint32, try int) bool {
@@ -180,9 +641,6 @@ func NewHarvestedResourceStorage(contentHarvester *harvester.ContentHarvester, l
 // The Streaming API is being retired in June:
 //   https://blog.twitter.com/developer/en_us/topics/tools/2017/announcing-more-functionality-to-improve-customer-engagements-on-twitter.html
 
-// TODO use http://websocketd.com/ to turn this into a streaming server
-// TODO if using straight HTTP REST (and not GraphQL) consider https://github.com/gorilla/mux
-
 // TODO use https://www.lukemorton.co.uk/thoughts/2017-01-15-deploying-go-on-zeit-now to figure
 // how to run this on Zeit (like Node.js versions)
 
@@ -259,6 +717,13 @@ func resourceToString(hr *harvester.HarvestedResource) string {
 	return urlToString(referrerURL)
 }
 
+func provenanceQuery(provenance *Provenance) string {
+	if provenance == nil {
+		return ""
+	}
+	return provenance.Query
+}
+
 func urlToString(url *url.URL) string {
 	if url == nil {
 		return ""
@@ -267,41 +732,34 @@ func urlToString(url *url.URL) string {
 }
 
 func createTweetTestData(contentHarvester *harvester.ContentHarvester, csvWriter *csv.Writer, tweet string) {
-	time := time.Now().Format("01-02 15:04:05")
 	r := contentHarvester.HarvestResources(tweet)
-	tweetText := removeNewLinesRegEx.ReplaceAllString(tweet, " ")
-	for _, res := range r.Resources {
-		isURLValid, isDestValid := res.IsValid()
-		if !isURLValid {
-			csvWriter.Write([]string{time, tweetText, res.OriginalURLText(), "Invalid URL", "Not sure why"})
-			continue
-		}
-		if !isDestValid {
-			isIgnored, ignoreReason := res.IsIgnored()
-			if isIgnored {
-				csvWriter.Write([]string{time, tweetText, res.OriginalURLText(), "Invalid URL Destination", ignoreReason})
-			} else {
-				csvWriter.Write([]string{time, tweetText, res.OriginalURLText(), "Invalid URL Destination", "Unknown reason"})
-			}
-			continue
-		}
-		finalURL, resolvedURL, cleanedURL := res.GetURLs()
-		isIgnored, ignoreReason := res.IsIgnored()
-		if isIgnored {
-			csvWriter.Write([]string{time, tweetText, res.OriginalURLText(), "Ignored", ignoreReason, resourceToString(res.ReferredByResource()), urlToString(finalURL), urlToString(resolvedURL)})
-			continue
-		}
-
-		csvWriter.Write([]string{time, tweetText, res.OriginalURLText(), "Resolved", "Success", resourceToString(res.ReferredByResource()), urlToString(finalURL), urlToString(resolvedURL), urlToString(cleanedURL)})
-	}
-	csvWriter.Flush()
+	csvWriter.WriteAll(harvestedResourceRows(tweet, r.Resources, nil))
 }
 
 func main() {
-	// TODO add ability to configure hooks or GraphQL subscriptions for outbound event calls
 	var twitterQuery textList
-	var ignoreURLsRegEx ignoreURLsRegExList
-	var removeParamsFromURLsRegEx cleanURLsRegExList
+	var timelines textList
+	var lists textList
+	var mastodonHashtags textList
+	var blueskyKeywords textList
+	var subreddits textList
+	var hackerNewsKeywords textList
+	var rssFeeds textList
+	var telegramChannels textList
+	var inputs textList
+	var followUsers textList
+	var languages languageList
+	var geoBBox geoBoundingBox
+	var onlyUsers textList
+	var blockUsers textList
+	var allowContentTypes contentTypeList
+	var onlyDomains filter.DomainList
+	var sampleDomains filter.DomainList
+	var excludeCategories textList
+	var denyContentTypes contentTypeList
+	var outputs textList
+	var webhookURLs textList
+	var reloadable ReloadableConfig
 
 	// I've created this Twitter App: https://apps.twitter.com/app/15163306
 	flags := flag.NewFlagSet("options", flag.ExitOnError)
@@ -309,64 +767,960 @@ func main() {
 	consumerSecret := flags.String("consumer-secret", "", "Twitter Consumer Secret")
 	accessToken := flags.String("access-token", "", "Twitter Access Token")
 	accessSecret := flags.String("access-secret", "", "Twitter Access Secret")
+	credentialsFile := flags.String("credentials-file", "", "File with one set of Twitter credentials per line (consumer key, consumer secret, access token and access secret separated by spaces) to rotate among when rate limits are hit, besides the one given with -consumer-key etc.")
 	filterTwitterStream := flags.Bool("filter-stream", false, "Search for content in a continuous Twitter filter (until Ctrl+C is pressed)")
 	searchTwitter := flags.Bool("search", false, "Search for content in Twitter and return results")
+	sampleStream := flags.Bool("sample-stream", false, "Harvest the tweets in Twitter's sampled stream that match -query and -sample-domain, until Ctrl+C is pressed")
+	fullArchiveSearch := flags.Bool("full-archive-search", false, "Search Twitter's full archive with the v2 API (Academic or paid access) for -query, between -search-start-time and -search-end-time")
+	bearerToken := flags.String("bearer-token", "", "Twitter app's Bearer Token, which -full-archive-search uses; best given as TWITTER_BEARER_TOKEN")
+	searchStartTime := flags.String("search-start-time", "", "Oldest tweets -full-archive-search harvests (RFC 3339, e.g. 2019-01-01T00:00:00Z; 30 days before -search-end-time if not given)")
+	searchEndTime := flags.String("search-end-time", "", "Newest tweets -full-archive-search harvests (RFC 3339; now if not given)")
+	mastodonInstance := flags.String("mastodon-instance", "", "Also harvest the statuses streamed by this Mastodon instance (e.g. https://mastodon.social)")
+	mastodonAccessToken := flags.String("mastodon-access-token", "", "Access token for -mastodon-instance, which most instances require; best given as TWITTER_MASTODON_ACCESS_TOKEN")
+	mastodonLocal := flags.Bool("mastodon-local", false, "Only harvest the statuses posted on -mastodon-instance itself")
+	bluesky := flags.Bool("bluesky", false, "Also harvest the posts on Bluesky matching -bluesky-keyword, from the firehose")
+	blueskyJetstream := flags.String("bluesky-jetstream", defaultJetstream, "Jetstream URL the Bluesky firehose is read from")
+	redditClientID := flags.String("reddit-client-id", "", "Reddit app client ID, for -subreddit")
+	redditClientSecret := flags.String("reddit-client-secret", "", "Reddit app client secret, for -subreddit; best given as TWITTER_REDDIT_CLIENT_SECRET")
+	redditSort := flags.String("reddit-sort", "new", "Which listing of the subreddits is polled: new or hot")
+	redditPollInterval := flags.Duration("reddit-poll-interval", 2*time.Minute, "How often the subreddits are polled")
+	hackerNews := flags.Bool("hn", false, "Also harvest the Hacker News stories matching -hn-keyword, and the links in their comments")
+	hackerNewsList := flags.String("hn-list", "new", "Which Hacker News stories are polled: new, top or best")
+	hackerNewsPollInterval := flags.Duration("hn-poll-interval", 5*time.Minute, "How often the Hacker News stories are polled")
+	rssFeedsFile := flags.String("rss-feeds-file", "", "File with one feed URL per line to add to -rss-feed")
+	rssPollInterval := flags.Duration("rss-poll-interval", 15*time.Minute, "How often the RSS and Atom feeds are polled")
+	telegramBotToken := flags.String("telegram-bot-token", "", "Telegram bot token for -telegram-channel; best given as TWITTER_TELEGRAM_BOT_TOKEN")
+	slackEvents := flags.String("slack-events", "", "Also harvest the links shared in Slack, receiving the Events API's callbacks on /slack/events at this address (e.g. :3000)")
+	slackSigningSecret := flags.String("slack-signing-secret", "", "Signing secret of the Slack app sending -slack-events; best given as TWITTER_SLACK_SIGNING_SECRET")
+	importArchive := flags.String("import-archive", "", "Harvest the tweets in this Twitter account archive: the zip downloaded from Twitter, its directory or its tweets.js")
+	retainRawTweets := flags.Bool("retain-raw-tweets", false, "Keep the JSON of every tweet received in -raw-tweets-dir, so they can be replayed")
+	rawTweetsDir := flags.String("raw-tweets-dir", "", "Directory raw tweets are kept in, a JSONL file a day (default storage-base-path-raw-tweets)")
+	replay := flags.Bool("replay", false, "Harvest the tweets retained with -retain-raw-tweets again, through the current rules and enrichment (-dedupe-across-runs would skip them)")
+	maxTweets := flags.Int("max-tweets", 0, "Stop the stream or search after this many tweets (0 for no limit)")
+	maxDuration := flags.Duration("max-duration", 0, "Stop the stream or search after running this long (e.g. 1h, 0 for no limit)")
+	pollInterval := flags.Duration("poll-interval", 0, "In search mode, repeat the search at this interval (e.g. 5m) instead of running it once")
+	backfill := flags.Bool("backfill", true, "Search for the tweets the filter stream missed while it was disconnected, or the harvester wasn't running (with -resume, which also leaves out the tweets harvested already)")
+	backfillGap := flags.Duration("backfill-gap", 5*time.Minute, "Also backfill when the filter stream goes this long without a tweet, which can be a disconnection it silently reconnected from (0 to not)")
+	resume := flags.Bool("resume", true, "Keep the newest tweet each search found and the stream's last tweet and track rules in storage-base-path's state.json, and resume from them after a restart")
+	maxAPIRetries := flags.Int("max-api-retries", 3, "How many times to retry a failed Twitter API request before giving up")
+	dedupe := flags.Bool("dedupe", false, "Store each cleaned URL only once, counting repeated links instead of storing them again")
+	dedupeAcrossRuns := flags.String("dedupe-across-runs", "", "Bloom filter file shared across runs, used to skip URLs harvested by previous runs")
+	dedupeCapacity := flags.Int("dedupe-capacity", 1000000, "How many URLs the -dedupe-across-runs filter is sized for")
+	minRetweets := flags.Int("min-retweets", 0, "Only harvest tweets retweeted at least this many times")
+	minFavorites := flags.Int("min-favorites", 0, "Only harvest tweets favorited at least this many times")
+	onlyVerified := flags.Bool("only-verified", false, "Only harvest tweets by verified accounts")
+	minFollowers := flags.Int("min-followers", 0, "Only harvest tweets by accounts with at least this many followers")
+	scoreSpam := flags.Bool("score-spam", false, "Record a bot/spam likelihood score (0-1) in the front matter of harvested resources")
+	maxSpamScore := flags.Float64("max-spam-score", 0, "Skip tweets whose spam score is at least this (0-1, implies -score-spam)")
+	enrichMetadata := flags.Bool("enrich-metadata", false, "Fetch each destination and record its title, description, OpenGraph and Twitter Card fields")
+	extractArticles := flags.Bool("extract-articles", false, "Fetch each destination and store its main article text below the front matter")
+	extractPDFs := flags.Bool("extract-pdfs", false, "Fetch each PDF destination and store its title and text below the front matter")
+	extractKeywordsCount := flags.Int("extract-keywords", 0, "Record this many top keywords of the extracted article text (needs -extract-articles or -extract-pdfs)")
+	extractEntities := flags.Bool("extract-entities", false, "Record the people, organizations and locations in the extracted article text (needs -extract-articles or -extract-pdfs)")
+	detectNearDuplicates := flags.Int("detect-near-duplicates", -1, "Link articles whose text fingerprints differ by at most this many bits (0-64) to the first one stored, instead of storing their text again")
+	resolveCanonical := flags.Bool("resolve-canonical", false, "Fetch each destination and store it under its canonical (non-AMP) URL, keeping the URL it was found with as nonCanonicalURL")
+	detectPaywalls := flags.Bool("detect-paywalls", false, "Fetch each destination and record whether it's behind a paywall in an isPaywalled front matter field")
+	paywalledDomainsFile := flags.String("paywalled-domains-file", "", "File with one domain per line to add to the domains -detect-paywalls knows for their paywalls")
+	archiveHTML := flags.Bool("archive-html", false, "Fetch each destination and store a copy of its HTML next to the document")
+	compressArchivedHTML := flags.Bool("compress-archived-html", false, "Gzip the HTML stored by -archive-html")
+	warcDir := flags.String("warc-dir", "", "Fetch each destination and record the HTTP request and response in WARC files in this directory")
+	saveToWayback := flags.Bool("save-to-wayback-machine", false, "Submit each stored URL to the Internet Archive's Wayback Machine and record the snapshot URL")
+	captureScreenshots := flags.Bool("capture-screenshots", false, "Render each destination in headless Chrome and store a PNG screenshot next to the document")
+	proxy := flags.String("proxy", "", "Resolve URLs and fetch destinations through this proxy (e.g. http://proxy:3128 or socks5://localhost:1080)")
+	userAgent := flags.String("user-agent", "", "User-Agent to identify the harvester with when resolving URLs and fetching destinations, and to follow robots.txt rules for")
+	circuitFailures := flags.Int("circuit-failures", 5, "Stop resolving and fetching URLs of a host for circuit-cooldown after this many recent failures (0 to never)")
+	circuitCooldown := flags.Duration("circuit-cooldown", time.Minute, "How long a failing host is skipped before it's tried again")
+	safeBrowsingKey := flags.String("safe-browsing-key", "", "Google Safe Browsing API key to check destinations for malware and phishing with")
+	urlBlocklist := flags.String("url-blocklist", "", "File of known malware and phishing URLs and domains, one per line, to check destinations against")
+	keepUnsafe := flags.Bool("keep-unsafe", false, "Store destinations flagged by safe-browsing-key or url-blocklist with the threat in an unsafe front matter field instead of dropping them")
+	resolveCacheSize := flags.Int("resolve-cache-size", 100000, "How many permanent redirects (e.g. of t.co and bit.ly links) to remember across tweets and runs, in storage-base-path (0 to resolve every time)")
+	resolveTimeout := flags.Duration("resolve-timeout", 30*time.Second, "How long each request resolving a URL may take, redirects included")
+	maxRedirects := flags.Int("max-redirects", 10, "Most redirects followed resolving a URL")
+	resolveRetries := flags.Int("resolve-retries", 2, "How many times a request resolving a URL is retried after a network error or a 429, 502, 503 or 504")
+	perHostQPS := flags.Float64("per-host-qps", 0, "Most requests a second made to any one host while resolving and fetching destinations (0 for no limit)")
+	perHostBurst := flags.Int("per-host-burst", 5, "How many requests to a host may go out at once before per-host-qps kicks in")
+	perHostConcurrency := flags.Int("per-host-concurrency", 0, "Most requests in flight to any one host (0 for no limit)")
+	ignoreRobotsTxt := flags.Bool("ignore-robots-txt", false, "Fetch destinations for enrichment and archiving even where their robots.txt disallows it")
+	fetchTimeout := flags.Duration("fetch-timeout", 30*time.Second, "How long to wait for a destination page when enriching resources")
+	harvestMedia := flags.Bool("harvest-media", false, "Store the photos and video thumbnails attached to tweets, with a document for each such tweet")
+	detectLanguage := flags.Bool("detect-language", false, "Detect the language of each tweet and record it as lang in the front matter")
+	detectArticleLanguage := flags.Bool("detect-article-language", false, "Detect the language of extracted article text and record it as articleLang in the front matter")
+	sentiment := flags.String("sentiment", "", "Record the sentiment of each tweet, scored by the built in lexicon (lexicon) or an external service (api)")
+	sentimentLexicon := flags.String("sentiment-lexicon", "", "File of word<TAB>score lines (-5 to 5) extending the -sentiment lexicon")
+	sentimentAPIURL := flags.String("sentiment-api-url", "", "Service scoring -sentiment api requests, POSTed {\"text\": ...} and answering {\"score\": -1...1}")
+	integrityHashes := flags.Bool("integrity-hashes", false, "Record SHA-256 hashes of stored documents and their attachments in the front matter")
+	dryRun := flags.Bool("dry-run", false, "Harvest, resolve and clean URLs but only print what would be saved or ignored, leaving storage and every output and notification alone")
+	serve := flags.String("serve", "", "Serve the GraphQL and REST APIs over storage-base-path on this address (e.g. localhost:8080, or :8080 for every interface), alongside harvesting or on its own")
+	serveGRPC := flags.String("serve-grpc", "", "Serve the Harvester gRPC service (harvestpb/harvest.proto) on this address (e.g. :9090)")
+	diagnoseInvalidURLs := flags.Bool("diagnose-invalid-urls", false, "Try invalid URLs again to find out why they couldn't be resolved (DNS failure, HTTP 404, TLS error, timeout...), which holds up harvesting by up to "+maxDiagnosisTimeout.String()+" per text with invalid URLs")
+	recordURLErrors := flags.Bool("record-url-errors", false, "Store an error record, with the URL and why it couldn't be resolved in its front matter, for each invalid URL")
+	quarantineDir := flags.String("quarantine-dir", "", "Keep the invalid, ignored, duplicate and filtered resources in this directory, with why, to audit the rules with")
+	otlpEndpoint := flags.String("otlp-endpoint", "", "Export OpenTelemetry traces of the harvest pipeline to the OTLP/HTTP collector at this URL (e.g. http://localhost:4318)")
+	traceSampleRatio := flags.Float64("trace-sample-ratio", 1, "Fraction of tweets traced when otlp-endpoint is set")
+	staleStreamAfter := flags.Duration("stale-stream-after", defaultStaleStreamAfter, "Fail serve mode's /healthz when nothing has arrived on the filter stream for this long, reconnecting or not (0 to never)")
+	serveProfiles := flags.Bool("pprof", false, "Also serve net/http/pprof's profiles under /debug/pprof/ in serve mode, behind -admin-token (which is required)")
+	adminToken := flags.String("admin-token", "", "Bearer token the serve mode's /admin, /debug and harvesting endpoints (and gRPC SubmitText) require; they're off without one")
+	asyncWrites := flags.Int("async-writes", 0, "Queue up to this many resources and write them to storage in the background (0 writes each one as it's harvested)")
+	writeBatchSize := flags.Int("write-batch-size", 100, "Most queued resources written at a time with -async-writes; the seen URLs index is saved once per batch")
+	writeRetries := flags.Int("write-retries", 3, "Times a transient storage write failure is retried")
+	deadLetterDir := flags.String("dead-letter-dir", "", "Directory resources that can't be stored are kept in, with why (default storage-base-path-dead-letter)")
+	maintainManifest := flags.Bool("manifest", false, "Keep an index.json in storage-base-path listing every stored slug with its URL, domain, harvest time and query")
+	retentionDays := flags.Int("retention-days", 0, "Delete (or archive) stored resources harvested more than this many days ago, hourly while harvesting (0 keeps them forever)")
+	prune := flags.Bool("prune", false, "Apply retention-days to storage-base-path and exit")
+	pruneArchiveDir := flags.String("prune-archive-dir", "", "Move expired resources to this directory instead of deleting them")
+	pruneCompress := flags.Bool("prune-compress", false, "Archive the expired resources of each day as one expired-YYYY-MM-DD.tar.gz, in prune-archive-dir or storage-base-path")
+	verifyStorage := flags.Bool("verify-storage", false, "Check every document in storage-base-path against its integrity hashes and exit")
+	feed := flags.String("feed", "", "Print the most recent resources in storage-base-path as an rss or atom feed and exit")
+	feedItems := flags.Int("feed-items", 20, "How many resources the feed has")
+	feedTitle := flags.String("feed-title", "Harvested content", "Title of the feed")
+	feedLink := flags.String("feed-link", "", "Link of the feed, e.g. where it's published")
+	digest := flags.String("digest", "", "Print a daily or weekly markdown digest of storage-base-path and exit")
+	digestTop := flags.Int("digest-top", 10, "How many domains, URLs and hashtags the digest lists")
+	digestSkipPaywalled := flags.Bool("digest-skip-paywalled", false, "Leave resources marked as paywalled by -detect-paywalls out of the digest's most shared URLs")
+	searchIndex := flags.String("search-index", "./tmp/search.bleve", "Full-text index the bleve output, find and reindex use, shared across runs")
+	find := flags.String("find", "", "Print the resources in search-index matching this Bleve query string and exit")
+	findResults := flags.Int("find-results", 20, "How many resources find prints")
+	reindex := flags.Bool("reindex", false, "Add every document in storage-base-path to search-index and exit")
+	flags.Var(&outputs, "output", "Also send each stored resource to an output: jsonl, csv, parquet, bleve, elasticsearch, kafka or nats (repeat for more than one)")
+	jsonlFile := flags.String("jsonl-file", "-", "File the jsonl output appends to, - for stdout")
+	csvFile := flags.String("csv-file", "", "File the csv output appends a row per harvested resource to, including invalid and ignored ones")
+	parquetFile := flags.String("parquet-file", "", "Parquet file the parquet output writes, replaced on each run")
+	elasticsearchURL := flags.String("elasticsearch-url", "http://localhost:9200", "Elasticsearch or OpenSearch cluster the elasticsearch output indexes into")
+	elasticsearchIndex := flags.String("elasticsearch-index", "harvested-resources", "Index the elasticsearch output writes to")
+	elasticsearchBatch := flags.Int("elasticsearch-batch", 100, "How many resources the elasticsearch output sends per bulk request")
+	kafkaBrokers := flags.String("kafka-brokers", "localhost:9092", "Kafka brokers the kafka output publishes to (comma separated)")
+	kafkaTopic := flags.String("kafka-topic", "harvested-resources", "Topic the kafka output publishes to")
+	natsURL := flags.String("nats-url", "nats://localhost:4222", "NATS server the nats output publishes to")
+	natsSubject := flags.String("nats-subject", "harvested.resources", "Subject the nats output publishes on")
+	natsJetStream := flags.Bool("nats-jetstream", false, "Publish through JetStream so messages are persisted; the subject must belong to a stream")
+	flags.Var(&webhookURLs, "webhook-url", "POST a JSON event to this URL for each stored resource (repeat for more than one)")
+	webhookSecret := flags.String("webhook-secret", "", "Sign webhook events with an HMAC-SHA256 of the body using this secret")
+	webhookTimeout := flags.Duration("webhook-timeout", 10*time.Second, "How long to wait for a webhook to respond")
+	webhookRetries := flags.Int("webhook-retries", 3, "How many times to retry a webhook event that failed")
+	webhookIgnored := flags.Bool("webhook-ignored", false, "Send webhook events for ignored resources too")
+	slackWebhookURL := flags.String("slack-webhook-url", "", "Post a message to this Slack incoming webhook for each stored resource")
+	discordWebhookURL := flags.String("discord-webhook-url", "", "Post a message to this Discord webhook for each stored resource")
+	notifyMatch := flags.String("notify-match", "", "Only post Slack and Discord messages for resources whose tweet, URL or title matches this regular expression")
+	emailSMTPServer := flags.String("email-smtp-server", "", "SMTP server (host:port) to mail digests of stored resources through")
+	emailUsername := flags.String("email-username", "", "Username for the SMTP server, if it needs one")
+	emailPassword := flags.String("email-password", "", "Password for the SMTP server")
+	emailFrom := flags.String("email-from", "", "Sender of the digest emails")
+	emailTo := flags.String("email-to", "", "Recipients of the digest emails (comma separated)")
+	emailDigest := flags.String("email-digest", "daily", "How often to send digest emails: hourly or daily")
+	emailSubject := flags.String("email-subject", "{{.Count}} resources harvested since {{.Since.Format \"Jan 2 15:04\"}}", "Go template for the digest email subject (see EmailDigestSubjectData)")
+	onlyUsersFile := flags.String("only-users-file", "", "File with one user per line to add to -only-users")
+	onlyDomainsFile := flags.String("only-domains-file", "", "File with one domain per line to add to -only-domains")
+	blockUsersFile := flags.String("block-users-file", "", "File with one user per line to add to -block-users")
 	storageBasePath := flags.String("storage-base-path", fmt.Sprintf("./tmp/storage-%s", time.Now().Format("2006-01-02-15-04-05")), "Name of the root directory to storage harvested resources in")
+	storageCompression := flags.String("storage-compression", "none", "Compress everything stored, documents and attachments alike, with none, gzip or zstd (files stored otherwise are still read)")
+	storageEncryptionKey := flags.String("storage-encryption-key", "", "Base64 AES key (16, 24 or 32 bytes) to encrypt stored documents and attachments with, AES-GCM; best given as TWITTER_STORAGE_ENCRYPTION_KEY")
+	storageEncryptionKeyCommand := flags.String("storage-encryption-key-command", "", "Shell command printing storage-encryption-key, e.g. to decrypt it with a KMS")
+	storageLayout := flags.String("storage-layout", "flat", "How documents are spread over subdirectories of storage-base-path: flat, date (one per day), partitioned (YYYY/MM/DD, in "+store.DefaultPartitionedPath+" unless storage-base-path is given), hash (of the slug) or domain (of the destination)")
 	flags.Var(&twitterQuery, "query", "The items to search in Twitter Filter")
-	flags.Var(&ignoreURLsRegEx, "ignore-urls-reg-ex", "Regular expression indicating which URL patterns to not harvest")
-	flags.Var(&removeParamsFromURLsRegEx, "remove-params-from-urls-reg-ex", "Regular expression indicating which URL query params to 'clean' in harvested URLs")
-	flags.Parse(os.Args[1:])
+	flags.Var(&timelines, "timeline", "Harvest all links shared in this user's timeline (e.g. @user)")
+	flags.Var(&lists, "list", "Harvest all links shared by the members of this Twitter List (owner/slug or list ID)")
+	flags.Var(&mastodonHashtags, "mastodon-hashtag", "Harvest the statuses with this hashtag from -mastodon-instance rather than its public timeline")
+	flags.Var(&blueskyKeywords, "bluesky-keyword", "Harvest the Bluesky posts containing this keyword, regardless of case (without any, every post is)")
+	flags.Var(&subreddits, "subreddit", "Also harvest the submissions to this subreddit (e.g. r/golang)")
+	flags.Var(&hackerNewsKeywords, "hn-keyword", "Harvest the Hacker News stories whose title or text contains this keyword, regardless of case (without any, every story is)")
+	flags.Var(&rssFeeds, "rss-feed", "Also harvest the links in the entries of this RSS or Atom feed (URL)")
+	flags.Var(&telegramChannels, "telegram-channel", "Also harvest the posts in this Telegram channel (@username), which -telegram-bot-token's bot must have been added to")
+	flags.Var(&inputs, "input", "Also harvest the lines of this file (- for stdin), each text or a tweet's JSON")
+	flags.Var(&followUsers, "follow", "In filter-stream mode, also harvest tweets by this user (ID or screen name)")
+	flags.Var(&languages, "lang", "Only harvest tweets in these languages (comma separated BCP 47 codes, e.g. en,de)")
+	flags.Var(&geoBBox, "geo-bbox", "Only harvest tweets located in this bounding box (west,south,east,north)")
+	flags.Var(&onlyUsers, "only-users", "Only harvest tweets by this user (ID or screen name)")
+	flags.Var(&blockUsers, "block-users", "Never harvest tweets by this user (ID or screen name)")
+	flags.Var(&excludeCategories, "exclude-categories", "Skip resources whose destination is on a domain of these categories (comma separated, e.g. adult,gambling)")
+	domainCategoriesFile := flags.String("domain-categories-file", "", "File with one \"domain category\" pair per line to add to the bundled adult and gambling domains -exclude-categories knows")
+	flags.Var(&sampleDomains, "sample-domain", "With -sample-stream, only harvest the tweets linking to these domains or their subdomains (comma separated, or repeat)")
+	flags.Var(&onlyDomains, "only-domains", "Only store resources whose destination is on these domains or their subdomains (comma separated, e.g. example.com,nytimes.com)")
+	flags.Var(&allowContentTypes, "allow-content-types", "Only store destinations with these content types (comma separated, e.g. text/html,application/pdf)")
+	flags.Var(&denyContentTypes, "deny-content-types", "Never store destinations with these content types (comma separated, e.g. video/*)")
+	var logOptions LogOptions
+	flags.StringVar(&logOptions.Level, "log-level", "info", "Least severe messages logged: debug, info, warn or error")
+	flags.StringVar(&logOptions.Format, "log-format", "json", "Log as json or as human readable console lines")
+	flags.StringVar(&logOptions.File, "log-file", "", "Log to this file instead of stderr, rotating it as it grows")
+	flags.IntVar(&logOptions.MaxSize, "log-max-size", 100, "Megabytes log-file grows to before it's rotated")
+	flags.IntVar(&logOptions.MaxBackups, "log-max-backups", 10, "How many rotated log files are kept (0 keeps them all)")
+	flags.IntVar(&logOptions.MaxAge, "log-max-age", 0, "Days rotated log files are kept (0 keeps them regardless of age)")
+	reloadable.register(flags)
+	configFile := flags.String("config", "", "File of name = value flag settings; ignore and clean rules and output settings are reloaded from it on SIGHUP")
+	parseCommandLine(flags, os.Args[1:])
 	flagutil.SetFlagsFromEnv(flags, "TWITTER")
+	explicitFlags := setOnCommandLine(flags)
+	commandLine := reloadable
+	if *configFile != "" {
+		if err := applyConfigFile(flags, *configFile); err != nil {
+			log.Fatalf("can't read config: %v", err)
+		}
+	}
+
+	if *storageLayout == "partitioned" && !setOnCommandLine(flags)["storage-base-path"] {
+		*storageBasePath = store.DefaultPartitionedPath
+	}
+
+	if *rssFeedsFile != "" {
+		feeds, err := filter.ReadListFile(*rssFeedsFile)
+		if err != nil {
+			log.Fatalf("can't read rss-feeds-file: %v", err)
+		}
+		rssFeeds = append(rssFeeds, feeds...)
+	}
 
-	if !*filterTwitterStream && !*searchTwitter {
-		log.Fatal("Either filter-stream or search should be specified")
+	// these only work over what's already in storage, without Twitter
+	storageOnly := *prune || *verifyStorage || *feed != "" || *digest != "" || *find != "" || *reindex
+	harvestingTwitter := *filterTwitterStream || *sampleStream || *searchTwitter || len(timelines) > 0 || len(lists) > 0
+	harvesting := harvestingTwitter || *fullArchiveSearch || *mastodonInstance != "" || *bluesky || len(subreddits) > 0 || *hackerNews ||
+		len(rssFeeds) > 0 || len(telegramChannels) > 0 || *slackEvents != "" ||
+		len(inputs) > 0 || *importArchive != "" || *replay
+	if !harvesting && !storageOnly && *serve == "" && *serveGRPC == "" {
+		log.Fatal("Either a command (run help for them) or one of filter-stream, sample-stream, search, full-archive-search, timeline, list, mastodon-instance, bluesky, subreddit, hn, rss-feed, telegram-channel, slack-events, input, import-archive, replay, serve or serve-grpc should be specified")
 	}
 
-	if *consumerKey == "" || *consumerSecret == "" || *accessToken == "" || *accessSecret == "" {
+	var credentials []twitter.Credentials
+	if *consumerKey != "" || *consumerSecret != "" || *accessToken != "" || *accessSecret != "" {
+		credentials = append(credentials, twitter.Credentials{ConsumerKey: *consumerKey, ConsumerSecret: *consumerSecret, AccessToken: *accessToken, AccessSecret: *accessSecret})
+	}
+	if *credentialsFile != "" {
+		fromFile, err := twitter.ReadCredentialsFile(*credentialsFile)
+		if err != nil {
+			log.Fatalf("can't read credentials-file: %v", err)
+		}
+		credentials = append(credentials, fromFile...)
+	}
+	if harvestingTwitter && !storageOnly && (len(credentials) == 0 || !credentials[0].Complete()) {
 		log.Fatal("Consumer key/secret and Access token/secret required")
 	}
 
-	if len(twitterQuery) == 0 {
+	var archiveStart, archiveEnd time.Time
+	if *fullArchiveSearch {
+		if *bearerToken == "" {
+			log.Fatal("Bearer token required for -full-archive-search")
+		}
+		var err error
+		if *searchStartTime != "" {
+			if archiveStart, err = time.Parse(time.RFC3339, *searchStartTime); err != nil {
+				log.Fatalf("can't parse search-start-time: %v", err)
+			}
+		}
+		if *searchEndTime != "" {
+			if archiveEnd, err = time.Parse(time.RFC3339, *searchEndTime); err != nil {
+				log.Fatalf("can't parse search-end-time: %v", err)
+			}
+		}
+		if !archiveStart.IsZero() && !archiveEnd.IsZero() && !archiveStart.Before(archiveEnd) {
+			log.Fatal("search-start-time should be before search-end-time")
+		}
+	}
+
+	if len(subreddits) > 0 && (*redditClientID == "" || *redditClientSecret == "") {
+		log.Fatal("Reddit client ID/secret required for -subreddit")
+	}
+	if *redditSort != "new" && *redditSort != "hot" {
+		log.Fatalf("unknown reddit-sort %q, should be new or hot", *redditSort)
+	}
+
+	if *hackerNewsList != "new" && *hackerNewsList != "top" && *hackerNewsList != "best" {
+		log.Fatalf("unknown hn-list %q, should be new, top or best", *hackerNewsList)
+	}
+
+	if len(telegramChannels) > 0 && *telegramBotToken == "" {
+		log.Fatal("telegram-bot-token is required for -telegram-channel")
+	}
+
+	if *slackEvents != "" && *slackSigningSecret == "" {
+		log.Fatal("slack-signing-secret is required for -slack-events")
+	}
+
+	// the profiles include the command line, with the secrets given on it
+	if *serveProfiles && *adminToken == "" {
+		log.Fatal("admin-token required for -pprof")
+	}
+
+	if len(mastodonHashtags) > 0 && *mastodonInstance == "" {
+		log.Fatal("mastodon-instance is required for -mastodon-hashtag")
+	}
+
+	if len(twitterQuery) == 0 && (*searchTwitter || *fullArchiveSearch || (*filterTwitterStream && len(followUsers) == 0)) {
 		log.Fatal("Twitter filter track items required")
 	}
 
-	if len(ignoreURLsRegEx) == 0 {
-		ignoreURLsRegEx = []*regexp.Regexp{regexp.MustCompile(`^https://twitter.com/(.*?)/status/(.*)$`), regexp.MustCompile(`https://t.co`)}
+	if *onlyUsersFile != "" {
+		users, err := filter.ReadListFile(*onlyUsersFile)
+		if err != nil {
+			log.Fatalf("can't read only-users-file: %v", err)
+		}
+		onlyUsers = append(onlyUsers, users...)
 	}
 
-	if len(removeParamsFromURLsRegEx) == 0 {
-		removeParamsFromURLsRegEx = []*regexp.Regexp{regexp.MustCompile(`^utm_`)}
+	if *blockUsersFile != "" {
+		users, err := filter.ReadListFile(*blockUsersFile)
+		if err != nil {
+			log.Fatalf("can't read block-users-file: %v", err)
+		}
+		blockUsers = append(blockUsers, users...)
 	}
 
-	logger, err := zap.NewProduction()
+	if *onlyDomainsFile != "" {
+		domains, err := filter.ReadListFile(*onlyDomainsFile)
+		if err != nil {
+			log.Fatalf("can't read only-domains-file: %v", err)
+		}
+		for _, domain := range domains {
+			onlyDomains.Set(domain)
+		}
+	}
+
+	ignoreURLsRegEx, removeParamsFromURLsRegEx, err := reloadable.urlRules()
+	if err != nil {
+		log.Fatalf("can't read rule files: %v", err)
+	}
+
+	logger, err := NewLogger(logOptions)
 	if err != nil {
 		log.Fatalf("can't initialize zap logger: %v", err)
 	}
 	defer logger.Sync()
 
 	contentHarvester := harvester.MakeContentHarvester(logger, ignoreURLsRegEx, removeParamsFromURLsRegEx, true)
-	storage := NewHarvestedResourceStorage(contentHarvester, logger, *storageBasePath)
-	twitterAPI := anaconda.NewTwitterApiWithCredentials(*accessToken, *accessSecret, *consumerKey, *consumerSecret)
+	layout, err := store.NewLayout(*storageLayout, *storageBasePath)
+	if err != nil {
+		log.Fatalf("can't lay out storage: %v", err)
+	}
+	layout.OnRotation(func(partition string) {
+		logger.Info("Starting new storage partition", zap.String("partition", partition))
+	})
+	compression, err := store.NewCompression(*storageCompression)
+	if err != nil {
+		log.Fatalf("can't compress storage: %v", err)
+	}
+	if *storageEncryptionKeyCommand != "" {
+		if *storageEncryptionKey, err = store.EncryptionKeyFromCommand(*storageEncryptionKeyCommand); err != nil {
+			log.Fatalf("can't get storage-encryption-key: %v", err)
+		}
+	}
+	if *storageEncryptionKey != "" {
+		if compression, err = store.NewEncryption(*storageEncryptionKey, compression); err != nil {
+			log.Fatalf("can't encrypt storage: %v", err)
+		}
+	}
+	storage := NewHarvestedResourceStorage(contentHarvester, logger, *storageBasePath, layout, compression)
+	if err := storage.UseOutputFormat(reloadable.OutputFormat); err != nil {
+		log.Fatal(err)
+	}
+	if *maintainManifest && !*dryRun {
+		if err := storage.MaintainManifest(); err != nil {
+			log.Fatalf("can't build manifest: %v", err)
+		}
+	}
+	retention := RetentionPolicy{MaxAge: time.Duration(*retentionDays) * 24 * time.Hour, ArchiveDir: *pruneArchiveDir, Compress: *pruneCompress}
+	if *prune {
+		if *retentionDays <= 0 {
+			log.Fatal("prune needs retention-days")
+		}
+		result, err := storage.Prune(retention, time.Now())
+		if err != nil {
+			log.Fatalf("can't prune storage: %v", err)
+		}
+		fmt.Printf("Pruned %d documents (%d files) from %s\n", result.Documents, result.Files, *storageBasePath)
+		storage.Close()
+		return
+	}
+	if *verifyStorage {
+		checked, failed := storage.VerifyAll()
+		fmt.Printf("Verified %d documents in %s, %d failed\n", checked, *storageBasePath, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if *feed != "" {
+		rendered, err := storage.RenderFeed(*feed, *feedTitle, *feedLink, *feedItems)
+		if err != nil {
+			log.Fatalf("can't render feed: %v", err)
+		}
+		fmt.Println(rendered)
+		return
+	}
+	if *digest != "" {
+		report, err := storage.RenderDigest(*digest, *digestTop, *digestSkipPaywalled, time.Now())
+		if err != nil {
+			log.Fatalf("can't render digest: %v", err)
+		}
+		fmt.Print(report)
+		return
+	}
+	if *find != "" || *reindex {
+		index, err := OpenSearchIndex(*searchIndex)
+		if err != nil {
+			log.Fatalf("can't open search-index: %v", err)
+		}
+		defer index.Close()
+		if *reindex {
+			indexed, err := index.IndexStored(storage.StoredDocuments())
+			if err != nil {
+				log.Fatalf("can't reindex: %v", err)
+			}
+			fmt.Printf("Indexed %d documents from %s\n", indexed, *storageBasePath)
+			return
+		}
+		result, err := index.Search(*find, *findResults)
+		if err != nil {
+			log.Fatalf("can't search: %v", err)
+		}
+		fmt.Printf("%d matches\n", result.Total)
+		for _, hit := range result.Hits {
+			fmt.Printf("\n%s (%.2f) %v\n%v\n", hit.ID, hit.Score, hit.Fields["title"], hit.Fields["url"])
+			for _, fragments := range hit.Fragments {
+				for _, fragment := range fragments {
+					fmt.Printf("  %s\n", fragment)
+				}
+			}
+		}
+		return
+	}
+	if *dryRun {
+		// nothing is written or sent in a dry run, whatever the config file says
+		storage.DryRun(os.Stdout)
+		outputs, webhookURLs = nil, nil
+		*slackWebhookURL, *discordWebhookURL, *emailSMTPServer, *warcDir = "", "", "", ""
+		*saveToWayback, *harvestMedia = false, false
+		*quarantineDir = ""
+		*detectNearDuplicates = -1
+	}
+	if *otlpEndpoint != "" {
+		shutdownTracing, err := StartTracing(*otlpEndpoint, *traceSampleRatio)
+		if err != nil {
+			log.Fatalf("can't start tracing: %v", err)
+		}
+		defer shutdownTracing(context.Background())
+	}
+	if err := configureDefaultTransport(*proxy, *userAgent); err != nil {
+		log.Fatalf("can't use proxy: %v", err)
+	}
+	// the per-host limits, circuit breaker and redirect cache are for the destinations of
+	// harvested links, so they go on the clients resolving and fetching those rather than on
+	// the default transport the outputs and the other sources share
+	destinations := http.DefaultTransport
+	if *perHostQPS > 0 || *perHostConcurrency > 0 {
+		// the resolver shares net/http's default client with the Twitter API, which has rate
+		// limits of its own
+		destinations = transport.NewPolite(destinations, *perHostQPS, *perHostBurst, *perHostConcurrency, twitter.APIHosts)
+	}
+	if *circuitFailures > 0 {
+		breaker := transport.NewCircuitBreaker(destinations, *circuitFailures, *circuitCooldown, twitter.APIHosts)
+		breaker.OnOpen = func(string) { circuitsOpenedCounter.Inc() }
+		destinations = breaker
+	}
+	// only resolving answers redirects from the cache; fetching a page follows them afresh
+	resolution := destinations
+	if *resolveCacheSize > 0 {
+		cache, err := transport.NewRedirectCache(destinations, filepath.Join(*storageBasePath, transport.RedirectCacheFile), *resolveCacheSize)
+		if err != nil {
+			log.Fatalf("can't load redirect cache: %v", err)
+		}
+		resolution = cache
+		if !*dryRun {
+			go cache.SaveEvery(time.Minute, logger)
+			defer cache.Save()
+		}
+	}
+	if *retentionDays > 0 && harvesting && !*dryRun {
+		if err := storage.inStoreLayout("retention-days"); err != nil {
+			log.Fatal(err)
+		}
+		go storage.PruneEvery(time.Hour, retention)
+	}
+	configureResolution(*resolveTimeout, *maxRedirects, *resolveRetries, resolution)
+	if *diagnoseInvalidURLs {
+		storage.DiagnoseInvalidURLsWith(NewURLErrorDiagnoser(*fetchTimeout))
+	}
+	if *recordURLErrors {
+		storage.RecordURLErrors()
+	}
+	if *integrityHashes {
+		storage.RecordIntegrityHashes()
+	}
+	if *deadLetterDir == "" {
+		*deadLetterDir = filepath.Clean(*storageBasePath) + "-dead-letter"
+	}
+	storage.RetryWrites(*writeRetries, *deadLetterDir)
+	if *asyncWrites > 0 {
+		storage.WriteAsynchronously(*asyncWrites, *writeBatchSize)
+	}
+	for _, output := range outputs {
+		switch output {
+		case "jsonl":
+			writer, err := NewJSONLinesWriter(*jsonlFile)
+			if err != nil {
+				log.Fatalf("can't open jsonl-file: %v", err)
+			}
+			storage.OutputTo(writer)
+		case "csv":
+			if *csvFile == "" {
+				log.Fatal("csv output needs a csv-file")
+			}
+			csvLog, err := NewCSVHarvestLog(*csvFile)
+			if err != nil {
+				log.Fatalf("can't open csv-file: %v", err)
+			}
+			storage.LogHarvestsTo(csvLog)
+		case "parquet":
+			if *parquetFile == "" {
+				log.Fatal("parquet output needs a parquet-file")
+			}
+			exporter, err := NewParquetExporter(*parquetFile)
+			if err != nil {
+				log.Fatalf("can't create parquet-file: %v", err)
+			}
+			storage.OutputTo(exporter)
+		case "bleve":
+			index, err := OpenSearchIndex(*searchIndex)
+			if err != nil {
+				log.Fatalf("can't open search-index: %v", err)
+			}
+			storage.OutputTo(index)
+		case "elasticsearch":
+			storage.OutputTo(NewElasticsearchIndexer(logger, *elasticsearchURL, *elasticsearchIndex, *elasticsearchBatch))
+		case "kafka":
+			publisher, err := NewKafkaPublisher(strings.Split(*kafkaBrokers, ","), *kafkaTopic)
+			if err != nil {
+				log.Fatalf("can't connect to kafka-brokers: %v", err)
+			}
+			storage.OutputTo(publisher)
+		case "nats":
+			publisher, err := NewNATSPublisher(*natsURL, *natsSubject, *natsJetStream)
+			if err != nil {
+				log.Fatalf("can't connect to nats-url: %v", err)
+			}
+			storage.OutputTo(publisher)
+		default:
+			log.Fatalf("unknown output %q", output)
+		}
+	}
+	if *quarantineDir != "" {
+		storage.ObserveIgnoredWith(NewQuarantineStore(logger, *quarantineDir))
+	}
+	if len(webhookURLs) > 0 {
+		webhooks := NewWebhookNotifier(logger, webhookURLs, *webhookSecret, *webhookTimeout, *webhookRetries, *webhookIgnored)
+		storage.OutputTo(webhooks)
+		storage.ObserveIgnoredWith(webhooks)
+	}
+	var notifyMatchRegEx *regexp.Regexp
+	if *notifyMatch != "" {
+		if notifyMatchRegEx, err = regexp.Compile(*notifyMatch); err != nil {
+			log.Fatalf("can't parse notify-match: %v", err)
+		}
+	}
+	if *slackWebhookURL != "" {
+		storage.OutputTo(NewSlackNotifier(logger, *slackWebhookURL, *webhookTimeout, *webhookRetries, notifyMatchRegEx))
+	}
+	if *discordWebhookURL != "" {
+		storage.OutputTo(NewDiscordNotifier(logger, *discordWebhookURL, *webhookTimeout, *webhookRetries, notifyMatchRegEx))
+	}
+	if *emailSMTPServer != "" {
+		interval := map[string]time.Duration{"hourly": time.Hour, "daily": 24 * time.Hour}[*emailDigest]
+		if interval == 0 {
+			log.Fatalf("unknown email-digest %q", *emailDigest)
+		}
+		if *emailFrom == "" || *emailTo == "" {
+			log.Fatal("email digests need email-from and email-to")
+		}
+		sender, err := NewEmailDigestSender(logger, *emailSMTPServer, *emailUsername, *emailPassword, *emailFrom, strings.Split(*emailTo, ","), *emailSubject, interval)
+		if err != nil {
+			log.Fatalf("can't set up email digests: %v", err)
+		}
+		storage.OutputTo(sender)
+	}
+	if reloadable.FrontMatterTemplate != "" {
+		if err := storage.UseDocumentTemplate(reloadable.FrontMatterTemplate); err != nil {
+			log.Fatalf("can't parse frontmatter-template: %v", err)
+		}
+	}
+	reloader := NewConfigReloader(*configFile, storage, logger, &commandLine, explicitFlags, &reloadable)
+	go reloader.WatchRuleFiles(ruleFilesCheckInterval)
+	if *configFile != "" {
+		hangups := make(chan os.Signal, 1)
+		signal.Notify(hangups, syscall.SIGHUP)
+		go func() {
+			for range hangups {
+				if err := reloader.Reload(); err != nil {
+					logger.Error("Unable to reload config", zap.String("config", *configFile), zap.Error(err))
+					continue
+				}
+				logger.Info("Reloaded config", zap.String("config", *configFile))
+			}
+		}()
+	}
+	if *dedupe {
+		if err := storage.DeduplicateResources(); err != nil {
+			log.Fatalf("can't load seen URLs index: %v", err)
+		}
+	}
+	if *dedupeAcrossRuns != "" {
+		if err := storage.DeduplicateAcrossRuns(*dedupeAcrossRuns, *dedupeCapacity); err != nil {
+			log.Fatalf("can't load seen URLs filter: %v", err)
+		}
+	}
+	defer storage.Close()
+	var enrichers []PageEnricher
+	if *enrichMetadata {
+		enrichers = append(enrichers, metadataEnricher{})
+	}
+	if *extractArticles {
+		enrichers = append(enrichers, articleTextEnricher{})
+	}
+	if *extractPDFs {
+		enrichers = append(enrichers, pdfTextEnricher{})
+	}
+	if *resolveCanonical {
+		enrichers = append(enrichers, canonicalEnricher{})
+	}
+	if *detectArticleLanguage {
+		enrichers = append(enrichers, articleLanguageEnricher{})
+	}
+	if *extractKeywordsCount > 0 {
+		enrichers = append(enrichers, keywordEnricher{count: *extractKeywordsCount})
+	}
+	if *extractEntities {
+		enrichers = append(enrichers, namedEntityEnricher{})
+	}
+	if *detectNearDuplicates >= 0 {
+		nearDuplicates, err := NewNearDuplicateDetector(filepath.Join(*storageBasePath, ".simhashes.json"), *detectNearDuplicates, logger)
+		if err != nil {
+			log.Fatalf("can't load article fingerprints: %v", err)
+		}
+		defer nearDuplicates.Save()
+		enrichers = append(enrichers, nearDuplicates)
+	}
+	if *detectPaywalls {
+		paywalledDomains := append(filter.DomainList{}, defaultPaywalledDomains...)
+		if *paywalledDomainsFile != "" {
+			domains, err := filter.ReadListFile(*paywalledDomainsFile)
+			if err != nil {
+				log.Fatalf("can't read paywalled-domains-file: %v", err)
+			}
+			for _, domain := range domains {
+				paywalledDomains.Set(domain)
+			}
+		}
+		enrichers = append(enrichers, paywallEnricher{domains: paywalledDomains})
+	}
+	if *archiveHTML {
+		enrichers = append(enrichers, htmlArchiveEnricher{compress: *compressArchivedHTML})
+	}
+	if *warcDir != "" {
+		warcWriter, err := NewWARCWriter(*warcDir, "harvest")
+		if err != nil {
+			log.Fatalf("can't create WARC writer: %v", err)
+		}
+		defer warcWriter.Close()
+		enrichers = append(enrichers, warcEnricher{writer: warcWriter, logger: logger})
+	}
+	fetcher := NewPageFetcher(*fetchTimeout, destinations)
+	if !*ignoreRobotsTxt {
+		robotsAgent := defaultRobotsAgent
+		if *userAgent != "" {
+			robotsAgent = *userAgent
+		}
+		fetcher.RespectRobotsTxt(robotsAgent)
+	}
+	storage.EnrichWith(fetcher, enrichers)
+	if *resolveCanonical {
+		storage.ResolveCanonicalURLs()
+	}
+	var destinationEnrichers []DestinationEnricher
+	if *saveToWayback {
+		destinationEnrichers = append(destinationEnrichers, NewWaybackMachine(logger, 5*time.Second))
+	}
+	if *captureScreenshots {
+		screenshots := NewScreenshotCapturer(logger, *fetchTimeout)
+		defer screenshots.Close()
+		destinationEnrichers = append(destinationEnrichers, screenshots)
+	}
+	storage.EnrichDestinationsWith(destinationEnrichers)
+	if len(allowContentTypes) > 0 || len(denyContentTypes) > 0 {
+		storage.FilterContentTypes(allowContentTypes, denyContentTypes)
+	}
+	if len(onlyDomains) > 0 {
+		storage.OnlyDomains(onlyDomains)
+	}
+	if len(excludeCategories) > 0 {
+		categories := filter.NewDomainCategories()
+		if *domainCategoriesFile != "" {
+			if err := categories.Load(*domainCategoriesFile); err != nil {
+				log.Fatalf("can't read domain-categories-file: %v", err)
+			}
+		}
+		storage.ExcludeCategories(categories, excludeCategories)
+	}
+	var screeners []URLScreener
+	if *urlBlocklist != "" {
+		blocklist, err := NewBlocklistScreener(*urlBlocklist)
+		if err != nil {
+			log.Fatalf("can't read url-blocklist: %v", err)
+		}
+		screeners = append(screeners, blocklist)
+	}
+	if *safeBrowsingKey != "" {
+		screeners = append(screeners, NewSafeBrowsingScreener(*safeBrowsingKey, *fetchTimeout))
+	}
+	storage.ScreenURLsWith(screeners, !*keepUnsafe)
+	if len(credentials) == 0 {
+		// storage-only modes and the other sources never call Twitter
+		credentials = append(credentials, twitter.Credentials{})
+	}
+	var twitterAPIs []*anaconda.TwitterApi
+	for _, c := range credentials {
+		api := anaconda.NewTwitterApiWithCredentials(c.AccessToken, c.AccessSecret, c.ConsumerKey, c.ConsumerSecret)
+		// the default client has the resolution timeout, which would cut the stream off
+		api.HttpClient = &http.Client{}
+		twitterAPIs = append(twitterAPIs, api)
+	}
+	// the stream is a single connection, on the first set of credentials
+	twitterAPI := twitterAPIs[0]
+
+	scheduler := twitter.NewRateLimitScheduler(twitterAPIs, logger, *maxAPIRetries)
+	var state *HarvestState
+	if *resume && !*dryRun {
+		var err error
+		state, err = LoadHarvestState(*storageBasePath, logger)
+		if err != nil {
+			log.Fatalf("can't load harvest state: %v", err)
+		}
+		defer func() {
+			if err := state.Save(); err != nil {
+				logger.Error("Unable to save harvest state", zap.Error(err))
+			}
+		}()
+	}
+	var tweetFilters []TweetFilter
+	if len(languages) > 0 {
+		tweetFilters = append(tweetFilters, languages)
+	}
+	if geoBBox.set {
+		tweetFilters = append(tweetFilters, geoBBox)
+	}
+	if *minRetweets > 0 || *minFavorites > 0 {
+		tweetFilters = append(tweetFilters, engagementFilter{minRetweets: *minRetweets, minFavorites: *minFavorites})
+	}
+	if len(onlyUsers) > 0 || len(blockUsers) > 0 {
+		tweetFilters = append(tweetFilters, newUserFilter(onlyUsers, blockUsers))
+	}
+	if *onlyVerified || *minFollowers > 0 {
+		tweetFilters = append(tweetFilters, authorFilter{onlyVerified: *onlyVerified, minFollowers: *minFollowers})
+	}
+	tweets := NewTweetHarvester(storage, logger, tweetFilters)
+	if *scoreSpam || *maxSpamScore > 0 {
+		tweets.ScoreSpam(*maxSpamScore)
+	}
+	if *detectLanguage {
+		tweets.DetectLanguage()
+	}
+	switch *sentiment {
+	case "":
+	case "lexicon":
+		analyzer, err := NewLexiconSentimentAnalyzer(*sentimentLexicon)
+		if err != nil {
+			log.Fatalf("can't load sentiment lexicon: %v", err)
+		}
+		tweets.AnalyzeSentiment(analyzer)
+	case "api":
+		if *sentimentAPIURL == "" {
+			log.Fatal("sentiment-api-url is required for -sentiment api")
+		}
+		tweets.AnalyzeSentiment(NewAPISentimentAnalyzer(*sentimentAPIURL, *fetchTimeout))
+	default:
+		log.Fatalf("unknown sentiment analyzer %q, should be lexicon or api", *sentiment)
+	}
+	if *harvestMedia {
+		tweets.HarvestMedia(NewMediaHarvester(storage, logger, *fetchTimeout))
+	}
+
+	var server *HarvesterServer
+	if *serve != "" || *serveGRPC != "" {
+		broadcaster := NewResourceBroadcaster()
+		storage.OutputTo(broadcaster)
+		if *serveGRPC != "" {
+			go func() {
+				log.Fatalf("can't serve gRPC: %v", ServeGRPC(storage, broadcaster, logger, *serveGRPC, *adminToken))
+			}()
+		}
+		if *serve != "" {
+			server = NewHarvesterServer(storage, broadcaster, logger, *adminToken)
+			server.ExpectTweetsWithin(*staleStreamAfter)
+			if *serveProfiles {
+				server.ServeProfiles()
+			}
+			go func() {
+				log.Fatalf("can't serve: %v", server.ListenAndServe(*serve))
+			}()
+		}
+		if !harvesting {
+			select {}
+		}
+	}
+
+	limit := NewRunLimit(*maxTweets, *maxDuration)
+	tweets.LimitTo(limit)
+	// whatever the harvest, an interrupt ends it like reaching the limit does, so the deferred
+	// saves and closes still run
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		logger.Info("Interrupted, stopping the harvest")
+		// a second interrupt kills the process, should stopping hang
+		signal.Stop(interrupts)
+		limit.Stop()
+	}()
+	if state != nil {
+		go state.SaveEvery(limit.Context(), harvestStateSaveInterval)
+	}
+	if state != nil {
+		tweets.RememberHarvestedIn(state)
+	}
+
+	// the other sources are harvested alongside Twitter's stream or search, or on their own
+	var sources []Source
+	if *mastodonInstance != "" {
+		sources = append(sources, NewMastodonStream(*mastodonInstance, *mastodonAccessToken, mastodonHashtags, *mastodonLocal, logger))
+	}
+	if *bluesky {
+		sources = append(sources, NewBlueskyFirehose(*blueskyJetstream, blueskyKeywords, logger))
+	}
+	if len(subreddits) > 0 {
+		sources = append(sources, NewRedditSubreddits(*redditClientID, *redditClientSecret, subreddits, *redditSort, *redditPollInterval, *fetchTimeout, logger))
+	}
+	if *hackerNews {
+		sources = append(sources, NewHackerNews(*hackerNewsList, hackerNewsKeywords, *hackerNewsPollInterval, *fetchTimeout, logger))
+	}
+	if len(rssFeeds) > 0 {
+		sources = append(sources, NewFeedPoller(rssFeeds, *rssPollInterval, *fetchTimeout, logger))
+	}
+	if len(telegramChannels) > 0 {
+		sources = append(sources, NewTelegramChannels(*telegramBotToken, telegramChannels, logger))
+	}
+	if *slackEvents != "" {
+		sources = append(sources, NewSlackEvents(*slackEvents, *slackSigningSecret, logger))
+	}
+	for _, input := range inputs {
+		sources = append(sources, NewInputLines(input, logger))
+	}
+	if *importArchive != "" {
+		sources = append(sources, NewTwitterArchive(*importArchive, logger))
+	}
+	if *rawTweetsDir == "" {
+		*rawTweetsDir = filepath.Clean(*storageBasePath) + "-raw-tweets"
+	}
+	if *replay {
+		sources = append(sources, NewRawTweetReplay(*rawTweetsDir, logger))
+	} else if *retainRawTweets {
+		// replayed tweets are already retained
+		rawTweets, err := NewRawTweetLog(*rawTweetsDir, logger)
+		if err != nil {
+			log.Fatalf("can't retain raw tweets: %v", err)
+		}
+		defer rawTweets.Close()
+		tweets.RetainRawTweets(rawTweets)
+	}
+
+	if len(timelines) > 0 || len(lists) > 0 {
+		for _, user := range timelines {
+			fmt.Printf("Harvesting timeline %s in %s...\n", user, *storageBasePath)
+			HarvestUserTimeline(limit.Context(), scheduler, tweets, logger, user)
+		}
+		for _, list := range lists {
+			fmt.Printf("Harvesting list %s in %s...\n", list, *storageBasePath)
+			HarvestList(limit.Context(), scheduler, tweets, logger, list)
+		}
+		return
+	}
+
+	if *fullArchiveSearch {
+		search := NewFullArchiveSearch(*bearerToken, logger, twitterQuery, archiveStart, archiveEnd, *maxAPIRetries)
+		summary := NewHarvestSummary()
+		tweets.SummarizeTo(summary)
+		fmt.Printf("Searching Twitter's full archive: %s in %s...\n", twitterQuery, *storageBasePath)
+		tweets.HarvestFrom(limit.Context(), MergeSources(append(sources, search)...))
+		summary.Print(os.Stdout)
+		summary.Log(logger)
+		return
+	}
 
 	if *searchTwitter {
-		fmt.Printf("Searching Twitter: %s in %s...\n", twitterQuery, *storageBasePath)
-		searchResult, _ := twitterAPI.GetSearch(twitterQuery[0], nil)
-		for _, tweet := range searchResult.Statuses {
-			//createTweetTestData(contentHarvester, csvWriter, tweet.Text)
-			storage.SaveAllInText(tweet.Text)
+		params := url.Values{}
+		if len(languages) > 0 {
+			// the Search API only takes one language, the others are still enforced by the filter
+			params.Set("lang", languages[0])
+		}
+		if geoBBox.set {
+			params.Set("geocode", geoBBox.searchGeocode())
 		}
+		search := NewTwitterSearch(scheduler, logger, twitterQuery, params)
+		if state != nil {
+			search.ResumeFrom(state)
+		}
+		summary := NewHarvestSummary()
+		tweets.SummarizeTo(summary)
+		if *pollInterval > 0 {
+			fmt.Printf("Polling Twitter every %s: %s in %s...\n", *pollInterval, twitterQuery, *storageBasePath)
+			search.PollEvery(*pollInterval)
+		} else {
+			fmt.Printf("Searching Twitter: %s in %s...\n", twitterQuery, *storageBasePath)
+		}
+		tweets.HarvestFrom(limit.Context(), MergeSources(append(sources, search)...))
+		summary.Print(os.Stdout)
+		summary.Log(logger)
 		return
 	}
 
-	fmt.Printf("Starting Twitter Stream: %s in %s...\n", twitterQuery, *storageBasePath)
-	v := url.Values{"track": twitterQuery}
-	s := twitterAPI.PublicStreamFilter(v)
+	if *sampleStream {
+		fmt.Printf("Starting Twitter sample stream: %s in %s...\n", twitterQuery, *storageBasePath)
+		stream := NewSampleStream(twitterAPI, logger, twitterQuery, sampleDomains)
+		tweets.HarvestFrom(limit.Context(), MergeSources(append(sources, stream)...))
+		return
+	}
 
-	for t := range s.C {
-		switch v := t.(type) {
-		case anaconda.Tweet:
-			//createTweetTestData(contentHarvester, csvWriter, v.Text)
-			storage.SaveAllInText(v.Text)
+	if !*filterTwitterStream {
+		fmt.Printf("Harvesting %d sources in %s...\n", len(sources), *storageBasePath)
+		summary := NewHarvestSummary()
+		tweets.SummarizeTo(summary)
+		tweets.HarvestFrom(limit.Context(), MergeSources(sources...))
+		summary.Print(os.Stdout)
+		summary.Log(logger)
+		return
+	}
+
+	fmt.Printf("Starting Twitter Stream: %s in %s...\n", twitterQuery, *storageBasePath)
+	v := url.Values{}
+	if len(followUsers) > 0 {
+		followIDs, err := resolveUserIDs(limit.Context(), scheduler, followUsers)
+		if err != nil {
+			log.Fatalf("can't resolve users to follow: %v", err)
+		}
+		v.Set("follow", strings.Join(followIDs, ","))
+	}
+	if len(languages) > 0 {
+		v.Set("language", languages.String())
+	}
+	if geoBBox.set {
+		v.Set("locations", geoBBox.streamLocations())
+	}
+	stream := NewFilterStream(twitterAPI, logger, twitterQuery, v)
+	if state != nil {
+		stream.ResumeFrom(state)
+	}
+	if *backfill {
+		params := url.Values{}
+		if len(languages) > 0 {
+			params.Set("lang", languages[0])
 		}
+		stream.BackfillWith(NewStreamBackfill(scheduler, state, logger, params), *backfillGap)
+	}
+	if server != nil {
+		server.AdministerStream(stream)
 	}
+	tweets.HarvestFrom(limit.Context(), MergeSources(append(sources, stream)...))
 }
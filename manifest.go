@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/store"
+)
+
+func manifestEntry(document *StoredDocument) *store.ManifestEntry {
+	return &store.ManifestEntry{
+		Slug:        document.Key,
+		URL:         document.URL(),
+		Domain:      destinationDomain(document.Field("finalURL")),
+		HarvestedAt: document.HarvestedAt.Format(time.RFC3339),
+		Query:       document.Field("query"),
+	}
+}
+
+// MaintainManifest makes the storage keep an index.json manifest of the store up to date,
+// building it from the stored documents if there isn't one yet
+func (storage *HarvestedResourceStorage) MaintainManifest() error {
+	if err := storage.inStoreLayout("the manifest"); err != nil {
+		return err
+	}
+	manifest, found, err := store.NewManifest(filepath.Join(storage.basePath, store.ManifestFile))
+	if err != nil {
+		return err
+	}
+	if !found {
+		for _, document := range storage.StoredDocuments() {
+			manifest.Add(manifestEntry(document))
+		}
+		if err := manifest.Save(); err != nil {
+			return err
+		}
+	}
+	storage.manifest = manifest
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// mastodonMaxBackoff is the longest a Mastodon stream waits before reconnecting
+const mastodonMaxBackoff = time.Minute
+
+type mastodonAccount struct {
+	Acct string `json:"acct"`
+	URL  string `json:"url"`
+}
+
+type mastodonCard struct {
+	URL string `json:"url"`
+}
+
+type mastodonStatus struct {
+	ID       string          `json:"id"`
+	URL      string          `json:"url"`
+	Content  string          `json:"content"`
+	Language string          `json:"language"`
+	Account  mastodonAccount `json:"account"`
+	Reblog   *mastodonStatus `json:"reblog"`
+	Card     *mastodonCard   `json:"card"`
+}
+
+// MastodonStream is a Source of the statuses on a Mastodon instance's hashtag timelines, or its
+// public timeline when there are no hashtags, as they arrive over the instance's streaming API
+type MastodonStream struct {
+	instance string
+	token    string
+	hashtags []string
+	local    bool
+	logger   *zap.Logger
+	client   *http.Client
+}
+
+// NewMastodonStream streams from instance (e.g. https://mastodon.social) with the access token,
+// which most instances require; local keeps to the statuses posted on the instance itself
+func NewMastodonStream(instance string, token string, hashtags []string, local bool, logger *zap.Logger) *MastodonStream {
+	result := new(MastodonStream)
+	result.instance = strings.TrimSuffix(instance, "/")
+	result.token = token
+	for _, hashtag := range hashtags {
+		result.hashtags = append(result.hashtags, strings.TrimPrefix(hashtag, "#"))
+	}
+	result.local = local
+	result.logger = logger
+	// like the Twitter stream's, the client mustn't time out the connection
+	result.client = &http.Client{}
+	return result
+}
+
+// Start implements Source, following each timeline until ctx is done
+func (m *MastodonStream) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	var following sync.WaitGroup
+	timeline := func(hashtag string) {
+		defer following.Done()
+		m.follow(ctx, hashtag, items)
+	}
+	if len(m.hashtags) == 0 {
+		following.Add(1)
+		go timeline("")
+	}
+	for _, hashtag := range m.hashtags {
+		following.Add(1)
+		go timeline(hashtag)
+	}
+	go func() {
+		following.Wait()
+		close(items)
+	}()
+	return items
+}
+
+func (m *MastodonStream) streamURL(hashtag string) string {
+	stream := "public"
+	v := url.Values{}
+	if hashtag != "" {
+		stream = "hashtag"
+		v.Set("tag", hashtag)
+	}
+	if m.local {
+		stream += "/local"
+	}
+	return m.instance + "/api/v1/streaming/" + stream + "?" + v.Encode()
+}
+
+// follow keeps a timeline's stream connected, backing off while the instance fails
+func (m *MastodonStream) follow(ctx context.Context, hashtag string, items chan<- Item) {
+	backoff := time.Second
+	for {
+		m.logger.Info("Connecting to Mastodon stream", zap.String("instance", m.instance), zap.String("hashtag", hashtag))
+		connectedAt := time.Now()
+		err := m.receive(ctx, hashtag, items)
+		if ctx.Err() != nil {
+			m.logger.Info("Stopped Mastodon stream", zap.String("instance", m.instance), zap.String("hashtag", hashtag))
+			return
+		}
+		if time.Since(connectedAt) > mastodonMaxBackoff {
+			backoff = time.Second
+		}
+		m.logger.Warn("Mastodon stream disconnected", zap.String("instance", m.instance), zap.String("hashtag", hashtag),
+			zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > mastodonMaxBackoff {
+			backoff = mastodonMaxBackoff
+		}
+	}
+}
+
+// receive reads the server-sent events of one connection, sending each new status on
+func (m *MastodonStream) receive(ctx context.Context, hashtag string, items chan<- Item) error {
+	req, err := http.NewRequest(http.MethodGet, m.streamURL(hashtag), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	if m.token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.token)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP status %d", m.streamURL(hashtag), resp.StatusCode)
+	}
+
+	var event string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:") && event == "update":
+			var status mastodonStatus
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &status); err != nil {
+				m.logger.Warn("Unable to parse Mastodon status", zap.String("instance", m.instance), zap.Error(err))
+				continue
+			}
+			select {
+			case items <- m.item(&status, hashtag):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (m *MastodonStream) item(status *mastodonStatus, hashtag string) Item {
+	if status.Reblog != nil {
+		status = status.Reblog
+	}
+	provenance := &Provenance{Source: "mastodon", Author: "@" + status.Account.Acct, PostURL: status.URL, Lang: status.Language}
+	if hashtag != "" {
+		provenance.Query = "#" + hashtag
+	}
+	text := htmlPostText(status.Content, ".mention, .hashtag")
+	if status.Card != nil && status.Card.URL != "" && !strings.Contains(text, status.Card.URL) {
+		text += " " + status.Card.URL
+	}
+	return Item{Text: text, Provenance: provenance}
+}
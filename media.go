@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"go.uber.org/zap"
+)
+
+// maxMediaSize is the largest photo or thumbnail we download
+const maxMediaSize = 20 * 1024 * 1024
+
+// tweetDocumentKeyPrefix starts the keys of the documents written for tweets with media, as
+// opposed to the documents of harvested resources
+const tweetDocumentKeyPrefix = "tweet-"
+
+// MediaHarvester stores the photos (and GIF/video thumbnails) attached to tweets, along with a
+// document for the tweet itself so tweets that only have media aren't lost
+type MediaHarvester struct {
+	client  *http.Client
+	storage *HarvestedResourceStorage
+	logger  *zap.Logger
+}
+
+// NewMediaHarvester creates a harvester that stores media in storage
+func NewMediaHarvester(storage *HarvestedResourceStorage, logger *zap.Logger, timeout time.Duration) *MediaHarvester {
+	result := new(MediaHarvester)
+	result.client = &http.Client{Timeout: timeout}
+	result.storage = storage
+	result.logger = logger
+	return result
+}
+
+// Harvest downloads the media in tweet and returns the keys they were stored under
+func (m *MediaHarvester) Harvest(tweet *anaconda.Tweet, provenance *Provenance) []string {
+	media := tweet.ExtendedEntities.Media
+	if len(media) == 0 {
+		media = tweet.Entities.Media
+	}
+	if len(media) == 0 {
+		return nil
+	}
+
+	var keys []string
+	for i, entity := range media {
+		data, err := m.download(entity.Media_url_https)
+		if err != nil {
+			m.logger.Warn("Unable to download tweet media", zap.String("tweetID", tweet.IdStr),
+				zap.String("url", entity.Media_url_https),
+				zap.Error(err))
+			continue
+		}
+		key := fmt.Sprintf("media-%s-%d%s", tweet.IdStr, i+1, path.Ext(entity.Media_url_https))
+		if err := m.storage.diskv.Write(key, data); err != nil {
+			m.logger.Error("Unable to store tweet media", zap.String("tweetID", tweet.IdStr), zap.String("key", key), zap.Error(err))
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	provenance.addFrontMatter(fields)
+	fields["media"] = keys
+	document, err := addFrontMatter(tweet.Text+"\n", fields)
+	if err != nil {
+		m.logger.Error("Unable to add front matter", zap.String("tweetID", tweet.IdStr), zap.Error(err))
+		return keys
+	}
+	if err := m.storage.diskv.Write(tweetDocumentKeyPrefix+tweet.IdStr, []byte(document)); err != nil {
+		m.logger.Error("Unable to store tweet document", zap.String("tweetID", tweet.IdStr), zap.Error(err))
+	}
+	return keys
+}
+
+func (m *MediaHarvester) download(mediaURL string) ([]byte, error) {
+	resp, err := m.client.Get(mediaURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", mediaURL, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxMediaSize))
+}
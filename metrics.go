@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	tweetsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "harvester_tweets_received_total",
+		Help: "Tweets received from Twitter, before the tweet filters.",
+	})
+	sourceItemsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "harvester_source_items_total",
+		Help: "Posts received from sources other than Twitter, by source.",
+	}, []string{"source"})
+	resourcesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "harvester_resources_total",
+		Help: "Resources harvested from tweets, by outcome: saved, invalid, ignored, duplicate or filtered.",
+	}, []string{"outcome"})
+	domainResourcesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "harvester_domain_resources_total",
+		Help: "Resources saved, by destination domain.",
+	}, []string{"domain"})
+	resolutionHistogram = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "harvester_resolution_seconds",
+		Help:    "Time taken to discover and resolve the URLs in a tweet.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	})
+	fetchHistogram = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "harvester_fetch_seconds",
+		Help:    "Time taken to fetch destinations for enrichment, until the response headers.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	})
+	storageWriteErrorsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "harvester_storage_write_errors_total",
+		Help: "Resources that couldn't be written to storage or to one of the outputs.",
+	})
+	storageWriteRetriesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "harvester_storage_write_retries_total",
+		Help: "Storage writes retried after a transient failure.",
+	})
+	storageDeadLettersCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "harvester_storage_dead_letters_total",
+		Help: "Resources that couldn't be stored, by stage: compose or write.",
+	}, []string{"stage"})
+	storageWriteQueueFullCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "harvester_storage_write_queue_full_total",
+		Help: "Resources harvesting had to wait for because the -async-writes queue was full.",
+	})
+	streamReconnectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "harvester_stream_reconnects_total",
+		Help: "Times the filter stream was reconnected.",
+	})
+	circuitsOpenedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "harvester_circuits_opened_total",
+		Help: "Times a failing host was cut off by the circuit breaker.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(tweetsCounter, sourceItemsCounter, resourcesCounter, domainResourcesCounter, resolutionHistogram,
+		fetchHistogram, storageWriteErrorsCounter, storageWriteRetriesCounter, storageDeadLettersCounter, storageWriteQueueFullCounter, streamReconnectsCounter, circuitsOpenedCounter)
+}
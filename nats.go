@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes each stored resource as a JSON message on a NATS subject, through
+// JetStream when the messages should be persisted for consumers that aren't connected
+type NATSPublisher struct {
+	conn      *nats.Conn
+	jetStream nats.JetStreamContext
+	subject   string
+}
+
+// NewNATSPublisher connects to the NATS server at serverURL and publishes on subject; with
+// jetStream the subject must belong to a stream and each message waits for the server's ack
+func NewNATSPublisher(serverURL string, subject string, jetStream bool) (*NATSPublisher, error) {
+	conn, err := nats.Connect(serverURL, nats.Name("content-harvester-twitter"))
+	if err != nil {
+		return nil, err
+	}
+	result := new(NATSPublisher)
+	result.conn = conn
+	result.subject = subject
+	if jetStream {
+		if result.jetStream, err = conn.JetStream(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// WriteResource implements ResourceWriter
+func (n *NATSPublisher) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	data, err := json.Marshal(newResourceRecord(resource))
+	if err != nil {
+		return err
+	}
+	if n.jetStream != nil {
+		_, err = n.jetStream.Publish(n.subject, data)
+		return err
+	}
+	return n.conn.Publish(n.subject, data)
+}
+
+// Close sends whatever is still buffered and closes the connection
+func (n *NATSPublisher) Close() error {
+	return n.conn.Drain()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+)
+
+// obsidianVaultWriter writes each resource as a markdown note that wiki-links to a note for the
+// tweet it came from, the tweet's author and the destination's domain; those notes are created
+// the first time they're linked to and Obsidian's backlinks do the rest
+type obsidianVaultWriter struct {
+	basePath string
+}
+
+func (w obsidianVaultWriter) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	if err := os.MkdirAll(w.basePath, 0755); err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	if tags := resourceTags(resource); len(tags) > 0 {
+		fields["tags"] = tags
+	}
+	if _, found := resource.FrontMatter["aliases"]; !found {
+		fields["aliases"] = []string{resource.CleanedURL}
+	}
+	document, err := addFrontMatter(document, fields)
+	if err != nil {
+		return err
+	}
+
+	var links []string
+	if resource.Provenance != nil && resource.Provenance.Tweet != nil {
+		tweet := resource.Provenance.Tweet
+		tweetNote := obsidianTweetNote(tweet)
+		authorNote := obsidianAuthorNote(tweet.User)
+		links = append(links, "- Tweet: [["+tweetNote+"]]", "- Author: [["+authorNote+"]]")
+
+		if err := w.createNote(tweetNote, fmt.Sprintf("---\nauthor: \"[[%s]]\"\n---\n%s\n", authorNote, tweet.Text)); err != nil {
+			return err
+		}
+		if err := w.createNote(authorNote, fmt.Sprintf("# %s\n\nhttps://twitter.com/%s\n", tweet.User.Name, tweet.User.ScreenName)); err != nil {
+			return err
+		}
+	}
+	if domainNote := destinationDomain(resource.FinalURL); domainNote != "" {
+		links = append(links, "- Domain: [["+domainNote+"]]")
+		if err := w.createNote(domainNote, "# "+domainNote+"\n"); err != nil {
+			return err
+		}
+	}
+	for _, attachment := range attachments {
+		links = append(links, "- Attachment: [["+attachment.Key+"]]")
+		if err := ioutil.WriteFile(filepath.Join(w.basePath, attachment.Key), attachment.Data, 0644); err != nil {
+			return err
+		}
+	}
+	if len(links) > 0 {
+		document += "\n## Links\n\n" + strings.Join(links, "\n") + "\n"
+	}
+
+	return ioutil.WriteFile(filepath.Join(w.basePath, resource.Slug+".md"), []byte(document), 0644)
+}
+
+// createNote writes a note unless it already exists, so notes people edit in the vault are kept
+func (w obsidianVaultWriter) createNote(name string, content string) error {
+	file, err := os.OpenFile(filepath.Join(w.basePath, name+".md"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func obsidianTweetNote(tweet *anaconda.Tweet) string {
+	return "tweet-" + tweet.IdStr
+}
+
+func obsidianAuthorNote(user anaconda.User) string {
+	return "@" + user.ScreenName
+}
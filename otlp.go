@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpTracesPath is where OTLP/HTTP collectors take spans
+const otlpTracesPath = "/v1/traces"
+
+// OTLPHTTPExporter sends spans to an OTLP/HTTP collector (Jaeger, Tempo or the OpenTelemetry
+// Collector, usually on port 4318) in OTLP's JSON encoding, so that tracing doesn't need the
+// OTLP exporters' protobuf and gRPC dependencies
+type OTLPHTTPExporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewOTLPHTTPExporter prepares an exporter for the collector at endpoint, e.g. http://localhost:4318
+func NewOTLPHTTPExporter(endpoint string) *OTLPHTTPExporter {
+	result := new(OTLPHTTPExporter)
+	result.url = strings.TrimSuffix(endpoint, "/") + otlpTracesPath
+	result.client = &http.Client{Timeout: 10 * time.Second}
+	return result
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes,omitempty"`
+	} `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+// ExportSpans implements sdktrace.SpanExporter, posting the batch in a single request
+func (e *OTLPHTTPExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	// the harvester has a single tracer provider, so all spans share its resource
+	resourceSpans := new(otlpResourceSpans)
+	resourceSpans.Resource.Attributes = otlpAttributes(spans[0].Resource().Attributes())
+	scopes := make(map[string]*otlpScopeSpans)
+	for _, span := range spans {
+		scope, found := scopes[span.InstrumentationScope().Name]
+		if !found {
+			scope = new(otlpScopeSpans)
+			scope.Scope.Name = span.InstrumentationScope().Name
+			scope.Scope.Version = span.InstrumentationScope().Version
+			scopes[scope.Scope.Name] = scope
+			resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, scope)
+		}
+		scope.Spans = append(scope.Spans, newOTLPSpan(span))
+	}
+
+	body, err := json.Marshal(otlpTracesRequest{ResourceSpans: []*otlpResourceSpans{resourceSpans}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter; there's nothing to release
+func (e *OTLPHTTPExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func newOTLPSpan(span sdktrace.ReadOnlySpan) otlpSpan {
+	result := otlpSpan{
+		TraceID:           span.SpanContext().TraceID().String(),
+		SpanID:            span.SpanContext().SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes()),
+	}
+	if span.Parent().IsValid() {
+		result.ParentSpanID = span.Parent().SpanID().String()
+	}
+	for _, event := range span.Events() {
+		result.Events = append(result.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:         event.Name,
+			Attributes:   otlpAttributes(event.Attributes),
+		})
+	}
+	// OTLP numbers the status codes differently than the API does
+	switch span.Status().Code {
+	case codes.Ok:
+		result.Status.Code = 1
+	case codes.Error:
+		result.Status.Code = 2
+		result.Status.Message = span.Status().Description
+	}
+	return result
+}
+
+func otlpAttributes(attributes []attribute.KeyValue) []otlpAttribute {
+	var result []otlpAttribute
+	for _, kv := range attributes {
+		var value otlpValue
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			b := kv.Value.AsBool()
+			value.BoolValue = &b
+		case attribute.INT64:
+			i := strconv.FormatInt(kv.Value.AsInt64(), 10)
+			value.IntValue = &i
+		case attribute.FLOAT64:
+			f := kv.Value.AsFloat64()
+			value.DoubleValue = &f
+		default:
+			// slices are sent as their string form
+			s := kv.Value.Emit()
+			value.StringValue = &s
+		}
+		result = append(result, otlpAttribute{Key: string(kv.Key), Value: value})
+	}
+	return result
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/diskv"
+	"github.com/shah/content-harvester-utils"
+	"go.uber.org/zap"
+)
+
+// ResourceWriter persists a stored resource's composed document and its attachments
+type ResourceWriter interface {
+	WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error
+}
+
+// diskvResourceWriter is the default layout: documents and attachments side by side in the store
+type diskvResourceWriter struct {
+	diskv *diskv.Diskv
+}
+
+func (w diskvResourceWriter) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	if err := w.diskv.Write(resource.Slug, []byte(document)); err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := w.diskv.Write(attachment.Key, attachment.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hugoBundleWriter writes each resource as a Hugo leaf bundle, <slug>/index.md with its
+// attachments next to it, so the storage directory can be used as (part of) a content directory
+type hugoBundleWriter struct {
+	basePath string
+}
+
+func (w hugoBundleWriter) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	fields, _, err := readFrontMatter(document)
+	if err != nil {
+		return err
+	}
+
+	// only add what Hugo needs and the document doesn't have already
+	hugoFields := make(map[string]interface{})
+	if _, found := fields["date"]; !found {
+		hugoFields["date"] = resource.HarvestedAt.Format(time.RFC3339)
+	}
+	if _, found := fields["title"]; !found {
+		hugoFields["title"] = resource.CleanedURL
+	}
+	if _, found := fields["tags"]; !found {
+		if tags := resourceTags(resource); len(tags) > 0 {
+			hugoFields["tags"] = tags
+		}
+	}
+	if _, found := fields["canonical"]; !found {
+		canonical, _ := resource.FrontMatter["canonicalURL"].(string)
+		if canonical == "" {
+			canonical = resource.CleanedURL
+		}
+		hugoFields["canonical"] = canonical
+	}
+	if document, err = addFrontMatter(document, hugoFields); err != nil {
+		return err
+	}
+
+	bundle := filepath.Join(w.basePath, resource.Slug)
+	if err := os.MkdirAll(bundle, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(bundle, "index.md"), []byte(document), 0644); err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := ioutil.WriteFile(filepath.Join(bundle, attachment.Key), attachment.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resourceTags is what output formats with a notion of tags use: the tweet's hashtags and the
+// extracted keywords
+func resourceTags(resource *DocumentTemplateData) []string {
+	var tags []string
+	for _, field := range []string{"hashtags", "keywords"} {
+		values, _ := resource.FrontMatter[field].([]string)
+		for _, value := range values {
+			tags = appendDistinct(tags, value)
+		}
+	}
+	return tags
+}
+
+// destinationDomain is the host of a resource's final URL without the www. prefix, or empty if
+// it can't be parsed
+func destinationDomain(finalURL string) string {
+	destination, err := url.Parse(finalURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(destination.Hostname(), "www.")
+}
+
+// exportFormat tells whether format lays resources out for another tool to read rather than in
+// the store's own diskv layout
+func exportFormat(format string) bool {
+	return format == "hugo" || format == "obsidian"
+}
+
+// inStoreLayout returns an error naming feature when resources are written in an export format,
+// which retention, the manifest and reading stored documents back don't cover
+func (storage *HarvestedResourceStorage) inStoreLayout(feature string) error {
+	if exportFormat(storage.outputFormat) {
+		return fmt.Errorf("%s only covers the diskv output format, not %s", feature, storage.outputFormat)
+	}
+	return nil
+}
+
+// UseOutputFormat selects how stored resources are laid out: "diskv" (the default, flat files
+// keyed by slug), "hugo" (page bundles) or "obsidian" (a vault of linked notes). Hugo and
+// Obsidian read their files as they are, so those formats can't be used when the store is
+// compressed or encrypted.
+//
+// Hugo's and Obsidian's layouts are exports: what's written there isn't in the store proper
+// (Obsidian's notes for tweets, authors and domains least of all), so pruning and
+// the manifest refuse to work with it, and StoredDocuments and everything reading the store
+// back (feeds, digests, the serve mode's APIs, reindexing) only find the resources stored in
+// the diskv layout.
+func (storage *HarvestedResourceStorage) UseOutputFormat(format string) error {
+	writer, err := storage.outputWriter(format)
+	if err != nil {
+		return err
+	}
+	storage.useWriter(format, writer)
+	return nil
+}
+
+// outputWriter returns the writer laying resources out in format, if the storage can use it
+func (storage *HarvestedResourceStorage) outputWriter(format string) (ResourceWriter, error) {
+	var writer ResourceWriter
+	switch format {
+	case "", "diskv":
+		writer = diskvResourceWriter{diskv: storage.diskv}
+	case "hugo":
+		writer = hugoBundleWriter{basePath: storage.basePath}
+	case "obsidian":
+		writer = obsidianVaultWriter{basePath: storage.basePath}
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+	if _, diskvLayout := writer.(diskvResourceWriter); !diskvLayout && storage.compression != nil {
+		return nil, fmt.Errorf("the %s output format is written in plain text, it can't be used with storage-compression or storage-encryption-key", format)
+	}
+	if exportFormat(format) && storage.manifest != nil {
+		return nil, fmt.Errorf("the manifest only covers the diskv output format, not %s", format)
+	}
+	return writer, nil
+}
+
+// useWriter makes the storage write resources in format with writer, through the async writer
+// if there's one
+func (storage *HarvestedResourceStorage) useWriter(format string, writer ResourceWriter) {
+	storage.outputFormat = format
+	if storage.asyncWriter != nil {
+		storage.asyncWriter.writeTo(writer)
+		return
+	}
+	storage.writer = writer
+}
+
+// writeRecord writes a document that isn't a harvested resource, like a URL error record, with
+// the writer resources are written with, retries included, but without counting it as stored
+func (storage *HarvestedResourceStorage) writeRecord(record *DocumentTemplateData, document string) {
+	failed := func(write *pendingWrite, attempts int, err error) {
+		storageWriteErrorsCounter.Inc()
+		storage.logger.Error("Unable to write record", zap.String("key", write.resource.Slug),
+			zap.Int("attempts", attempts), zap.Error(err))
+	}
+	write := &pendingWrite{resource: record, document: document, failed: failed}
+	if storage.asyncWriter != nil {
+		if err := storage.asyncWriter.enqueue(write); err != nil {
+			failed(write, 1, err)
+		}
+		return
+	}
+	if attempts, err := writeWithRetries(storage.writer, record, document, nil, storage.writeRetries); err != nil {
+		failed(write, attempts, err)
+	}
+}
+
+// OutputTo sends each stored resource to writer as well, after it's been written to the store;
+// writers that are io.Closers are closed with the storage
+func (storage *HarvestedResourceStorage) OutputTo(writer ResourceWriter) {
+	storage.outputs = append(storage.outputs, writer)
+}
+
+// IgnoredResource is a harvested resource that wasn't stored, along with why
+type IgnoredResource struct {
+	Text        string `json:"text"`
+	OriginalURL string `json:"originalURL"`
+	FinalURL    string `json:"finalURL,omitempty"`
+	// Outcome is invalid, ignored, duplicate or filtered
+	Outcome    string      `json:"outcome"`
+	Reason     string      `json:"reason"`
+	IgnoredAt  time.Time   `json:"ignoredAt"`
+	Provenance *Provenance `json:"-"`
+}
+
+// IgnoreObserver is told about each harvested resource that the storage doesn't store, be it
+// invalid, ignored by a rule, a duplicate or of a filtered content type
+type IgnoreObserver interface {
+	ResourceIgnored(resource *IgnoredResource)
+}
+
+// ObserveIgnoredWith makes the storage tell observer about the resources it doesn't store
+func (storage *HarvestedResourceStorage) ObserveIgnoredWith(observer IgnoreObserver) {
+	storage.ignoreObservers = append(storage.ignoreObservers, observer)
+}
+
+func (storage *HarvestedResourceStorage) ignored(text string, provenance *Provenance, originalURL string, finalURL string, outcome string, reason string) {
+	resourcesCounter.WithLabelValues(outcome).Inc()
+	if storage.summary != nil {
+		storage.summary.resourceIgnored(outcome, reason)
+	}
+	if storage.dryRun != nil {
+		fmt.Fprintf(storage.dryRun, "Would ignore %s: %s\n", originalURL, reason)
+	}
+	if len(storage.ignoreObservers) == 0 {
+		return
+	}
+	resource := &IgnoredResource{
+		Text:        text,
+		OriginalURL: originalURL,
+		FinalURL:    finalURL,
+		Outcome:     outcome,
+		Reason:      reason,
+		IgnoredAt:   time.Now(),
+		Provenance:  provenance,
+	}
+	for _, observer := range storage.ignoreObservers {
+		observer.ResourceIgnored(resource)
+	}
+}
+
+// observeHarvesterIgnores reports the resources the harvester itself rejected, which never
+// make it to serialization
+func (storage *HarvestedResourceStorage) observeHarvesterIgnores(text string, provenance *Provenance, resources []*harvester.HarvestedResource, reasons invalidURLReasons) {
+	for _, res := range resources {
+		isURLValid, isDestValid := res.IsValid()
+		if !isURLValid {
+			storage.ignored(text, provenance, res.OriginalURLText(), "", "invalid", "Invalid URL: "+reasons[res])
+			continue
+		}
+		isIgnored, ignoreReason := res.IsIgnored()
+		if !isDestValid {
+			if !isIgnored {
+				ignoreReason = "Invalid URL destination"
+			}
+			storage.ignored(text, provenance, res.OriginalURLText(), "", "invalid", ignoreReason)
+			continue
+		}
+		if isIgnored {
+			finalURL, _, _ := res.GetURLs()
+			storage.ignored(text, provenance, res.OriginalURLText(), urlToString(finalURL), "ignored", ignoreReason)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/store"
+)
+
+func TestPlainTextOutputFormatsRefuseCompressedStorage(t *testing.T) {
+	compression, err := store.NewCompression("gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &HarvestedResourceStorage{basePath: t.TempDir(), compression: compression}
+	for _, format := range []string{"hugo", "obsidian"} {
+		if err := storage.UseOutputFormat(format); err == nil {
+			t.Errorf("the %s output format was used with a compressed store", format)
+		}
+	}
+	if err := storage.UseOutputFormat("diskv"); err != nil {
+		t.Errorf("the diskv output format wasn't used with a compressed store: %v", err)
+	}
+}
+
+func TestExportOutputFormatsArentPrunedOrInTheManifest(t *testing.T) {
+	for _, format := range []string{"hugo", "obsidian"} {
+		storage := &HarvestedResourceStorage{basePath: t.TempDir()}
+		if err := storage.UseOutputFormat(format); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.Prune(RetentionPolicy{MaxAge: time.Hour}, time.Now()); err == nil {
+			t.Errorf("the %s output was pruned", format)
+		}
+		if err := storage.MaintainManifest(); err == nil {
+			t.Errorf("a manifest was kept of the %s output", format)
+		}
+
+		manifest, _, err := store.NewManifest(filepath.Join(storage.basePath, store.ManifestFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		storage = &HarvestedResourceStorage{basePath: storage.basePath, manifest: manifest}
+		if err := storage.UseOutputFormat(format); err == nil {
+			t.Errorf("switched to the %s output format while keeping a manifest", format)
+		}
+	}
+}
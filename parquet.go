@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/xitongsys/parquet-go/ParquetFile"
+	"github.com/xitongsys/parquet-go/ParquetWriter"
+	"github.com/xitongsys/parquet-go/parquet"
+)
+
+// parquetResourceRow is the Parquet schema the parquet output writes, one row per stored resource
+type parquetResourceRow struct {
+	Slug        string `parquet:"name=slug, type=UTF8"`
+	Query       string `parquet:"name=query, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Timeline    string `parquet:"name=timeline, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	List        string `parquet:"name=list, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TweetID     string `parquet:"name=tweet_id, type=UTF8"`
+	Author      string `parquet:"name=author, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Domain      string `parquet:"name=domain, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	TweetedAt   string `parquet:"name=tweeted_at, type=UTF8"`
+	HarvestedAt int64  `parquet:"name=harvested_at, type=TIMESTAMP_MILLIS"`
+	OriginalURL string `parquet:"name=original_url, type=UTF8"`
+	FinalURL    string `parquet:"name=final_url, type=UTF8"`
+	ResolvedURL string `parquet:"name=resolved_url, type=UTF8"`
+	CleanedURL  string `parquet:"name=cleaned_url, type=UTF8"`
+	Text        string `parquet:"name=text, type=UTF8"`
+}
+
+// ParquetExporter writes stored resources to a Parquet file for DuckDB, Spark or Athena; the
+// file is only complete once the writer is closed
+type ParquetExporter struct {
+	mutex  sync.Mutex
+	file   ParquetFile.ParquetFile
+	writer *ParquetWriter.ParquetWriter
+}
+
+// NewParquetExporter creates (or replaces) the Parquet file at path
+func NewParquetExporter(path string) (*ParquetExporter, error) {
+	file, err := ParquetFile.NewLocalFileWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := ParquetWriter.NewParquetWriter(file, new(parquetResourceRow), 1)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	result := new(ParquetExporter)
+	result.file = file
+	result.writer = writer
+	return result, nil
+}
+
+// WriteResource implements ResourceWriter
+func (w *ParquetExporter) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	record := newResourceRecord(resource)
+	row := parquetResourceRow{
+		Slug:        record.Slug,
+		Query:       record.Query,
+		Timeline:    record.Timeline,
+		List:        record.List,
+		TweetID:     record.TweetID,
+		Author:      record.Author,
+		Domain:      destinationDomain(record.FinalURL),
+		TweetedAt:   record.TweetedAt,
+		HarvestedAt: record.HarvestedAt.UnixNano() / 1e6,
+		OriginalURL: record.OriginalURL,
+		FinalURL:    record.FinalURL,
+		ResolvedURL: record.ResolvedURL,
+		CleanedURL:  record.CleanedURL,
+		Text:        record.Text,
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(row)
+}
+
+// Close writes the Parquet footer and closes the file
+func (w *ParquetExporter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.writer.WriteStop(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+)
+
+// defaultPaywalledDomains are news sites known to keep most of their articles behind a paywall
+var defaultPaywalledDomains = filter.DomainList{
+	"barrons.com", "bloomberg.com", "economist.com", "ft.com", "hbr.org", "latimes.com",
+	"newyorker.com", "nytimes.com", "telegraph.co.uk", "theatlantic.com", "theathletic.com",
+	"thetimes.co.uk", "washingtonpost.com", "wired.com", "wsj.com",
+}
+
+// paywallEnricher records isPaywalled in the front matter of resources whose destination is
+// behind a paywall: a 402 Payment Required answer, schema.org markup saying the article isn't
+// free, a locked or metered content tier, or a domain known for its paywall. paywallSignal
+// says which of them it was.
+type paywallEnricher struct {
+	domains filter.DomainList
+}
+
+func (e paywallEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	signal := paywallSignal(page, e.domains)
+	resource.FrontMatter["isPaywalled"] = signal != ""
+	if signal != "" {
+		resource.FrontMatter["paywallSignal"] = signal
+	}
+}
+
+// paywallSignal returns what gives page's paywall away, or "" if it doesn't seem to have one
+func paywallSignal(page *FetchedPage, domains filter.DomainList) string {
+	if page.StatusCode == http.StatusPaymentRequired {
+		return "http-402"
+	}
+	if doc, err := page.Document(); err == nil {
+		if signal := paywallMarkup(doc); signal != "" {
+			return signal
+		}
+	}
+	if domains.Matches(page.URL.Hostname()) {
+		return "known-domain"
+	}
+	return ""
+}
+
+func paywallMarkup(doc *goquery.Document) string {
+	signal := ""
+	doc.Find("meta").EachWithBreak(func(i int, meta *goquery.Selection) bool {
+		name := strings.ToLower(meta.AttrOr("property", meta.AttrOr("name", "")))
+		content := strings.ToLower(strings.TrimSpace(meta.AttrOr("content", "")))
+		switch {
+		case name == "article:content_tier" && (content == "locked" || content == "metered"):
+			signal = "content-tier-" + content
+		case name == "isaccessibleforfree" && content == "false":
+			signal = "not-accessible-for-free"
+		}
+		return signal == ""
+	})
+	if signal != "" {
+		return signal
+	}
+
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, script *goquery.Selection) bool {
+		var data interface{}
+		if json.Unmarshal([]byte(script.Text()), &data) == nil && notAccessibleForFree(data) {
+			signal = "not-accessible-for-free"
+		}
+		return signal == ""
+	})
+	return signal
+}
+
+// notAccessibleForFree looks for "isAccessibleForFree": false anywhere in schema.org JSON-LD
+func notAccessibleForFree(data interface{}) bool {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if key == "isAccessibleForFree" {
+				switch free := field.(type) {
+				case bool:
+					if !free {
+						return true
+					}
+				case string:
+					if strings.EqualFold(free, "false") {
+						return true
+					}
+				}
+			}
+			if notAccessibleForFree(field) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, element := range value {
+			if notAccessibleForFree(element) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfTextEnricher does for PDF destinations what article extraction does for web pages, since
+// so many of the research links people share are PDFs
+type pdfTextEnricher struct{}
+
+func (pdfTextEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	// the cross reference table is at the end, so a partial PDF can't be read
+	if page.ContentType() != "application/pdf" || page.Truncated {
+		return
+	}
+
+	title, pageCount, text := readPDF(page.Body)
+	if title != "" {
+		if _, found := resource.FrontMatter["title"]; !found {
+			resource.FrontMatter["title"] = title
+		}
+	}
+	if pageCount > 0 {
+		resource.FrontMatter["pageCount"] = pageCount
+	}
+	if text == "" {
+		return
+	}
+	resource.ArticleText = text
+	resource.FrontMatter["wordCount"] = len(strings.Fields(text))
+	resource.Sections = append(resource.Sections, text)
+}
+
+// readPDF returns what it can of the PDF's title, page count and text. The PDF reader panics on
+// some malformed PDFs, which anyone can link to, so a panic only means there's no more to read.
+func readPDF(data []byte) (title string, pageCount int, text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", 0, ""
+	}
+	title = strings.TrimSpace(reader.Trailer().Key("Info").Key("Title").Text())
+	pageCount = reader.NumPage()
+
+	plainText, err := reader.GetPlainText()
+	if err != nil {
+		return title, pageCount, ""
+	}
+	extracted, err := ioutil.ReadAll(plainText)
+	if err != nil {
+		return title, pageCount, ""
+	}
+	return title, pageCount, strings.TrimSpace(string(extracted))
+}
@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultDomainCategories are well known adult and gambling sites, so they can be excluded
+// without a category list of one's own
+var defaultDomainCategories = map[string]string{
+	"chaturbate.com":  "adult",
+	"onlyfans.com":    "adult",
+	"pornhub.com":     "adult",
+	"redtube.com":     "adult",
+	"xhamster.com":    "adult",
+	"xnxx.com":        "adult",
+	"xvideos.com":     "adult",
+	"youporn.com":     "adult",
+	"888casino.com":   "gambling",
+	"bet365.com":      "gambling",
+	"betfair.com":     "gambling",
+	"draftkings.com":  "gambling",
+	"fanduel.com":     "gambling",
+	"paddypower.com":  "gambling",
+	"pokerstars.com":  "gambling",
+	"williamhill.com": "gambling",
+}
+
+// DomainCategories knows the category (e.g. adult or gambling) of domains and their subdomains
+type DomainCategories struct {
+	categories map[string]string
+}
+
+// NewDomainCategories starts with the bundled categories
+func NewDomainCategories() *DomainCategories {
+	result := new(DomainCategories)
+	result.categories = make(map[string]string)
+	for domain, category := range defaultDomainCategories {
+		result.categories[domain] = category
+	}
+	return result
+}
+
+// Load adds the categories in the file at path, one "domain category" pair per line; they
+// take precedence over the bundled ones
+func (c *DomainCategories) Load(path string) error {
+	lines, err := ReadListFile(path)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s: expected a domain and a category, got %q", path, line)
+		}
+		c.categories[strings.TrimPrefix(strings.ToLower(fields[0]), "www.")] = strings.ToLower(fields[1])
+	}
+	return nil
+}
+
+// Category returns the category of host, or of the closest domain it's a subdomain of, or ""
+func (c *DomainCategories) Category(host string) string {
+	host = strings.ToLower(host)
+	for {
+		if category, found := c.categories[host]; found {
+			return category
+		}
+		dot := strings.Index(host, ".")
+		if dot < 0 {
+			return ""
+		}
+		host = host[dot+1:]
+	}
+}
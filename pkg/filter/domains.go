@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"strings"
+)
+
+// DomainList is a comma separated list of domains, each also matching its subdomains
+type DomainList []string
+
+func (l *DomainList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value, adding the comma separated domains in value
+func (l *DomainList) Set(value string) error {
+	for _, domain := range strings.Split(value, ",") {
+		if domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www."); domain != "" {
+			*l = append(*l, domain)
+		}
+	}
+	return nil
+}
+
+// Matches is true if host is one of the domains or a subdomain of one
+func (l DomainList) Matches(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range l {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+// Package filter has what the harvester decides which resources to keep with that doesn't
+// depend on where they came from: domain lists and categories, and list files to read them from.
+package filter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// ReadListFile reads one entry per line from path, skipping blank lines and # comments
+func ReadListFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result, scanner.Err()
+}
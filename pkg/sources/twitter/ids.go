@@ -0,0 +1,13 @@
+package twitter
+
+import "time"
+
+// epoch is when tweet IDs start counting; an ID's upper bits are the milliseconds since then
+// that the tweet was posted
+const epoch = 1288834974657
+
+// PostedAt returns when the tweet with id was posted, going by the ID alone
+func PostedAt(id int64) time.Time {
+	ms := id>>22 + epoch
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
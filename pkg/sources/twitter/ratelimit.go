@@ -0,0 +1,229 @@
+// Package twitter has what the harvester's Twitter sources share: the credentials they run on
+// and the scheduling of their API requests within Twitter's rate limits. The sources
+// themselves are still in the harvester's package main, with the pipeline they feed.
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+	"go.uber.org/zap"
+)
+
+// APIHosts are Twitter's own APIs, which are rate limited by the RateLimitScheduler and must
+// never be held back like destinations are
+var APIHosts = []string{"api.twitter.com", "stream.twitter.com", "upload.twitter.com"}
+
+// Credentials is one set of a Twitter app's keys and a user's access tokens
+type Credentials struct {
+	ConsumerKey    string
+	ConsumerSecret string
+	AccessToken    string
+	AccessSecret   string
+}
+
+// Complete is true when none of the keys or tokens is missing
+func (c Credentials) Complete() bool {
+	return c.ConsumerKey != "" && c.ConsumerSecret != "" && c.AccessToken != "" && c.AccessSecret != ""
+}
+
+// ReadCredentialsFile reads a set of credentials per line, its consumer key, consumer secret,
+// access token and access secret separated by spaces
+func ReadCredentialsFile(path string) ([]Credentials, error) {
+	lines, err := filter.ReadListFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []Credentials
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s: set %d should have consumer key, consumer secret, access token and access secret", path, i+1)
+		}
+		result = append(result, Credentials{fields[0], fields[1], fields[2], fields[3]})
+	}
+	return result, nil
+}
+
+// rateLimitFallbackCalls and rateLimitFallbackWindow are the budget assumed when Twitter's rate
+// limit status isn't available: the smallest limit, of 15 calls in 15 minutes, any endpoint has
+const rateLimitFallbackCalls = 15
+const rateLimitFallbackWindow = 15 * time.Minute
+
+type rateLimitBudget struct {
+	remaining int
+	reset     time.Time
+}
+
+// rateLimitCredentials is one set of Twitter credentials, with rate limit windows of its own
+type rateLimitCredentials struct {
+	api     *anaconda.TwitterApi
+	budgets map[string]*rateLimitBudget
+}
+
+// RateLimitScheduler keeps track of the remaining calls in each rate limit window of Twitter API
+// requests, reserving one for each request, and waits instead of exceeding them. Given more than
+// one set of credentials, each has its own windows and requests rotate to the next set with calls
+// left when one's are used up, only waiting once they all are.
+type RateLimitScheduler struct {
+	credentials []*rateLimitCredentials
+	current     int
+	logger      *zap.Logger
+	maxRetries  int
+	backoff     time.Duration
+	mutex       sync.Mutex
+}
+
+// NewRateLimitScheduler creates a scheduler for the apis, one per set of credentials, that
+// retries failed requests up to maxRetries times
+func NewRateLimitScheduler(apis []*anaconda.TwitterApi, logger *zap.Logger, maxRetries int) *RateLimitScheduler {
+	result := new(RateLimitScheduler)
+	for _, api := range apis {
+		result.credentials = append(result.credentials, &rateLimitCredentials{api: api, budgets: make(map[string]*rateLimitBudget)})
+	}
+	result.logger = logger
+	result.maxRetries = maxRetries
+	result.backoff = 5 * time.Second
+	return result
+}
+
+// Do runs request, with the api of the credentials to use, once there's budget left for
+// endpoint (e.g. "/search/tweets" in the "search" family), waiting out rate limit windows and
+// backing off on transient errors until ctx is done
+func (s *RateLimitScheduler) Do(ctx context.Context, family string, endpoint string, request func(api *anaconda.TwitterApi) error) error {
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		credentials, err := s.reserve(ctx, family, endpoint)
+		if err != nil {
+			return err
+		}
+		err = request(credentials.api)
+		if err == nil {
+			return nil
+		}
+
+		retryAfter := backoff
+		if apiErr, ok := err.(*anaconda.ApiError); ok {
+			if isRateLimited, nextWindow := apiErr.RateLimitCheck(); isRateLimited {
+				// the next attempt goes to other credentials, if there are any with calls left
+				s.mutex.Lock()
+				credentials.budgets[endpoint] = &rateLimitBudget{remaining: 0, reset: nextWindow}
+				s.mutex.Unlock()
+				retryAfter = 0
+			} else if apiErr.StatusCode < http.StatusInternalServerError {
+				return err
+			}
+		}
+
+		if attempt >= s.maxRetries {
+			return err
+		}
+		s.logger.Warn("Twitter API request failed, retrying", zap.String("endpoint", endpoint),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("retryAfter", retryAfter),
+			zap.Error(err))
+		if retryAfter > 0 {
+			if err := sleepContext(ctx, retryAfter); err != nil {
+				return err
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// reserve waits until one of the credentials has at least one call left in endpoint's window,
+// uses it up and returns those credentials; the current ones are kept while they have calls left
+func (s *RateLimitScheduler) reserve(ctx context.Context, family string, endpoint string) (*rateLimitCredentials, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		s.mutex.Lock()
+		var reserved, stale *rateLimitCredentials
+		var earliest time.Time
+		for i := range s.credentials {
+			index := (s.current + i) % len(s.credentials)
+			credentials := s.credentials[index]
+			budget, found := credentials.budgets[endpoint]
+			if !found || (budget.remaining <= 0 && time.Now().After(budget.reset)) {
+				if stale == nil {
+					stale = credentials
+				}
+				continue
+			}
+			if budget.remaining > 0 {
+				if index != s.current {
+					s.logger.Info("Rotating Twitter credentials", zap.String("endpoint", endpoint), zap.Int("credentials", index+1))
+					s.current = index
+				}
+				budget.remaining--
+				reserved = credentials
+				break
+			}
+			if earliest.IsZero() || budget.reset.Before(earliest) {
+				earliest = budget.reset
+			}
+		}
+		s.mutex.Unlock()
+
+		if reserved != nil {
+			return reserved, nil
+		}
+		if stale != nil {
+			s.refresh(stale, family, endpoint)
+			continue
+		}
+
+		wait := time.Until(earliest)
+		if wait <= 0 {
+			// the window should be over; refreshing it next time round says for sure
+			wait = time.Second
+		}
+		s.logger.Info("Twitter rate limit reached, waiting for next window", zap.String("endpoint", endpoint),
+			zap.Duration("wait", wait))
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// refresh asks Twitter for the current rate limit status of endpoint for credentials; if that
+// isn't available we allow rateLimitFallbackCalls calls in rateLimitFallbackWindow and let the
+// error handling in Do catch up, rather than asking again before every request
+func (s *RateLimitScheduler) refresh(credentials *rateLimitCredentials, family string, endpoint string) {
+	budget := &rateLimitBudget{remaining: rateLimitFallbackCalls, reset: time.Now().Add(rateLimitFallbackWindow)}
+	status, err := credentials.api.GetRateLimits([]string{family})
+	if err != nil {
+		s.logger.Warn("Unable to get Twitter rate limit status", zap.String("endpoint", endpoint), zap.Error(err))
+	} else if limits, found := status.Resources[family][endpoint]; found {
+		budget.remaining = limits.Remaining
+		budget.reset = time.Unix(int64(limits.Reset), 0)
+	}
+	// a reset that's already past (or clocks that disagree) would have the budget refreshed
+	// again straight away, over and over while it's used up
+	if earliest := time.Now().Add(time.Second); budget.reset.Before(earliest) {
+		budget.reset = earliest
+	}
+	s.mutex.Lock()
+	credentials.budgets[endpoint] = budget
+	s.mutex.Unlock()
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	}
+}
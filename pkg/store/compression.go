@@ -0,0 +1,67 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/peterbourgon/diskv"
+)
+
+// compressedHeader starts every file compressed by the store, followed by a byte for the codec;
+// files without it are read as is, so -compress-archived-html's .gz files stay gzipped
+var compressedHeader = []byte("\x00chz")
+
+// storageCompression is the diskv compression of the store: everything written is compressed
+// with codec ("gzip" or "zstd"), and what's read is decompressed according to how it was
+// compressed, if at all, so stores written before compression was turned on (or with another
+// codec) stay readable
+type storageCompression struct {
+	codec string
+}
+
+// NewCompression returns the diskv compression for codec, or nil for "none"
+func NewCompression(codec string) (diskv.Compression, error) {
+	switch codec {
+	case "", "none":
+		return nil, nil
+	case "gzip", "zstd":
+		return storageCompression{codec: codec}, nil
+	}
+	return nil, fmt.Errorf("unknown storage compression %q", codec)
+}
+
+func (c storageCompression) Writer(dst io.Writer) (io.WriteCloser, error) {
+	if _, err := dst.Write(append(append([]byte{}, compressedHeader...), c.codec[0])); err != nil {
+		return nil, err
+	}
+	if c.codec == "zstd" {
+		return zstd.NewWriter(dst)
+	}
+	return gzip.NewWriter(dst), nil
+}
+
+func (c storageCompression) Reader(src io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(src)
+	header, _ := buffered.Peek(len(compressedHeader) + 1)
+	if len(header) <= len(compressedHeader) || !bytes.HasPrefix(header, compressedHeader) {
+		return ioutil.NopCloser(buffered), nil
+	}
+	codec := header[len(compressedHeader)]
+	buffered.Discard(len(header))
+	switch codec {
+	case 'z':
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case 'g':
+		return gzip.NewReader(buffered)
+	}
+	return nil, fmt.Errorf("unknown storage compression codec %q", codec)
+}
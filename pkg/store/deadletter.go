@@ -0,0 +1,76 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// DeadLetter is what the dead-letter directory records about a resource that couldn't be stored
+type DeadLetter struct {
+	Slug        string    `json:"slug"`
+	Stage       string    `json:"stage"`
+	Error       string    `json:"error"`
+	Attempts    int       `json:"attempts"`
+	FailedAt    time.Time `json:"failedAt"`
+	OriginalURL string    `json:"originalURL"`
+	FinalURL    string    `json:"finalURL"`
+	Text        string    `json:"text"`
+}
+
+// DeadLetters keeps the resources that couldn't be composed or written in a directory of their
+// own, <slug>.json saying why next to the document (<slug>.md) and its attachments, so they can
+// be looked into and stored again rather than lost
+type DeadLetters struct {
+	basePath string
+}
+
+// NewDeadLetters keeps failed resources in basePath
+func NewDeadLetters(basePath string) *DeadLetters {
+	result := new(DeadLetters)
+	result.basePath = basePath
+	return result
+}
+
+// Keep writes letter, the resource's document as far as it got and its attachments (by key) to
+// the dead-letter directory
+func (d *DeadLetters) Keep(letter *DeadLetter, document string, attachments map[string][]byte) error {
+	if err := os.MkdirAll(d.basePath, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(d.basePath, letter.Slug+".md"), []byte(document), 0644); err != nil {
+		return err
+	}
+	for key, data := range attachments {
+		if err := ioutil.WriteFile(filepath.Join(d.basePath, key), data, 0644); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(d.basePath, letter.Slug+".json"), data, 0644)
+}
+
+// TransientWriteError is false for the failures retrying won't fix: a full, read-only or
+// forbidden disk and keys the file system can't take
+func TransientWriteError(err error) bool {
+	var errno syscall.Errno
+	switch cause := err.(type) {
+	case *os.PathError:
+		errno, _ = cause.Err.(syscall.Errno)
+	case *os.LinkError:
+		errno, _ = cause.Err.(syscall.Errno)
+	case syscall.Errno:
+		errno = cause
+	}
+	switch errno {
+	case syscall.ENOSPC, syscall.EROFS, syscall.EACCES, syscall.EPERM, syscall.ENAMETOOLONG, syscall.EINVAL:
+		return false
+	}
+	return true
+}
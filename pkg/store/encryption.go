@@ -0,0 +1,125 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	"github.com/peterbourgon/diskv"
+)
+
+// encryptedHeader starts every file the store encrypted, followed by the nonce
+var encryptedHeader = []byte("\x00che")
+
+// storageEncryption encrypts everything the store writes with AES-GCM, after compressing it
+// with the store's compression if there is one. Files without the header are read as they
+// are, so a store can be encrypted from a given point on.
+type storageEncryption struct {
+	aead        cipher.AEAD
+	compression diskv.Compression
+}
+
+// NewEncryption encrypts with key, a base64 encoded 16, 24 or 32 byte AES key, on top of
+// compression (which may be nil)
+func NewEncryption(key string, compression diskv.Compression) (diskv.Compression, error) {
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key isn't base64: %v", err)
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return storageEncryption{aead: aead, compression: compression}, nil
+}
+
+// EncryptionKeyFromCommand runs command line through the shell and returns what it prints, so
+// the key can come from a KMS (e.g. aws kms decrypt or gcloud kms decrypt) rather than sit in
+// the environment
+func EncryptionKeyFromCommand(command string) (string, error) {
+	output, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (e storageEncryption) Writer(dst io.Writer) (io.WriteCloser, error) {
+	writer := &encryptingWriter{dst: dst, aead: e.aead}
+	if e.compression == nil {
+		return writer, nil
+	}
+	compressor, err := e.compression.Writer(&writer.plaintext)
+	if err != nil {
+		return nil, err
+	}
+	writer.compressor = compressor
+	return writer, nil
+}
+
+func (e storageEncryption) Reader(src io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(src)
+	if header, _ := buffered.Peek(len(encryptedHeader)); bytes.Equal(header, encryptedHeader) {
+		data, err := ioutil.ReadAll(buffered)
+		if err != nil {
+			return nil, err
+		}
+		data = data[len(encryptedHeader):]
+		nonceSize := e.aead.NonceSize()
+		if len(data) < nonceSize {
+			return nil, fmt.Errorf("encrypted file is truncated")
+		}
+		plaintext, err := e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("can't decrypt: %v", err)
+		}
+		buffered = bufio.NewReader(bytes.NewReader(plaintext))
+	}
+	if e.compression == nil {
+		return ioutil.NopCloser(buffered), nil
+	}
+	return e.compression.Reader(buffered)
+}
+
+// encryptingWriter collects (and compresses) the whole file, since GCM seals it in one go
+type encryptingWriter struct {
+	dst        io.Writer
+	aead       cipher.AEAD
+	compressor io.WriteCloser
+	plaintext  bytes.Buffer
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+	return w.plaintext.Write(p)
+}
+
+func (w *encryptingWriter) Close() error {
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			return err
+		}
+	}
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := append(append([]byte{}, encryptedHeader...), nonce...)
+	sealed = w.aead.Seal(sealed, nonce, w.plaintext.Bytes(), nil)
+	_, err := w.dst.Write(sealed)
+	return err
+}
@@ -0,0 +1,159 @@
+// Package store has the building blocks of the harvester's diskv store: how files are laid
+// out, compressed and encrypted, and the manifest kept next to them.
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPartitionedPath is the directory of partitioned stores, which carry on from one run to
+// the next rather than starting a directory per run
+const DefaultPartitionedPath = "./tmp/storage"
+
+// Layout decides which subdirectory of the store each key goes in, so a store doesn't
+// end up with hundreds of thousands of files in one directory: "flat" (all in the base
+// directory, the way stores always were), "date" (a directory per day harvested), "partitioned"
+// (a YYYY/MM/DD subtree per day, a new one started at midnight), "hash" (two levels named after
+// the key's hash) or "domain" (a directory per destination domain).
+// Keys already in the store stay where they are, whatever the layout, and attachments go in
+// the same directory as their document.
+type Layout struct {
+	kind      string
+	basePath  string
+	mutex     sync.Mutex
+	paths     map[string][]string
+	partition string
+	rotated   func(partition string)
+}
+
+// NewLayout lays out the store in basePath as kind, finding the keys already in it
+func NewLayout(kind string, basePath string) (*Layout, error) {
+	switch kind {
+	case "", "flat":
+		kind = "flat"
+	case "date", "partitioned", "hash", "domain":
+	default:
+		return nil, fmt.Errorf("unknown storage layout %q", kind)
+	}
+	result := new(Layout)
+	result.kind = kind
+	result.basePath = basePath
+	result.paths = make(map[string][]string)
+	if kind == "flat" {
+		return result, nil
+	}
+
+	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == basePath {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		dir, err := filepath.Rel(basePath, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		result.paths[info.Name()] = splitLayoutPath(dir)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func splitLayoutPath(dir string) []string {
+	if dir == "." {
+		return []string{}
+	}
+	return strings.Split(filepath.ToSlash(dir), "/")
+}
+
+// Transform is the diskv transform function for the layout. Only the keys found in the store
+// and the ones placed are remembered, so looking up keys that aren't stored (or not yet) doesn't
+// grow the layout, nor keep Place from putting them where they belong.
+func (l *Layout) Transform(key string) []string {
+	if l.kind == "flat" {
+		return []string{}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if path, found := l.paths[key]; found {
+		return path
+	}
+	// attachments (<slug>.png and the like) go with their document
+	if dot := strings.Index(key, "."); dot > 0 {
+		if path, found := l.paths[key[:dot]]; found {
+			return path
+		}
+	}
+
+	path := l.defaultPath(key)
+	if _, err := os.Stat(filepath.Join(append(append([]string{l.basePath}, path...), key)...)); err == nil {
+		l.paths[key] = path
+	}
+	return path
+}
+
+// defaultPath is where key goes unless it was placed elsewhere
+func (l *Layout) defaultPath(key string) []string {
+	switch l.kind {
+	case "date":
+		return []string{time.Now().Format("2006-01-02")}
+	case "partitioned":
+		path := strings.Split(time.Now().Format("2006/01/02"), "/")
+		if partition := strings.Join(path, "/"); partition != l.partition {
+			if l.partition != "" && l.rotated != nil {
+				l.rotated(partition)
+			}
+			l.partition = partition
+		}
+		return path
+	case "hash":
+		hash := sha1.Sum([]byte(key))
+		digest := hex.EncodeToString(hash[:2])
+		return []string{digest[:2], digest[2:]}
+	}
+	// domain layout, for keys that weren't placed with a domain
+	return []string{}
+}
+
+// OnRotation calls rotated with the new partition whenever a partitioned store starts one
+func (l *Layout) OnRotation(rotated func(partition string)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rotated = rotated
+}
+
+// Place decides where key goes, in the directory of its destination's domain when laying out by
+// domain, and remembers it so key is found there later (a date layout's key after midnight
+// too); it has to be called before key is first written and leaves keys already stored alone
+func (l *Layout) Place(key string, domain string) {
+	if l == nil || l.kind == "flat" {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, found := l.paths[key]; found {
+		return
+	}
+	if l.kind != "domain" {
+		l.paths[key] = l.defaultPath(key)
+		return
+	}
+	if domain == "" {
+		domain = "_"
+	}
+	l.paths[key] = []string{domain}
+}
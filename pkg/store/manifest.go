@@ -0,0 +1,119 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManifestFile is the store's manifest, in the storage directory
+const ManifestFile = "index.json"
+
+// ManifestEntry is what the manifest says about each stored resource
+type ManifestEntry struct {
+	Slug        string `json:"slug"`
+	URL         string `json:"url"`
+	Domain      string `json:"domain"`
+	HarvestedAt string `json:"harvestedAt"`
+	Query       string `json:"query,omitempty"`
+}
+
+// Manifest lists every stored slug with its URL, destination domain, when it was harvested
+// and the query that found it, most recent first, so static site builds and audits can go
+// through the store without reading every document in it
+type Manifest struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]*ManifestEntry
+	savedAt time.Time
+	dirty   bool
+}
+
+// NewManifest loads the manifest at path, if there's one; found is false if there isn't
+func NewManifest(path string) (manifest *Manifest, found bool, err error) {
+	result := new(Manifest)
+	result.path = path
+	result.entries = make(map[string]*ManifestEntry)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entries []*ManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, false, err
+	}
+	for _, entry := range entries {
+		result.entries[entry.Slug] = entry
+	}
+	result.savedAt = time.Now()
+	return result, true, nil
+}
+
+// Add lists entry, replacing what was listed for its slug
+func (m *Manifest) Add(entry *ManifestEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries[entry.Slug] = entry
+	m.dirty = true
+}
+
+// Remove takes slug off the manifest
+func (m *Manifest) Remove(slug string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, found := m.entries[slug]; found {
+		delete(m.entries, slug)
+		m.dirty = true
+	}
+}
+
+// Save writes the manifest out if anything changed since it was last saved
+func (m *Manifest) Save() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.dirty {
+		return nil
+	}
+	entries := make([]*ManifestEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+	// RFC 3339 times in the same zone sort as strings
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].HarvestedAt != entries[j].HarvestedAt {
+			return entries[i].HarvestedAt > entries[j].HarvestedAt
+		}
+		return entries[i].Slug < entries[j].Slug
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return err
+	}
+	m.dirty = false
+	m.savedAt = time.Now()
+	return nil
+}
+
+// SavedAt is when the manifest was last saved (or loaded)
+func (m *Manifest) SavedAt() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.savedAt
+}
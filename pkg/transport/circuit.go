@@ -0,0 +1,120 @@
+// Package transport has the http.RoundTrippers the harvester fetches destinations through:
+// per-host politeness, circuit breaking and a persistent cache of resolved redirects.
+package transport
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// circuitFailureHalfLife is how quickly a host's failures are forgiven: a failure counts half
+// as much after this long
+const circuitFailureHalfLife = time.Minute
+
+// CircuitOpenError is returned for requests to a host that has been failing too much
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "circuit open for " + e.Host + " until " + e.Until.Format(time.RFC3339)
+}
+
+// CircuitBreaker stops sending requests to hosts that keep failing (network errors,
+// timeouts and 5xx responses) for a while, so an outage of a popular domain doesn't stall
+// harvesting; after cooldown one request is let through to see if the host is back
+type CircuitBreaker struct {
+	// OnOpen, if set, is called whenever a host's circuit opens
+	OnOpen    func(host string)
+	next      http.RoundTripper
+	threshold float64
+	cooldown  time.Duration
+	exempt    []string
+	mutex     sync.Mutex
+	hosts     map[string]*hostCircuit
+}
+
+type hostCircuit struct {
+	failures  float64
+	decayedAt time.Time
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker opens a host's circuit once its failures, decaying over time,
+// reach threshold; exempt hosts (and their subdomains) are never cut off
+func NewCircuitBreaker(next http.RoundTripper, threshold int, cooldown time.Duration, exempt []string) *CircuitBreaker {
+	result := new(CircuitBreaker)
+	result.next = next
+	result.threshold = float64(threshold)
+	result.cooldown = cooldown
+	result.exempt = exempt
+	result.hosts = make(map[string]*hostCircuit)
+	return result
+}
+
+// RoundTrip implements http.RoundTripper, failing right away with a CircuitOpenError while
+// the host's circuit is open
+func (t *CircuitBreaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	for _, exempt := range t.exempt {
+		if host == exempt || strings.HasSuffix(host, "."+exempt) {
+			return t.next.RoundTrip(req)
+		}
+	}
+	if err := t.allow(host); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	t.record(host, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
+
+func (t *CircuitBreaker) allow(host string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	circuit, found := t.hosts[host]
+	if !found || circuit.openUntil.IsZero() {
+		return nil
+	}
+	if time.Now().Before(circuit.openUntil) || circuit.probing {
+		return &CircuitOpenError{Host: host, Until: circuit.openUntil}
+	}
+	// half open: this request finds out whether the host is back
+	circuit.probing = true
+	return nil
+}
+
+func (t *CircuitBreaker) record(host string, failed bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	circuit, found := t.hosts[host]
+	if !failed {
+		if found {
+			delete(t.hosts, host)
+		}
+		return
+	}
+	if !found {
+		circuit = &hostCircuit{decayedAt: time.Now()}
+		t.hosts[host] = circuit
+	}
+
+	now := time.Now()
+	circuit.failures *= math.Pow(0.5, now.Sub(circuit.decayedAt).Seconds()/circuitFailureHalfLife.Seconds())
+	circuit.decayedAt = now
+	circuit.failures++
+	if circuit.probing || circuit.failures >= t.threshold {
+		if circuit.openUntil.IsZero() || circuit.probing {
+			if t.OnOpen != nil {
+				t.OnOpen(host)
+			}
+		}
+		circuit.openUntil = now.Add(t.cooldown)
+		circuit.probing = false
+	}
+}
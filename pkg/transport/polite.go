@@ -0,0 +1,176 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// politeSweepInterval is how often the limiters of the hosts that are done with are dropped
+const politeSweepInterval = time.Minute
+
+// Polite keeps the harvester from hammering a host when one of its links trends: each
+// host gets a token bucket refilled at qps requests a second (up to burst) and at most
+// concurrency requests in flight
+type Polite struct {
+	next        http.RoundTripper
+	qps         float64
+	burst       int
+	concurrency int
+	exempt      []string
+	mutex       sync.Mutex
+	hosts       map[string]*hostLimiter
+	sweptAt     time.Time
+}
+
+type hostLimiter struct {
+	// users is how many requests have the limiter, with Polite.mutex held
+	users    int
+	mutex    sync.Mutex
+	tokens   float64
+	refilled time.Time
+	slots    chan struct{}
+}
+
+// NewPolite limits the requests next makes to each host, apart from the exempt hosts
+// (and their subdomains); a qps or concurrency of 0 leaves that unlimited
+func NewPolite(next http.RoundTripper, qps float64, burst int, concurrency int, exempt []string) *Polite {
+	result := new(Polite)
+	result.next = next
+	result.qps = qps
+	result.burst = burst
+	if result.burst < 1 {
+		result.burst = 1
+	}
+	result.concurrency = concurrency
+	result.exempt = exempt
+	result.hosts = make(map[string]*hostLimiter)
+	result.sweptAt = time.Now()
+	return result
+}
+
+// RoundTrip implements http.RoundTripper, waiting for the host's turn first
+func (t *Polite) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	for _, exempt := range t.exempt {
+		if host == exempt || strings.HasSuffix(host, "."+exempt) {
+			return t.next.RoundTrip(req)
+		}
+	}
+
+	limiter := t.limiter(host)
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- struct{}{}:
+		case <-req.Context().Done():
+			t.done(limiter, false)
+			return nil, req.Context().Err()
+		}
+	}
+	if err := limiter.wait(req, t.qps, t.burst); err != nil {
+		t.done(limiter, true)
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.done(limiter, true)
+		return nil, err
+	}
+	// the request is in flight until its body has been read
+	resp.Body = &politeBody{ReadCloser: resp.Body, polite: t, limiter: limiter}
+	return resp, nil
+}
+
+func (t *Polite) limiter(host string) *hostLimiter {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if time.Since(t.sweptAt) > politeSweepInterval {
+		t.sweep()
+	}
+	limiter, found := t.hosts[host]
+	if !found {
+		limiter = &hostLimiter{tokens: float64(t.burst), refilled: time.Now()}
+		if t.concurrency > 0 {
+			limiter.slots = make(chan struct{}, t.concurrency)
+		}
+		t.hosts[host] = limiter
+	}
+	limiter.users++
+	return limiter
+}
+
+// done gives back the slot the request took, if it got one, and the limiter
+func (t *Polite) done(limiter *hostLimiter, tookSlot bool) {
+	if tookSlot && limiter.slots != nil {
+		<-limiter.slots
+	}
+	t.mutex.Lock()
+	limiter.users--
+	t.mutex.Unlock()
+}
+
+// sweep drops the limiters no request has and whose bucket has filled up again, as they're no
+// different from new ones, so the hosts linked to once don't stay around; with t.mutex held
+func (t *Polite) sweep() {
+	now := time.Now()
+	for host, limiter := range t.hosts {
+		if limiter.users == 0 && limiter.full(now, t.qps, t.burst) {
+			delete(t.hosts, host)
+		}
+	}
+	t.sweptAt = now
+}
+
+// wait takes a token, sleeping until there's one
+func (l *hostLimiter) wait(req *http.Request, qps float64, burst int) error {
+	if qps <= 0 {
+		return nil
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.refilled).Seconds() * qps
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+	l.refilled = now
+	// taking the token now, even if it's still to come, queues the requests up in order
+	l.tokens--
+	delay := time.Duration(-l.tokens / qps * float64(time.Second))
+	l.mutex.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func (l *hostLimiter) full(now time.Time, qps float64, burst int) bool {
+	if qps <= 0 {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.tokens+now.Sub(l.refilled).Seconds()*qps >= float64(burst)
+}
+
+type politeBody struct {
+	io.ReadCloser
+	polite  *Polite
+	limiter *hostLimiter
+	once    sync.Once
+}
+
+func (b *politeBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.polite.done(b.limiter, true) })
+	return err
+}
@@ -0,0 +1,186 @@
+package transport
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RedirectCacheFile is where the redirect cache is kept between runs, in the storage directory
+const RedirectCacheFile = ".resolved-urls.json"
+
+// redirectCacheTTL is how long a cached redirect is trusted; shortened links don't change but
+// other redirects do, eventually
+const redirectCacheTTL = 7 * 24 * time.Hour
+
+// CachedRedirect is a redirect response as the redirect cache remembers it
+type CachedRedirect struct {
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status"`
+	Location   string    `json:"location"`
+	CachedAt   time.Time `json:"cachedAt"`
+}
+
+// RedirectCache answers the requests for URLs that already redirected somewhere for good, so
+// the same t.co or bit.ly link in hundreds of retweets is only resolved once. Only permanent
+// redirects (301 and 308) are cached, temporary ones may well go elsewhere next time.
+// content-harvester-utils resolves each URL itself, so the cache sits in the transport it uses;
+// the least recently used redirects go once it's full and it's mirrored to a JSON file.
+type RedirectCache struct {
+	next     http.RoundTripper
+	path     string
+	capacity int
+	mutex    sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List
+	dirty    bool
+}
+
+// NewRedirectCache loads (or starts) the cache persisted at path, keeping at most capacity redirects
+func NewRedirectCache(next http.RoundTripper, path string, capacity int) (*RedirectCache, error) {
+	result := new(RedirectCache)
+	result.next = next
+	result.path = path
+	result.capacity = capacity
+	result.entries = make(map[string]*list.Element)
+	result.order = list.New()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var persisted []*CachedRedirect
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return nil, err
+	}
+	// persisted most recently used first; earlier versions cached temporary redirects too
+	for i := len(persisted) - 1; i >= 0; i-- {
+		if time.Since(persisted[i].CachedAt) < redirectCacheTTL && permanentRedirect(persisted[i].StatusCode) {
+			result.add(persisted[i])
+		}
+	}
+	return result, nil
+}
+
+// RoundTrip implements http.RoundTripper, answering GET and HEAD requests for cached redirects
+// without going out
+func (c *RedirectCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return c.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+	if cached := c.lookup(key); cached != nil {
+		header := make(http.Header)
+		header.Set("Location", cached.Location)
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+			StatusCode: cached.StatusCode,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if location := resp.Header.Get("Location"); location != "" && permanentRedirect(resp.StatusCode) {
+		c.mutex.Lock()
+		c.add(&CachedRedirect{URL: key, StatusCode: resp.StatusCode, Location: location, CachedAt: time.Now()})
+		c.dirty = true
+		c.mutex.Unlock()
+	}
+	return resp, nil
+}
+
+func permanentRedirect(statusCode int) bool {
+	return statusCode == http.StatusMovedPermanently || statusCode == http.StatusPermanentRedirect
+}
+
+func (c *RedirectCache) lookup(key string) *CachedRedirect {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, found := c.entries[key]
+	if !found {
+		return nil
+	}
+	cached := element.Value.(*CachedRedirect)
+	if time.Since(cached.CachedAt) > redirectCacheTTL {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		c.dirty = true
+		return nil
+	}
+	c.order.MoveToFront(element)
+	return cached
+}
+
+// add caches redirect as the most recently used one, with c.mutex held
+func (c *RedirectCache) add(redirect *CachedRedirect) {
+	if element, found := c.entries[redirect.URL]; found {
+		element.Value = redirect
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[redirect.URL] = c.order.PushFront(redirect)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*CachedRedirect).URL)
+	}
+}
+
+// Save writes the cache to disk if anything changed since the last save
+func (c *RedirectCache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	persisted := make([]*CachedRedirect, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		persisted = append(persisted, element.Value.(*CachedRedirect))
+	}
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// SaveEvery saves the cache every interval, until the process is stopped
+func (c *RedirectCache) SaveEvery(interval time.Duration, logger *zap.Logger) {
+	for range time.Tick(interval) {
+		if err := c.Save(); err != nil {
+			logger.Error("Unable to save redirect cache", zap.String("path", c.path), zap.Error(err))
+		}
+	}
+}
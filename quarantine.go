@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/peterbourgon/diskv"
+	"go.uber.org/zap"
+)
+
+// QuarantineStore keeps the resources that weren't stored in a directory of their own, one
+// document per outcome and URL, so ignore and clean rules can be audited from what they
+// caught. Each document's front matter has the outcome (invalid, ignored, duplicate or
+// filtered) as its code, the reason and how many times the URL was turned away; its body
+// is the latest text the URL was found in.
+type QuarantineStore struct {
+	diskv  *diskv.Diskv
+	logger *zap.Logger
+	mutex  sync.Mutex
+}
+
+// NewQuarantineStore keeps the quarantined resources in basePath
+func NewQuarantineStore(logger *zap.Logger, basePath string) *QuarantineStore {
+	result := new(QuarantineStore)
+	result.logger = logger
+	result.diskv = diskv.New(diskv.Options{
+		BasePath:     basePath,
+		Transform:    func(s string) []string { return []string{} },
+		CacheSizeMax: 1024 * 1024,
+	})
+	return result
+}
+
+// ResourceIgnored implements IgnoreObserver
+func (q *QuarantineStore) ResourceIgnored(resource *IgnoredResource) {
+	hash := sha1.Sum([]byte(resource.OriginalURL + " " + resource.FinalURL))
+	key := resource.Outcome + "-" + hex.EncodeToString(hash[:8])
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	hits := 1
+	firstIgnoredAt := resource.IgnoredAt.Format(time.RFC3339)
+	if data, err := q.diskv.Read(key); err == nil {
+		if previous, _, err := readFrontMatter(string(data)); err == nil {
+			if previousHits, ok := previous["hits"].(int); ok {
+				hits += previousHits
+			}
+			if first, ok := previous["firstIgnoredAt"].(string); ok {
+				firstIgnoredAt = first
+			}
+		}
+	}
+
+	fields := map[string]interface{}{
+		"code":           resource.Outcome,
+		"reason":         resource.Reason,
+		"originalURL":    resource.OriginalURL,
+		"hits":           hits,
+		"firstIgnoredAt": firstIgnoredAt,
+		"lastIgnoredAt":  resource.IgnoredAt.Format(time.RFC3339),
+	}
+	if resource.FinalURL != "" {
+		fields["finalURL"] = resource.FinalURL
+	}
+	resource.Provenance.addFrontMatter(fields)
+	document, err := addFrontMatter(resource.Text+"\n", fields)
+	if err == nil {
+		err = q.diskv.Write(key, []byte(document))
+	}
+	if err != nil {
+		q.logger.Error("Unable to quarantine resource", zap.String("originalURLText", resource.OriginalURL), zap.Error(err))
+	}
+}
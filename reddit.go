@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// redditUserAgent identifies the harvester to Reddit, which throttles generic user agents
+const redditUserAgent = "golang:github.com/shah/content-harvester-twitter:v1"
+
+type redditSubmission struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	IsSelf      bool   `json:"is_self"`
+	Selftext    string `json:"selftext"`
+	Permalink   string `json:"permalink"`
+	Author      string `json:"author"`
+	Subreddit   string `json:"subreddit"`
+	Score       int    `json:"score"`
+	NumComments int    `json:"num_comments"`
+}
+
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data redditSubmission `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// RedditSubreddits is a Source of the submissions to subreddits, polled every interval from
+// their new or hot listings with an app's OAuth client credentials. Reddit's rate limit
+// headers are honored: once a window's requests are used up polling waits for the next one.
+type RedditSubreddits struct {
+	clientID     string
+	clientSecret string
+	subreddits   []string
+	sort         string
+	interval     time.Duration
+	logger       *zap.Logger
+	client       *http.Client
+	mutex        sync.Mutex
+	token        string
+	tokenExpiry  time.Time
+	remaining    float64
+	reset        time.Time
+	seen         map[string]map[string]bool
+}
+
+// NewRedditSubreddits prepares polling the sort (new or hot) listing of each of subreddits
+func NewRedditSubreddits(clientID string, clientSecret string, subreddits []string, sort string, interval time.Duration, timeout time.Duration, logger *zap.Logger) *RedditSubreddits {
+	result := new(RedditSubreddits)
+	result.clientID = clientID
+	result.clientSecret = clientSecret
+	for _, subreddit := range subreddits {
+		result.subreddits = append(result.subreddits, strings.TrimPrefix(strings.TrimPrefix(subreddit, "/"), "r/"))
+	}
+	result.sort = sort
+	result.interval = interval
+	result.logger = logger
+	result.client = &http.Client{Timeout: timeout}
+	result.remaining = 1
+	result.seen = make(map[string]map[string]bool)
+	return result
+}
+
+// Start implements Source, polling the subreddits until ctx is done
+func (r *RedditSubreddits) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			for _, subreddit := range r.subreddits {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := r.poll(ctx, subreddit, items); err != nil && ctx.Err() == nil {
+					r.logger.Error("Unable to poll subreddit", zap.String("subreddit", subreddit), zap.Error(err))
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return items
+}
+
+// poll sends the submissions to subreddit that weren't in its listing the last time on
+func (r *RedditSubreddits) poll(ctx context.Context, subreddit string, items chan<- Item) error {
+	var listing redditListing
+	if err := r.get(ctx, "/r/"+subreddit+"/"+r.sort+"?limit=100&raw_json=1", &listing); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, child := range listing.Data.Children {
+		submission := child.Data
+		seen[submission.Name] = true
+		if r.seen[subreddit][submission.Name] {
+			continue
+		}
+		select {
+		case items <- redditItem(&submission):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	r.seen[subreddit] = seen
+	return nil
+}
+
+func redditItem(submission *redditSubmission) Item {
+	text := submission.Title
+	if !submission.IsSelf && submission.URL != "" {
+		text += " " + submission.URL
+	}
+	if submission.Selftext != "" {
+		text += "\n\n" + submission.Selftext
+	}
+	provenance := &Provenance{Source: "reddit", Author: "u/" + submission.Author, Query: "r/" + submission.Subreddit,
+		PostURL: "https://www.reddit.com" + submission.Permalink, Score: submission.Score, Comments: submission.NumComments}
+	return Item{Text: text, Provenance: provenance}
+}
+
+// get calls the Reddit API, waiting for the rate limit window to reset first if it's used up
+func (r *RedditSubreddits) get(ctx context.Context, path string, result interface{}) error {
+	if err := r.waitForRateLimit(ctx); err != nil {
+		return err
+	}
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://oauth.reddit.com"+path, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", redditUserAgent)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	r.recordRateLimit(resp.Header)
+	if resp.StatusCode == http.StatusUnauthorized {
+		// the token was revoked or expired early, the next poll gets a new one
+		r.mutex.Lock()
+		r.token = ""
+		r.mutex.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (r *RedditSubreddits) recordRateLimit(header http.Header) {
+	remaining, remainingErr := strconv.ParseFloat(header.Get("X-Ratelimit-Remaining"), 64)
+	reset, resetErr := strconv.Atoi(header.Get("X-Ratelimit-Reset"))
+	if remainingErr != nil || resetErr != nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.remaining = remaining
+	r.reset = time.Now().Add(time.Duration(reset) * time.Second)
+}
+
+func (r *RedditSubreddits) waitForRateLimit(ctx context.Context) error {
+	r.mutex.Lock()
+	wait := time.Duration(0)
+	if r.remaining < 1 {
+		wait = time.Until(r.reset)
+	}
+	r.mutex.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	r.logger.Info("Waiting for Reddit rate limit to reset", zap.Duration("wait", wait))
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// accessToken returns the app's OAuth token, getting a new one with the client credentials
+// when it's about to expire
+func (r *RedditSubreddits) accessToken(ctx context.Context) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.token != "" && time.Until(r.tokenExpiry) > time.Minute {
+		return r.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequest(http.MethodPost, "https://www.reddit.com/api/v1/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(r.clientID, r.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", redditUserAgent)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reddit access token: HTTP status %d", resp.StatusCode)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("reddit access token: none given, check reddit-client-id and reddit-client-secret")
+	}
+	r.token = token.AccessToken
+	r.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return r.token, nil
+}
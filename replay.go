@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"go.uber.org/zap"
+)
+
+// RawTweetLog retains the JSON of every tweet received, before any filter has seen it, in a
+// JSONL file a day (YYYY-MM-DD.jsonl) so the tweets can be replayed through a changed pipeline
+type RawTweetLog struct {
+	dir    string
+	mutex  sync.Mutex
+	day    string
+	file   *os.File
+	logger *zap.Logger
+}
+
+// NewRawTweetLog retains tweets in dir
+func NewRawTweetLog(dir string, logger *zap.Logger) (*RawTweetLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	result := new(RawTweetLog)
+	result.dir = dir
+	result.logger = logger
+	return result, nil
+}
+
+// Retain appends tweet to the day's file
+func (l *RawTweetLog) Retain(tweet *anaconda.Tweet) {
+	data, err := json.Marshal(tweet)
+	if err != nil {
+		l.logger.Error("Unable to retain raw tweet", zap.String("tweetID", tweet.IdStr), zap.Error(err))
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if day := time.Now().UTC().Format("2006-01-02"); day != l.day {
+		if l.file != nil {
+			l.file.Close()
+		}
+		l.file, err = os.OpenFile(filepath.Join(l.dir, day+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			l.day, l.file = "", nil
+			l.logger.Error("Unable to retain raw tweet", zap.String("tweetID", tweet.IdStr), zap.Error(err))
+			return
+		}
+		l.day = day
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		l.logger.Error("Unable to retain raw tweet", zap.String("tweetID", tweet.IdStr), zap.Error(err))
+	}
+}
+
+// Close closes the day's file
+func (l *RawTweetLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// RetainRawTweets makes the harvester retain every tweet it's given in log
+func (h *TweetHarvester) RetainRawTweets(log *RawTweetLog) {
+	h.rawTweets = log
+}
+
+// RawTweetReplay is a Source of the tweets a RawTweetLog retained, oldest day first
+type RawTweetReplay struct {
+	dir    string
+	logger *zap.Logger
+}
+
+// NewRawTweetReplay replays the tweets retained in dir
+func NewRawTweetReplay(dir string, logger *zap.Logger) *RawTweetReplay {
+	result := new(RawTweetReplay)
+	result.dir = dir
+	result.logger = logger
+	return result
+}
+
+// Start implements Source, sending each retained tweet on
+func (r *RawTweetReplay) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		days, err := filepath.Glob(filepath.Join(r.dir, "*.jsonl"))
+		if err != nil || len(days) == 0 {
+			r.logger.Error("No raw tweets to replay", zap.String("dir", r.dir), zap.Error(err))
+			return
+		}
+		sort.Strings(days)
+		for _, day := range days {
+			r.logger.Info("Replaying raw tweets", zap.String("file", day))
+			for item := range NewInputLines(day, r.logger).Start(ctx) {
+				if item.Tweet == nil {
+					continue
+				}
+				select {
+				case items <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return items
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/transport"
+)
+
+// resolveRetryBackoff is how long the first retry of a failed resolution request waits; each
+// further retry waits twice as long
+const resolveRetryBackoff = 500 * time.Millisecond
+
+// configureResolution sets how content-harvester-utils resolves URLs, through net/http's
+// default client: how long a request can take, how many redirects it follows, how many
+// times it's retried after a network error or a 429, 502, 503 or 504 and the transport the
+// requests go through
+func configureResolution(timeout time.Duration, maxRedirects int, retries int, next http.RoundTripper) {
+	http.DefaultClient.Timeout = timeout
+	http.DefaultClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+	http.DefaultClient.Transport = next
+	if retries > 0 {
+		http.DefaultClient.Transport = &retryingTransport{next: next, retries: retries}
+	}
+}
+
+// retryingTransport retries the GET and HEAD requests that fail in a way that might not
+// last, backing off between tries
+type retryingTransport struct {
+	next    http.RoundTripper
+	retries int
+}
+
+func (t *retryingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || req.Body != nil {
+		return t.next.RoundTrip(req)
+	}
+
+	backoff := resolveRetryBackoff
+	for try := 0; ; try++ {
+		resp, err := t.next.RoundTrip(req)
+		if try == t.retries || !retryable(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if _, circuitOpen := err.(*transport.CircuitOpenError); circuitOpen {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
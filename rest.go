@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// ignoreLogSize is how many of the latest ignore decisions the REST API shows
+const ignoreLogSize = 1000
+
+// maxHarvestRequestSize is the most text the REST API takes in one harvest request
+const maxHarvestRequestSize = 1024 * 1024
+
+// IgnoreLog remembers the latest ignore decisions, for the REST API
+type IgnoreLog struct {
+	mutex   sync.Mutex
+	ignored []*IgnoredResource
+	next    int
+}
+
+// ResourceIgnored implements IgnoreObserver
+func (l *IgnoreLog) ResourceIgnored(resource *IgnoredResource) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.ignored) < ignoreLogSize {
+		l.ignored = append(l.ignored, resource)
+		return
+	}
+	l.ignored[l.next] = resource
+	l.next = (l.next + 1) % ignoreLogSize
+}
+
+// Latest returns the remembered ignore decisions, most recent first
+func (l *IgnoreLog) Latest() []*IgnoredResource {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	result := make([]*IgnoredResource, 0, len(l.ignored))
+	for i := len(l.ignored) - 1; i >= 0; i-- {
+		result = append(result, l.ignored[(l.next+i)%len(l.ignored)])
+	}
+	return result
+}
+
+// storedDocumentJSON is how the REST API shows a stored document
+type storedDocumentJSON struct {
+	Slug        string      `json:"slug"`
+	URL         string      `json:"url"`
+	Title       string      `json:"title"`
+	HarvestedAt time.Time   `json:"harvestedAt"`
+	FrontMatter interface{} `json:"frontMatter"`
+	Body        string      `json:"body,omitempty"`
+}
+
+func newStoredDocumentJSON(document *StoredDocument, withBody bool) *storedDocumentJSON {
+	result := &storedDocumentJSON{
+		Slug:        document.Key,
+		URL:         document.URL(),
+		Title:       document.Title(),
+		HarvestedAt: document.HarvestedAt,
+		FrontMatter: jsonCompatible(document.FrontMatter),
+	}
+	if withBody {
+		result.Body = document.Body
+	}
+	return result
+}
+
+// jsonCompatible turns the map[interface{}]interface{} values YAML decodes nested mappings
+// into map[string]interface{} so they can be encoded as JSON
+func jsonCompatible(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[key] = jsonCompatible(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = jsonCompatible(item)
+		}
+		return result
+	}
+	return value
+}
+
+func (s *HarvesterServer) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		s.logger.Error("Unable to write response", zap.Error(err))
+	}
+}
+
+func (s *HarvesterServer) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// routeREST adds the REST API:
+//
+//	GET  /api/resources?text=&domain=&user=&hashtag=&since=RFC3339&limit=  stored resources, most recent first
+//	GET  /api/resources/{slug}                                              one stored resource with its body
+//	GET  /api/ignored                                                       the latest ignore decisions
+//	POST /api/harvest                                                       harvest the resources in the text body
+//
+// Harvesting fetches what the text links to and writes it to the store, so it's only served
+// with an admin token to require.
+func (s *HarvesterServer) routeREST() {
+	s.ignoreLog = new(IgnoreLog)
+	s.storage.ObserveIgnoredWith(s.ignoreLog)
+
+	api := s.router.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/resources", s.listResources).Methods(http.MethodGet)
+	api.HandleFunc("/resources/{slug}", s.getResource).Methods(http.MethodGet)
+	api.HandleFunc("/ignored", s.listIgnored).Methods(http.MethodGet)
+	if s.adminToken != "" {
+		api.Handle("/harvest", s.requireAdmin(s.harvestText)).Methods(http.MethodPost)
+	}
+}
+
+func (s *HarvesterServer) listResources(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &StoredDocumentFilter{
+		Text:    query.Get("text"),
+		Domain:  query.Get("domain"),
+		User:    query.Get("user"),
+		Hashtag: query.Get("hashtag"),
+		Limit:   100,
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("limit: %v", err))
+			return
+		}
+	}
+	if since := query.Get("since"); since != "" {
+		var err error
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("since: %v", err))
+			return
+		}
+	}
+
+	result := []*storedDocumentJSON{}
+	for _, document := range s.storage.FindStoredDocuments(filter) {
+		result = append(result, newStoredDocumentJSON(document, false))
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *HarvesterServer) getResource(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	if !isDocumentKey(slug) || !s.storage.diskv.Has(slug) {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("no resource %q", slug))
+		return
+	}
+	document, err := s.storage.StoredDocument(slug)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, newStoredDocumentJSON(document, true))
+}
+
+func (s *HarvesterServer) listIgnored(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.ignoreLog.Latest())
+}
+
+// harvestText harvests the resources in a text/plain body, or the "text" of a JSON one, and
+// responds with the slugs they were stored under
+func (s *HarvesterServer) harvestText(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHarvestRequestSize))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	text := string(body)
+	if r.Header.Get("Content-Type") == "application/json" {
+		var request struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		text = request.Text
+	}
+
+	slugs := s.storage.SaveAllInText(r.Context(), text, nil)
+	if slugs == nil {
+		slugs = []string{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string][]string{"stored": slugs})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/store"
+	"go.uber.org/zap"
+)
+
+// RetentionPolicy says what happens to stored resources once they're older than MaxAge:
+// they're deleted, or moved to ArchiveDir if there is one. With Compress, the expired
+// resources of each day go into a single expired-YYYY-MM-DD.tar.gz, in ArchiveDir or
+// otherwise in the storage directory. Archived files are copied as they're stored, still
+// compressed or encrypted if the store is.
+type RetentionPolicy struct {
+	MaxAge     time.Duration
+	ArchiveDir string
+	Compress   bool
+}
+
+// PruneResult counts what Prune did
+type PruneResult struct {
+	Documents int
+	Files     int
+}
+
+// Prune applies policy to everything in the store harvested before now minus its MaxAge.
+// Documents are dated by their harvestedAt field (or their file's modification time) and
+// their attachments go with them.
+func (storage *HarvestedResourceStorage) Prune(policy RetentionPolicy, now time.Time) (*PruneResult, error) {
+	// the export formats' files would be taken for another resource's attachments
+	if err := storage.inStoreLayout("retention"); err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-policy.MaxAge)
+	var keys []string
+	for key := range storage.diskv.Keys(nil) {
+		// the archives of earlier prunes land in the store too, when there's no ArchiveDir
+		if !strings.HasPrefix(key, ".") && key != store.ManifestFile && key != harvestStateFile && !isExpiredArchive(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	// date the documents first, so their attachments can follow them
+	harvestedAt := make(map[string]time.Time)
+	for _, key := range keys {
+		if !isDocumentKey(key) {
+			continue
+		}
+		data, err := storage.diskv.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		if document, err := storage.parseStoredDocument(key, string(data)); err == nil {
+			harvestedAt[key] = document.HarvestedAt
+		} else if info, err := os.Stat(storage.keyPath(key)); err == nil {
+			harvestedAt[key] = info.ModTime()
+		}
+	}
+
+	result := new(PruneResult)
+	expired := make(map[string][]string)
+	for _, key := range keys {
+		owner := key
+		if dot := strings.Index(key, "."); dot > 0 {
+			owner = key[:dot]
+		}
+		date, found := harvestedAt[owner]
+		if !found {
+			info, err := os.Stat(storage.keyPath(key))
+			if err != nil {
+				continue
+			}
+			date = info.ModTime()
+		}
+		if !date.Before(cutoff) {
+			continue
+		}
+		day := date.Format("2006-01-02")
+		expired[day] = append(expired[day], key)
+		if key == owner {
+			result.Documents++
+		}
+	}
+
+	for day, dayKeys := range expired {
+		var err error
+		switch {
+		case policy.Compress:
+			err = storage.archiveExpired(policy.ArchiveDir, day, dayKeys)
+		case policy.ArchiveDir != "":
+			err = storage.moveExpired(policy.ArchiveDir, dayKeys)
+		}
+		if err != nil {
+			return result, err
+		}
+		for _, key := range dayKeys {
+			if err := storage.diskv.Erase(key); err != nil && !os.IsNotExist(err) {
+				return result, err
+			}
+			if storage.manifest != nil {
+				storage.manifest.Remove(key)
+			}
+			result.Files++
+		}
+	}
+	return result, nil
+}
+
+// relativeKeyPath is where key is stored relative to the storage directory
+func (storage *HarvestedResourceStorage) relativeKeyPath(key string) string {
+	if path, err := filepath.Rel(storage.basePath, storage.keyPath(key)); err == nil {
+		return path
+	}
+	return key
+}
+
+func (storage *HarvestedResourceStorage) moveExpired(archiveDir string, keys []string) error {
+	for _, key := range keys {
+		data, err := ioutil.ReadFile(storage.keyPath(key))
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(archiveDir, storage.relativeKeyPath(key))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isExpiredArchive tells whether key is one of the expired-YYYY-MM-DD.tar.gz archives
+func isExpiredArchive(key string) bool {
+	return strings.HasPrefix(key, "expired-") && strings.HasSuffix(key, ".tar.gz")
+}
+
+func (storage *HarvestedResourceStorage) archiveExpired(archiveDir string, day string, keys []string) error {
+	if archiveDir == "" {
+		archiveDir = storage.basePath
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return err
+	}
+	// an earlier prune may have archived resources of the same day already
+	path := filepath.Join(archiveDir, "expired-"+day+".tar.gz")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(archiveDir, fmt.Sprintf("expired-%s-%d.tar.gz", day, i))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(file)
+	archive := tar.NewWriter(compressed)
+	err = storage.writeTar(archive, keys)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := compressed.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func (storage *HarvestedResourceStorage) writeTar(archive *tar.Writer, keys []string) error {
+	for _, key := range keys {
+		data, err := ioutil.ReadFile(storage.keyPath(key))
+		if err != nil {
+			return err
+		}
+		modTime := time.Now()
+		if info, err := os.Stat(storage.keyPath(key)); err == nil {
+			modTime = info.ModTime()
+		}
+		header := &tar.Header{
+			Name:    filepath.ToSlash(storage.relativeKeyPath(key)),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: modTime,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneEvery applies policy every interval, until the process is stopped
+func (storage *HarvestedResourceStorage) PruneEvery(interval time.Duration, policy RetentionPolicy) {
+	for range time.Tick(interval) {
+		result, err := storage.Prune(policy, time.Now())
+		if err != nil {
+			storage.logger.Error("Unable to prune storage", zap.String("path", storage.basePath), zap.Error(err))
+		}
+		if result != nil && result.Files > 0 {
+			storage.logger.Info("Pruned storage", zap.Int("documents", result.Documents), zap.Int("files", result.Files))
+		}
+	}
+}
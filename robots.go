@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// robotsTxtTTL is how long a host's robots.txt is trusted before it's fetched again
+const robotsTxtTTL = 24 * time.Hour
+
+// maxRobotsTxtSize is as much of a robots.txt as is read, Google stops at 500 KiB too
+const maxRobotsTxtSize = 500 * 1024
+
+// defaultRobotsAgent is who the harvester says it is to robots.txt rules
+const defaultRobotsAgent = "content-harvester-twitter"
+
+// RobotsTxtChecker tells whether destinations may be fetched according to their host's
+// robots.txt, which is cached for robotsTxtTTL
+type RobotsTxtChecker struct {
+	client *http.Client
+	agent  string
+	mutex  sync.Mutex
+	hosts  map[string]*cachedRobotsTxt
+}
+
+type cachedRobotsTxt struct {
+	once      sync.Once
+	robots    *robotstxt.RobotsData
+	fetchedAt time.Time
+}
+
+// NewRobotsTxtChecker checks the rules for agent, fetching robots.txt files with client
+func NewRobotsTxtChecker(client *http.Client, agent string) *RobotsTxtChecker {
+	result := new(RobotsTxtChecker)
+	result.client = client
+	result.agent = agent
+	result.hosts = make(map[string]*cachedRobotsTxt)
+	return result
+}
+
+// Allowed returns false if destination's host disallows it; hosts without a robots.txt,
+// or whose robots.txt couldn't be fetched, allow everything
+func (c *RobotsTxtChecker) Allowed(destination *url.URL) bool {
+	robotsURL := url.URL{Scheme: destination.Scheme, Host: destination.Host, Path: "/robots.txt"}
+	key := robotsURL.String()
+
+	c.mutex.Lock()
+	cached, found := c.hosts[key]
+	if !found || time.Since(cached.fetchedAt) > robotsTxtTTL {
+		cached = &cachedRobotsTxt{fetchedAt: time.Now()}
+		c.hosts[key] = cached
+	}
+	c.mutex.Unlock()
+
+	// requests for the same host wait for the first one to fetch robots.txt
+	cached.once.Do(func() {
+		cached.robots = c.fetch(key)
+	})
+	if cached.robots == nil {
+		return true
+	}
+	path := destination.EscapedPath()
+	if destination.RawQuery != "" {
+		path += "?" + destination.RawQuery
+	}
+	return cached.robots.TestAgent(path, c.agent)
+}
+
+func (c *RobotsTxtChecker) fetch(robotsURL string) *robotstxt.RobotsData {
+	resp, err := c.client.Get(robotsURL)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRobotsTxtSize))
+	if err != nil {
+		return nil
+	}
+	// 4xx means there are no rules and 5xx that the whole host is off limits for now
+	robots, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+	if err != nil {
+		return nil
+	}
+	return robots
+}
+
+// RespectRobotsTxt makes the fetcher refuse destinations their host's robots.txt disallows for agent
+func (f *PageFetcher) RespectRobotsTxt(agent string) {
+	f.robots = NewRobotsTxtChecker(f.client, agent)
+}
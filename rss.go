@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feedEntry is an RSS item or an Atom entry, whichever elements it has
+type feedEntry struct {
+	Title       string     `xml:"title"`
+	Links       []feedLink `xml:"link"`
+	GUID        string     `xml:"guid"`
+	ID          string     `xml:"id"`
+	Description string     `xml:"description"`
+	Summary     string     `xml:"summary"`
+	Content     string     `xml:"content"`
+	Encoded     string     `xml:"encoded"`
+	Creator     string     `xml:"creator"`
+	AuthorName  string     `xml:"author>name"`
+}
+
+// feedDocument is an RSS 2.0, RSS 1.0 (RDF) or Atom feed
+type feedDocument struct {
+	Channel struct {
+		Items []feedEntry `xml:"item"`
+	} `xml:"channel"`
+	Items   []feedEntry `xml:"item"`
+	Entries []feedEntry `xml:"entry"`
+}
+
+func (d *feedDocument) entries() []feedEntry {
+	return append(append(append([]feedEntry(nil), d.Channel.Items...), d.Items...), d.Entries...)
+}
+
+// link is the entry's page: RSS's link text or Atom's alternate link
+func (e *feedEntry) link() string {
+	for _, link := range e.Links {
+		if link.Href == "" && strings.TrimSpace(link.Text) != "" {
+			return strings.TrimSpace(link.Text)
+		}
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func (e *feedEntry) key() string {
+	for _, key := range []string{e.GUID, e.ID, e.link(), e.Title} {
+		if key = strings.TrimSpace(key); key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+func (e *feedEntry) summary() string {
+	for _, summary := range []string{e.Encoded, e.Content, e.Description, e.Summary} {
+		if strings.TrimSpace(summary) != "" {
+			return summary
+		}
+	}
+	return ""
+}
+
+type polledFeed struct {
+	url          string
+	etag         string
+	lastModified string
+	seen         map[string]bool
+}
+
+// FeedPoller is a Source of the new entries of RSS and Atom feeds, polled every interval; an
+// entry's link and the links in its summary are harvested
+type FeedPoller struct {
+	feeds    []*polledFeed
+	interval time.Duration
+	logger   *zap.Logger
+	client   *http.Client
+}
+
+// NewFeedPoller prepares polling the feeds at feedURLs
+func NewFeedPoller(feedURLs []string, interval time.Duration, timeout time.Duration, logger *zap.Logger) *FeedPoller {
+	result := new(FeedPoller)
+	for _, feedURL := range feedURLs {
+		result.feeds = append(result.feeds, &polledFeed{url: feedURL})
+	}
+	result.interval = interval
+	result.logger = logger
+	result.client = &http.Client{Timeout: timeout}
+	return result
+}
+
+// Start implements Source, polling the feeds until ctx is done
+func (p *FeedPoller) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			for _, feed := range p.feeds {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := p.poll(ctx, feed, items); err != nil && ctx.Err() == nil {
+					p.logger.Error("Unable to poll feed", zap.String("feed", feed.url), zap.Error(err))
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return items
+}
+
+// poll sends on the feed's entries that weren't in it the last time, unless it hasn't changed
+func (p *FeedPoller) poll(ctx context.Context, feed *polledFeed, items chan<- Item) error {
+	req, err := http.NewRequest(http.MethodGet, feed.url, nil)
+	if err != nil {
+		return err
+	}
+	if feed.etag != "" {
+		req.Header.Set("If-None-Match", feed.etag)
+	}
+	if feed.lastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.lastModified)
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	var doc feedDocument
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return err
+	}
+	feed.etag = resp.Header.Get("ETag")
+	feed.lastModified = resp.Header.Get("Last-Modified")
+
+	seen := make(map[string]bool)
+	for _, entry := range doc.entries() {
+		key := entry.key()
+		seen[key] = true
+		if feed.seen[key] {
+			continue
+		}
+		text := strings.TrimSpace(entry.Title)
+		if link := entry.link(); link != "" {
+			text += " " + link
+		}
+		if summary := entry.summary(); summary != "" {
+			text += "\n\n" + htmlPostText(summary, "")
+		}
+		author := entry.Creator
+		if author == "" {
+			author = entry.AuthorName
+		}
+		provenance := &Provenance{Source: "rss", Channel: feed.url, Author: strings.TrimSpace(author), PostURL: entry.link()}
+		select {
+		case items <- Item{Text: text, Provenance: provenance}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	feed.seen = seen
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+	"go.uber.org/zap"
+)
+
+// safeBrowsingLookupURL is Google Safe Browsing's Lookup API (v4)
+const safeBrowsingLookupURL = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+
+// URLScreener checks URLs against a list of known malware and phishing sites
+type URLScreener interface {
+	// ScreenURL returns the threat destination is known for, or "" if it's not listed
+	ScreenURL(destination *url.URL) (string, error)
+}
+
+// SafeBrowsingScreener looks URLs up with Google Safe Browsing
+type SafeBrowsingScreener struct {
+	client *http.Client
+	apiKey string
+}
+
+// NewSafeBrowsingScreener uses the Safe Browsing API with apiKey, giving up after timeout
+func NewSafeBrowsingScreener(apiKey string, timeout time.Duration) *SafeBrowsingScreener {
+	result := new(SafeBrowsingScreener)
+	result.client = &http.Client{Timeout: timeout}
+	result.apiKey = apiKey
+	return result
+}
+
+type safeBrowsingThreatEntry struct {
+	URL string `json:"url"`
+}
+
+type safeBrowsingRequest struct {
+	Client struct {
+		ClientID      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string                  `json:"threatTypes"`
+		PlatformTypes    []string                  `json:"platformTypes"`
+		ThreatEntryTypes []string                  `json:"threatEntryTypes"`
+		ThreatEntries    []safeBrowsingThreatEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type safeBrowsingResponse struct {
+	Matches []struct {
+		ThreatType string `json:"threatType"`
+	} `json:"matches"`
+}
+
+// ScreenURL implements URLScreener
+func (s *SafeBrowsingScreener) ScreenURL(destination *url.URL) (string, error) {
+	request := new(safeBrowsingRequest)
+	request.Client.ClientID = defaultRobotsAgent
+	request.Client.ClientVersion = "1.0"
+	request.ThreatInfo.ThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+	request.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	request.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	request.ThreatInfo.ThreatEntries = []safeBrowsingThreatEntry{{URL: destination.String()}}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.client.Post(safeBrowsingLookupURL+"?key="+url.QueryEscape(s.apiKey), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("safe browsing answered %s", resp.Status)
+	}
+	var response safeBrowsingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+	if len(response.Matches) == 0 {
+		return "", nil
+	}
+	return response.Matches[0].ThreatType, nil
+}
+
+// BlocklistScreener checks URLs against a local list of domains (which include their
+// subdomains) and URLs
+type BlocklistScreener struct {
+	domains filter.DomainList
+	urls    map[string]bool
+}
+
+// NewBlocklistScreener lists each entry of the file at path, one URL or domain per line
+func NewBlocklistScreener(path string) (*BlocklistScreener, error) {
+	entries, err := filter.ReadListFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := new(BlocklistScreener)
+	result.urls = make(map[string]bool)
+	for _, entry := range entries {
+		if strings.Contains(entry, "://") {
+			result.urls[entry] = true
+			continue
+		}
+		result.domains.Set(entry)
+	}
+	return result, nil
+}
+
+// ScreenURL implements URLScreener
+func (s *BlocklistScreener) ScreenURL(destination *url.URL) (string, error) {
+	if s.urls[destination.String()] || s.domains.Matches(destination.Hostname()) {
+		return "BLOCKLISTED", nil
+	}
+	return "", nil
+}
+
+// ScreenURLsWith makes the storage check each destination with screeners before storing it;
+// listed destinations are dropped or, unless drop is set, stored with an unsafe field in
+// their front matter that says why
+func (storage *HarvestedResourceStorage) ScreenURLsWith(screeners []URLScreener, drop bool) {
+	storage.screeners = screeners
+	storage.dropUnsafe = drop
+}
+
+// screen returns the first threat a screener knows destination for; screeners that fail
+// are logged and skipped
+func (storage *HarvestedResourceStorage) screen(destination *url.URL) string {
+	if destination == nil {
+		return ""
+	}
+	for _, screener := range storage.screeners {
+		threat, err := screener.ScreenURL(destination)
+		if err != nil {
+			storage.logger.Warn("Unable to screen URL", zap.String("url", destination.String()), zap.Error(err))
+			continue
+		}
+		if threat != "" {
+			return threat
+		}
+	}
+	return ""
+}
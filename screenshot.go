@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+)
+
+// ScreenshotCapturer renders destinations in headless Chrome and keeps a PNG of each next to
+// its document, for visual review and for working out what a dead link used to show
+type ScreenshotCapturer struct {
+	browser context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+// NewScreenshotCapturer starts a headless browser that's given timeout to render each page
+func NewScreenshotCapturer(logger *zap.Logger, timeout time.Duration) *ScreenshotCapturer {
+	result := new(ScreenshotCapturer)
+	result.browser, result.cancel = chromedp.NewContext(context.Background())
+	result.timeout = timeout
+	result.logger = logger
+	return result
+}
+
+// Capture renders destination and returns a PNG of what it looks like
+func (c *ScreenshotCapturer) Capture(destination *url.URL) ([]byte, error) {
+	tab, closeTab := chromedp.NewContext(c.browser)
+	defer closeTab()
+	ctx, cancel := context.WithTimeout(tab, c.timeout)
+	defer cancel()
+
+	var png []byte
+	err := chromedp.Run(ctx,
+		chromedp.EmulateViewport(1280, 1024),
+		chromedp.Navigate(destination.String()),
+		chromedp.CaptureScreenshot(&png))
+	return png, err
+}
+
+// Close shuts the browser down
+func (c *ScreenshotCapturer) Close() {
+	c.cancel()
+}
+
+func (c *ScreenshotCapturer) EnrichDestination(destination *url.URL, resource *EnrichedResource) {
+	png, err := c.Capture(destination)
+	if err != nil {
+		c.logger.Warn("Unable to capture screenshot", zap.String("url", destination.String()), zap.Error(err))
+		return
+	}
+	key := resource.Slug + ".png"
+	resource.Attachments = append(resource.Attachments, Attachment{Key: key, Data: png})
+	resource.FrontMatter["screenshot"] = key
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"go.uber.org/zap"
+)
+
+// TwitterSearch is a Source of the tweets one or more queries find with the Twitter Search API
+type TwitterSearch struct {
+	scheduler *twitter.RateLimitScheduler
+	logger    *zap.Logger
+	queries   []string
+	params    url.Values
+	sinceIDs  map[string]int64
+	interval  time.Duration
+	state     *HarvestState
+}
+
+// NewTwitterSearch prepares a search for the given queries, passing params (e.g. lang) along with each one
+func NewTwitterSearch(scheduler *twitter.RateLimitScheduler, logger *zap.Logger, queries []string, params url.Values) *TwitterSearch {
+	result := new(TwitterSearch)
+	result.scheduler = scheduler
+	result.logger = logger
+	result.queries = queries
+	result.params = params
+	result.sinceIDs = make(map[string]int64)
+	return result
+}
+
+// ResumeFrom makes the search only ask for tweets newer than the ones state says each query
+// found before, and keep state up to date as it finds more
+func (s *TwitterSearch) ResumeFrom(state *HarvestState) {
+	s.state = state
+	for _, query := range s.queries {
+		if sinceID := state.SinceID(query); sinceID > 0 {
+			s.sinceIDs[query] = sinceID
+		}
+	}
+}
+
+// PollEvery makes Start run all the queries every interval rather than once
+func (s *TwitterSearch) PollEvery(interval time.Duration) {
+	s.interval = interval
+}
+
+// Start implements Source, running each query once, or every PollEvery interval until ctx is
+// done, and only asking for tweets newer than the ones already seen
+func (s *TwitterSearch) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		if s.interval <= 0 {
+			s.searchAll(ctx, items)
+			return
+		}
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.searchAll(ctx, items)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return items
+}
+
+func (s *TwitterSearch) searchAll(ctx context.Context, items chan<- Item) {
+	for _, query := range s.queries {
+		if ctx.Err() != nil {
+			return
+		}
+		s.search(ctx, query, items)
+	}
+}
+
+func (s *TwitterSearch) search(ctx context.Context, query string, items chan<- Item) {
+	v := url.Values{}
+	for name, values := range s.params {
+		v[name] = values
+	}
+	if sinceID, ok := s.sinceIDs[query]; ok {
+		v.Set("since_id", strconv.FormatInt(sinceID, 10))
+	}
+
+	var searchResult anaconda.SearchResponse
+	err := s.scheduler.Do(ctx, "search", "/search/tweets", func(api *anaconda.TwitterApi) error {
+		var searchErr error
+		searchResult, searchErr = api.GetSearch(query, v)
+		return searchErr
+	})
+	if err != nil {
+		s.logger.Error("Unable to search Twitter", zap.String("query", query), zap.Error(err))
+		return
+	}
+
+	for i := range searchResult.Statuses {
+		tweet := &searchResult.Statuses[i]
+		//createTweetTestData(contentHarvester, csvWriter, tweet.Text)
+		select {
+		case items <- Item{Tweet: tweet, Provenance: &Provenance{Query: query}}:
+		case <-ctx.Done():
+			return
+		}
+		if tweet.Id > s.sinceIDs[query] {
+			s.sinceIDs[query] = tweet.Id
+			if s.state != nil {
+				s.state.SetSinceID(query, tweet.Id)
+			}
+		}
+	}
+}
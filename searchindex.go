@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve"
+)
+
+// indexedDocument is what the search index holds for each stored resource
+type indexedDocument struct {
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Text        string    `json:"text"`
+	Body        string    `json:"body"`
+	HarvestedAt time.Time `json:"harvestedAt"`
+}
+
+// SearchIndex is an embedded Bleve full-text index over stored resources, keyed by slug
+type SearchIndex struct {
+	mutex sync.Mutex
+	index bleve.Index
+}
+
+// OpenSearchIndex opens the index at path, creating it if it doesn't exist yet
+func OpenSearchIndex(path string) (*SearchIndex, error) {
+	index, err := bleve.Open(path)
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = bleve.New(path, bleve.NewIndexMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := new(SearchIndex)
+	result.index = index
+	return result, nil
+}
+
+// WriteResource implements ResourceWriter, indexing resources as they're stored
+func (s *SearchIndex) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	_, body, _ := splitFrontMatter(document)
+	title, _ := resource.FrontMatter["title"].(string)
+	url, _ := resource.FrontMatter["canonicalURL"].(string)
+	if url == "" {
+		url = resource.CleanedURL
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.index.Index(resource.Slug, indexedDocument{
+		Title:       title,
+		URL:         url,
+		Text:        resource.Text,
+		Body:        body,
+		HarvestedAt: resource.HarvestedAt,
+	})
+}
+
+// IndexStored (re)indexes documents read back from the store and returns how many it indexed
+func (s *SearchIndex) IndexStored(documents []*StoredDocument) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	batch := s.index.NewBatch()
+	for _, document := range documents {
+		err := batch.Index(document.Key, indexedDocument{
+			Title:       document.Field("title"),
+			URL:         document.URL(),
+			Body:        document.Body,
+			HarvestedAt: document.HarvestedAt,
+		})
+		if err != nil {
+			return 0, err
+		}
+		if batch.Size() >= 100 {
+			if err := s.index.Batch(batch); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	return len(documents), s.index.Batch(batch)
+}
+
+// Search runs a Bleve query string query (e.g. `+golang title:release`) and returns the best
+// size matches, with the title and URL fields and highlighted fragments
+func (s *SearchIndex) Search(query string, size int) (*bleve.SearchResult, error) {
+	request := bleve.NewSearchRequest(bleve.NewQueryStringQuery(query))
+	request.Size = size
+	request.Fields = []string{"title", "url"}
+	request.Highlight = bleve.NewHighlight()
+	return s.index.Search(request)
+}
+
+// Close closes the index
+func (s *SearchIndex) Close() error {
+	return s.index.Close()
+}
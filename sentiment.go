@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+)
+
+// SentimentAnalyzer scores text from -1 (very negative) to 1 (very positive)
+type SentimentAnalyzer interface {
+	AnalyzeSentiment(text string) (float64, error)
+}
+
+// Sentiment is the score and label recorded for a tweet
+type Sentiment struct {
+	Score float64
+	Label string
+}
+
+// sentimentNeutralBand is how far from zero a score has to be before it's called positive or negative
+const sentimentNeutralBand = 0.05
+
+func newSentiment(score float64) *Sentiment {
+	result := &Sentiment{Score: math.Round(score*100) / 100, Label: "neutral"}
+	if score > sentimentNeutralBand {
+		result.Label = "positive"
+	} else if score < -sentimentNeutralBand {
+		result.Label = "negative"
+	}
+	return result
+}
+
+// defaultSentimentLexicon is a small AFINN-style word list (scores from -5 to 5), good enough to
+// tell the obvious cases apart; use a lexicon file or an API for anything serious
+var defaultSentimentLexicon = map[string]float64{
+	"amazing": 4, "awesome": 4, "beautiful": 3, "best": 3, "brilliant": 4, "congrats": 2,
+	"cool": 1, "excellent": 3, "excited": 3, "fantastic": 4, "fun": 4, "glad": 3, "good": 3,
+	"great": 3, "happy": 3, "helpful": 2, "impressive": 3, "interesting": 2, "love": 3,
+	"loved": 3, "nice": 3, "perfect": 3, "recommend": 2, "success": 2, "thanks": 2, "win": 4,
+	"wonderful": 4, "wow": 4, "useful": 2, "agree": 1, "easy": 1, "favorite": 2, "improve": 2,
+	"angry": -3, "annoying": -2, "awful": -3, "bad": -3, "broken": -1, "bug": -2, "crash": -2,
+	"disappointed": -2, "disaster": -2, "fail": -2, "failed": -2, "fake": -3, "hate": -3,
+	"horrible": -3, "kill": -3, "lost": -3, "poor": -2, "problem": -2, "sad": -2, "scam": -2,
+	"stupid": -2, "terrible": -3, "ugly": -3, "worse": -3, "worst": -3, "wrong": -2,
+	"attack": -1, "crisis": -3, "fear": -2, "risk": -2, "warning": -3, "breach": -2,
+}
+
+var sentimentWordRegEx = regexp.MustCompile(`[a-z']+`)
+
+var sentimentNegations = map[string]bool{"not": true, "no": true, "never": true, "don't": true, "isn't": true, "wasn't": true, "can't": true}
+
+// LexiconSentimentAnalyzer scores text by averaging the scores of the words it knows
+type LexiconSentimentAnalyzer struct {
+	lexicon map[string]float64
+}
+
+// NewLexiconSentimentAnalyzer uses the built in word list, extended with the word<TAB>score
+// lines in lexiconPath if one is given
+func NewLexiconSentimentAnalyzer(lexiconPath string) (*LexiconSentimentAnalyzer, error) {
+	result := new(LexiconSentimentAnalyzer)
+	result.lexicon = make(map[string]float64)
+	for word, score := range defaultSentimentLexicon {
+		result.lexicon[word] = score
+	}
+	if lexiconPath == "" {
+		return result, nil
+	}
+
+	lines, err := filter.ReadListFile(lexiconPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: %q should be word<TAB>score", lexiconPath, line)
+		}
+		score, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q: %v", lexiconPath, line, err)
+		}
+		result.lexicon[strings.ToLower(fields[0])] = score
+	}
+	return result, nil
+}
+
+func (a *LexiconSentimentAnalyzer) AnalyzeSentiment(text string) (float64, error) {
+	words := sentimentWordRegEx.FindAllString(strings.ToLower(text), -1)
+	total, matched := 0.0, 0
+	for i, word := range words {
+		score, found := a.lexicon[word]
+		if !found {
+			continue
+		}
+		if i > 0 && sentimentNegations[words[i-1]] {
+			score = -score
+		}
+		total += score
+		matched++
+	}
+	if matched == 0 {
+		return 0, nil
+	}
+	return math.Max(-1, math.Min(1, total/float64(matched)/5)), nil
+}
+
+// APISentimentAnalyzer posts {"text": ...} to an external service that answers {"score": ...}
+type APISentimentAnalyzer struct {
+	client *http.Client
+	url    string
+}
+
+// NewAPISentimentAnalyzer creates an analyzer that calls the service at url
+func NewAPISentimentAnalyzer(url string, timeout time.Duration) *APISentimentAnalyzer {
+	result := new(APISentimentAnalyzer)
+	result.client = &http.Client{Timeout: timeout}
+	result.url = url
+	return result
+}
+
+func (a *APISentimentAnalyzer) AnalyzeSentiment(text string) (float64, error) {
+	request, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(request))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("sentiment API returned %s", resp.Status)
+	}
+
+	var response struct {
+		Score float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, err
+	}
+	return response.Score, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/graph-gophers/graphql-transport-ws/graphqlws"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// HarvesterServer is the serve mode's HTTP server, with APIs over the store and the resources
+// being harvested
+type HarvesterServer struct {
+	storage     *HarvestedResourceStorage
+	broadcaster *ResourceBroadcaster
+	logger      *zap.Logger
+	router      *mux.Router
+	ignoreLog   *IgnoreLog
+	adminToken  string
+	streamMutex sync.Mutex
+	stream      *FilterStream
+	staleAfter  time.Duration
+}
+
+// NewHarvesterServer creates the server, with broadcaster feeding its real-time APIs. GraphQL
+// queries are served on /graphql, subscriptions on the same path over WebSockets (graphql-ws
+// protocol), the REST API under /api and streams of stored resources over plain WebSockets on
+// /ws and Server-Sent Events on /events, with Prometheus metrics on /metrics and health checks
+// on /healthz and /readyz. The /admin endpoints, which change what's harvested, REST harvesting
+// and the /debug endpoints require adminToken as a bearer token, and are off without one.
+func NewHarvesterServer(storage *HarvestedResourceStorage, broadcaster *ResourceBroadcaster, logger *zap.Logger, adminToken string) *HarvesterServer {
+	result := new(HarvesterServer)
+	result.adminToken = adminToken
+	result.storage = storage
+	result.broadcaster = broadcaster
+	result.logger = logger
+	result.staleAfter = defaultStaleStreamAfter
+
+	schema := newGraphQLSchema(storage, result.broadcaster)
+	result.router = mux.NewRouter()
+	result.router.Handle("/graphql", graphqlws.NewHandlerFunc(schema, &relay.Handler{Schema: schema}))
+	result.router.HandleFunc("/ws", result.streamWebSocket)
+	result.router.HandleFunc("/events", result.streamEvents)
+	result.router.Handle("/metrics", promhttp.Handler())
+	result.routeHealth()
+	result.routeDebug()
+	result.routeREST()
+	result.routeAdmin()
+	return result
+}
+
+// ListenAndServe serves the APIs on addr (e.g. ":8080") until the server fails
+func (s *HarvesterServer) ListenAndServe(addr string) error {
+	s.logger.Info("Serving", zap.String("addr", addr))
+	return http.ListenAndServe(addr, s.router)
+}
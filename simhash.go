@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// simhashShingleSize is how many consecutive words make up each feature of the fingerprint
+const simhashShingleSize = 3
+
+// simhash fingerprints text so that texts differing in only a few words get fingerprints
+// differing in only a few bits
+func simhash(text string) uint64 {
+	words := strings.Fields(strings.ToLower(text))
+	var weights [64]int
+	for i := 0; i+simhashShingleSize <= len(words); i++ {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(words[i:i+simhashShingleSize], " ")))
+		feature := hash.Sum64()
+		for bit := uint(0); bit < 64; bit++ {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var result uint64
+	for bit := uint(0); bit < 64; bit++ {
+		if weights[bit] > 0 {
+			result |= 1 << bit
+		}
+	}
+	return result
+}
+
+type simhashRecord struct {
+	Simhash string `json:"simhash"`
+	Slug    string `json:"slug"`
+	hash    uint64
+}
+
+// NearDuplicateDetector remembers the fingerprints of stored articles so syndicated copies of
+// the same story can point at the first one stored instead of being stored again
+type NearDuplicateDetector struct {
+	path        string
+	maxDistance int
+	logger      *zap.Logger
+	mutex       sync.Mutex
+	records     []simhashRecord
+	unsaved     int
+}
+
+// NewNearDuplicateDetector loads the fingerprints kept at path; articles whose fingerprints are
+// at most maxDistance bits apart are considered copies
+func NewNearDuplicateDetector(path string, maxDistance int, logger *zap.Logger) (*NearDuplicateDetector, error) {
+	result := new(NearDuplicateDetector)
+	result.path = path
+	result.maxDistance = maxDistance
+	result.logger = logger
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &result.records); err != nil {
+		return nil, err
+	}
+	for i := range result.records {
+		if _, err := fmt.Sscanf(result.records[i].Simhash, "%016x", &result.records[i].hash); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Original returns the slug of the first stored article text is a near duplicate of, if any;
+// otherwise text is remembered as the original under slug
+func (d *NearDuplicateDetector) Original(text string, slug string) (string, uint64, bool) {
+	hash := simhash(text)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, record := range d.records {
+		if bits.OnesCount64(record.hash^hash) <= d.maxDistance {
+			return record.Slug, hash, true
+		}
+	}
+	d.records = append(d.records, simhashRecord{Simhash: fmt.Sprintf("%016x", hash), Slug: slug, hash: hash})
+	if d.unsaved++; d.unsaved >= 100 {
+		d.save()
+	}
+	return "", hash, false
+}
+
+// Save persists the fingerprints
+func (d *NearDuplicateDetector) Save() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.save()
+}
+
+func (d *NearDuplicateDetector) save() {
+	data, err := json.Marshal(d.records)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(d.path), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(d.path, data, 0644)
+	}
+	if err != nil {
+		d.logger.Error("Unable to save article fingerprints", zap.String("path", d.path), zap.Error(err))
+		return
+	}
+	d.unsaved = 0
+}
+
+// EnrichResource links near duplicate articles to their original and leaves the copy's text out,
+// so it has to run after article (or PDF) extraction
+func (d *NearDuplicateDetector) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	if resource.ArticleText == "" {
+		return
+	}
+	original, hash, duplicate := d.Original(resource.ArticleText, resource.Slug)
+	resource.FrontMatter["simhash"] = fmt.Sprintf("%016x", hash)
+	if !duplicate {
+		return
+	}
+
+	resource.FrontMatter["nearDuplicateOf"] = original
+	sections := resource.Sections[:0]
+	for _, section := range resource.Sections {
+		if section != resource.ArticleText {
+			sections = append(sections, section)
+		}
+	}
+	resource.Sections = sections
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// slackEventQueueSize is how many events can wait to be harvested before Slack is asked to
+// send them again later
+const slackEventQueueSize = 1000
+
+// slackMaxClockSkew is how old a request's timestamp can be before it's taken for a replay
+const slackMaxClockSkew = 5 * time.Minute
+
+// slackLinkRegEx matches the links in Slack's message formatting, <url> or <url|label>
+var slackLinkRegEx = regexp.MustCompile(`<(https?://[^|>]+)(\|[^>]*)?>`)
+
+type slackEvent struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	Channel string `json:"channel"`
+	User    string `json:"user"`
+	Text    string `json:"text"`
+	BotID   string `json:"bot_id"`
+	Links   []struct {
+		URL string `json:"url"`
+	} `json:"links"`
+}
+
+type slackCallback struct {
+	Type      string      `json:"type"`
+	Challenge string      `json:"challenge"`
+	Event     *slackEvent `json:"event"`
+}
+
+// SlackEvents is a Source of the links shared in a Slack workspace's channels, which the
+// workspace's app sends to its Events API endpoint as message and link_shared events. Requests
+// are checked against the app's signing secret.
+type SlackEvents struct {
+	addr          string
+	signingSecret []byte
+	logger        *zap.Logger
+	queue         chan Item
+}
+
+// NewSlackEvents prepares receiving Slack's callbacks at addr (e.g. ":3000"), on /slack/events
+func NewSlackEvents(addr string, signingSecret string, logger *zap.Logger) *SlackEvents {
+	result := new(SlackEvents)
+	result.addr = addr
+	result.signingSecret = []byte(signingSecret)
+	result.logger = logger
+	result.queue = make(chan Item, slackEventQueueSize)
+	return result
+}
+
+// Start implements Source, serving the endpoint until ctx is done
+func (s *SlackEvents) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	mux := http.NewServeMux()
+	mux.Handle("/slack/events", s)
+	server := &http.Server{Addr: s.addr, Handler: mux}
+	go func() {
+		s.logger.Info("Receiving Slack events", zap.String("addr", s.addr))
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			s.logger.Error("Unable to receive Slack events", zap.String("addr", s.addr), zap.Error(err))
+		}
+	}()
+	go func() {
+		defer close(items)
+		for {
+			select {
+			case item := <-s.queue:
+				select {
+				case items <- item:
+				case <-ctx.Done():
+				}
+			case <-ctx.Done():
+				server.Close()
+				return
+			}
+		}
+	}()
+	return items
+}
+
+// verify checks the request's X-Slack-Signature, an HMAC-SHA256 of its timestamp and body
+func (s *SlackEvents) verify(r *http.Request, body []byte) bool {
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > slackMaxClockSkew || skew < -slackMaxClockSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, s.signingSecret)
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature")))
+}
+
+// ServeHTTP receives one of Slack's callbacks
+func (s *SlackEvents) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST expected", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.verify(r, body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var callback slackCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch callback.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(callback.Challenge))
+		return
+	case "event_callback":
+		if item, harvest := slackItem(callback.Event); harvest {
+			select {
+			case s.queue <- item:
+			default:
+				// Slack sends the event again later
+				s.logger.Warn("Slack event queue is full", zap.Int("queued", len(s.queue)))
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// slackItem returns the links of a message or link_shared event; edits, bots' messages and
+// the other events are left alone
+func slackItem(event *slackEvent) (Item, bool) {
+	if event == nil {
+		return Item{}, false
+	}
+	provenance := &Provenance{Source: "slack", Channel: event.Channel, Author: event.User}
+	switch event.Type {
+	case "message":
+		if (event.Subtype != "" && event.Subtype != "thread_broadcast") || event.BotID != "" || !slackLinkRegEx.MatchString(event.Text) {
+			return Item{}, false
+		}
+		return Item{Text: slackLinkRegEx.ReplaceAllString(event.Text, "$1"), Provenance: provenance}, true
+	case "link_shared":
+		var text string
+		for _, link := range event.Links {
+			text += link.URL + " "
+		}
+		return Item{Text: text, Provenance: provenance}, text != ""
+	}
+	return Item{}, false
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// slugClaimsCapacity bounds how many stored slugs are remembered; older ones are looked up in
+// the store again
+const slugClaimsCapacity = 10000
+
+type slugClaim struct {
+	slug       string
+	cleanedURL string
+}
+
+// slugClaims remembers which resource holds a slug: the ones being written, until they're
+// stored or fail, and the most recently stored ones
+type slugClaims struct {
+	mutex    sync.Mutex
+	pending  map[string]string
+	entries  map[string]*list.Element
+	order    *list.List
+	capacity int
+}
+
+func newSlugClaims(capacity int) *slugClaims {
+	result := new(slugClaims)
+	result.pending = make(map[string]string)
+	result.entries = make(map[string]*list.Element)
+	result.order = list.New()
+	result.capacity = capacity
+	return result
+}
+
+func (c *slugClaims) lookup(slug string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if cleanedURL, found := c.pending[slug]; found {
+		return cleanedURL, true
+	}
+	element, found := c.entries[slug]
+	if !found {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*slugClaim).cleanedURL, true
+}
+
+// reserve holds slug for cleanedURL while it's being written
+func (c *slugClaims) reserve(slug string, cleanedURL string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pending[slug] = cleanedURL
+}
+
+// release gives up the reservation of a slug whose resource couldn't be stored
+func (c *slugClaims) release(slug string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.pending, slug)
+}
+
+// claim records that slug holds cleanedURL now that it's been stored
+func (c *slugClaims) claim(slug string, cleanedURL string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.pending, slug)
+	if element, found := c.entries[slug]; found {
+		element.Value = &slugClaim{slug: slug, cleanedURL: cleanedURL}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[slug] = c.order.PushFront(&slugClaim{slug: slug, cleanedURL: cleanedURL})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*slugClaim).slug)
+	}
+}
+
+// storageKey returns the key under which the resource with cleanedURL should be stored. That's
+// normally slug itself but when a different resource already lives under slug we try slug-2,
+// slug-3, ... until we find a free key or the one already holding this same resource. The key
+// is reserved until the resource is written, which claims it, or fails, which releases it.
+func (storage *HarvestedResourceStorage) storageKey(slug string, cleanedURL string) string {
+	key := slug
+	for version := 2; ; version++ {
+		storedURL, known := storage.slugs.lookup(key)
+		if !known && storage.diskv.Has(key) {
+			// written by an earlier run into the same storage directory, or long enough ago
+			// that it's no longer remembered
+			known = true
+			if data, err := storage.diskv.Read(key); err == nil && strings.Contains(string(data), cleanedURL) {
+				storedURL = cleanedURL
+			}
+		}
+		if !known || storedURL == cleanedURL {
+			storage.slugs.reserve(key, cleanedURL)
+			return key
+		}
+		key = fmt.Sprintf("%s-%d", slug, version)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Item is something a Source came across whose links should be harvested
+type Item struct {
+	// Tweet is set for items from Twitter, so the tweet filters, spam scoring and so on apply
+	Tweet *anaconda.Tweet
+	// Text is harvested for items that aren't tweets
+	Text       string
+	Provenance *Provenance
+}
+
+// Source is where items to harvest come from, e.g. the Twitter filter stream or search.
+// Start produces items until the source runs dry or ctx is done, then closes the channel.
+type Source interface {
+	Start(ctx context.Context) <-chan Item
+}
+
+// mergedSources is a Source of everything its sources produce, side by side
+type mergedSources []Source
+
+// MergeSources returns a Source of all of sources, done once they all are
+func MergeSources(sources ...Source) Source {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	return mergedSources(sources)
+}
+
+// Start implements Source
+func (sources mergedSources) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	var running sync.WaitGroup
+	for _, source := range sources {
+		running.Add(1)
+		go func(from <-chan Item) {
+			defer running.Done()
+			for item := range from {
+				items <- item
+			}
+		}(source.Start(ctx))
+	}
+	go func() {
+		running.Wait()
+		close(items)
+	}()
+	return items
+}
+
+// HarvestFrom harvests everything source produces, returning once it's done
+func (h *TweetHarvester) HarvestFrom(ctx context.Context, source Source) {
+	for item := range source.Start(ctx) {
+		h.HarvestItem(item)
+	}
+}
+
+// HarvestItem stores all resources in item unless a filter ignores it
+func (h *TweetHarvester) HarvestItem(item Item) {
+	if item.Tweet != nil {
+		h.Harvest(*item.Tweet, item.Provenance)
+		return
+	}
+	if h.limit != nil && !h.limit.allowTweet() {
+		return
+	}
+	if item.Provenance != nil {
+		sourceItemsCounter.WithLabelValues(item.Provenance.Source).Inc()
+	}
+	ctx, span := startSpan(context.Background(), "harvest item")
+	defer span.End()
+	h.storage.SaveAllInText(ctx, item.Text, item.Provenance)
+}
+
+// postParagraphs keeps a post's paragraphs and lines apart once it's turned into text
+var postParagraphs = strings.NewReplacer("</p>", " </p>", "<p>", " <p>", "<br>", " ", "<br/>", " ", "<br />", " ")
+
+// htmlPostText turns a post's HTML content (e.g. a Mastodon status or an HN comment) into text.
+// The links are spelled out after it, unless the full URL is already in the text (sites often
+// shorten the ones they show), apart from those matching the skip selector, if it's not empty.
+func htmlPostText(content string, skip string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(postParagraphs.Replace(content)))
+	if err != nil {
+		return content
+	}
+	text := doc.Text()
+	links := doc.Find("a[href]")
+	if skip != "" {
+		links = links.Not(skip)
+	}
+	links.Each(func(i int, link *goquery.Selection) {
+		if href := link.AttrOr("href", ""); href != "" && !strings.Contains(text, href) {
+			text += " " + href
+		}
+	})
+	return strings.TrimSpace(text)
+}
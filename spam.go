@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+)
+
+// spamRecentTextsWindow is how many recent tweet texts we compare new tweets against
+const spamRecentTextsWindow = 1000
+
+var normalizeTweetTextRegEx = regexp.MustCompile(`https?://\S+|@\w+|\s+`)
+
+// SpamAssessment is the result of scoring a tweet: 0 means nothing suspicious, 1 means every
+// signal we look for was present
+type SpamAssessment struct {
+	Score   float64
+	Signals []string
+}
+
+// SpamScorer uses simple account and content heuristics to spot likely bots and spammers
+type SpamScorer struct {
+	mutex       sync.Mutex
+	recentTexts map[string]int
+	recent      []string
+}
+
+// NewSpamScorer creates a scorer with an empty duplicate text history
+func NewSpamScorer() *SpamScorer {
+	result := new(SpamScorer)
+	result.recentTexts = make(map[string]int)
+	return result
+}
+
+// Assess scores tweet and records its text so copies of it later on are noticed
+func (s *SpamScorer) Assess(tweet *anaconda.Tweet) *SpamAssessment {
+	result := new(SpamAssessment)
+	signal := func(weight float64, name string) {
+		result.Score += weight
+		result.Signals = append(result.Signals, name)
+	}
+
+	if created, err := time.Parse(time.RubyDate, tweet.User.CreatedAt); err == nil {
+		age := time.Since(created)
+		if age < 30*24*time.Hour {
+			signal(0.3, "new-account")
+		}
+		days := math.Max(1, age.Hours()/24)
+		if float64(tweet.User.StatusesCount)/days > 100 {
+			signal(0.3, "high-tweet-frequency")
+		}
+	}
+	if tweet.User.DefaultProfileImage {
+		signal(0.2, "default-profile-image")
+	}
+	if s.copies(tweet.Text) >= 3 {
+		signal(0.2, "duplicate-text")
+	}
+
+	result.Score = math.Min(1, result.Score)
+	return result
+}
+
+// copies remembers text and returns how many times it was seen among the recent tweets
+func (s *SpamScorer) copies(text string) int {
+	normalized := strings.ToLower(strings.TrimSpace(normalizeTweetTextRegEx.ReplaceAllString(text, " ")))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.recent = append(s.recent, normalized)
+	s.recentTexts[normalized]++
+	if len(s.recent) > spamRecentTextsWindow {
+		oldest := s.recent[0]
+		s.recent = s.recent[1:]
+		if s.recentTexts[oldest]--; s.recentTexts[oldest] == 0 {
+			delete(s.recentTexts, oldest)
+		}
+	}
+	return s.recentTexts[normalized]
+}
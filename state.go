@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"go.uber.org/zap"
+)
+
+// harvestStateFile is the harvest's state, in the storage directory
+const harvestStateFile = "state.json"
+
+// harvestedTweetsRetention is how long harvested tweets are remembered, as far back as the
+// standard search, and so a backfill, goes
+const harvestedTweetsRetention = 7 * 24 * time.Hour
+
+// harvestStateSaveInterval is how often the state is saved while it changes
+const harvestStateSaveInterval = 10 * time.Second
+
+// HarvestState is where the harvest left off, kept in state.json so a restart resumes there:
+// the newest tweet each search query found, which later searches only ask for tweets after,
+// the stream's last tweet and the track rules it ended with, changes made while it ran included,
+// and the tweets harvested lately, which backfills leave out
+type HarvestState struct {
+	path   string
+	logger *zap.Logger
+	mutex  sync.Mutex
+	dirty  bool
+	// harvestedSorted tells whether HarvestedTweets is still in order since tweets were added
+	harvestedSorted bool
+
+	SinceIDs map[string]int64 `json:"sinceIDs,omitempty"`
+	// StreamQuery is the -query the stream started with, StreamTrack what it tracked last
+	StreamQuery     []string  `json:"streamQuery,omitempty"`
+	StreamTrack     []string  `json:"streamTrack,omitempty"`
+	LastTweetID     int64     `json:"lastTweetID,omitempty"`
+	LastTweetAt     time.Time `json:"lastTweetAt"`
+	LastTweetSource string    `json:"lastTweetSource,omitempty"`
+	// HarvestedTweets are the IDs of the tweets harvested, or screened out, that were posted in
+	// the last harvestedTweetsRetention, in order; IDs tell when their tweet was posted
+	HarvestedTweets []int64 `json:"harvestedTweetIDs,omitempty"`
+}
+
+// LoadHarvestState reads the state in storageBasePath, starting afresh if there's none yet
+func LoadHarvestState(storageBasePath string, logger *zap.Logger) (*HarvestState, error) {
+	result := new(HarvestState)
+	result.path = filepath.Join(storageBasePath, harvestStateFile)
+	result.logger = logger
+	data, err := ioutil.ReadFile(result.path)
+	if os.IsNotExist(err) {
+		result.SinceIDs = make(map[string]int64)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	if result.SinceIDs == nil {
+		result.SinceIDs = make(map[string]int64)
+	}
+	result.sortHarvestedTweets()
+	return result, nil
+}
+
+// SinceID returns the newest tweet query found, 0 if it never ran
+func (s *HarvestState) SinceID(query string) int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.SinceIDs[query]
+}
+
+// SetSinceID records id as the newest tweet query found
+func (s *HarvestState) SetSinceID(query string, id int64) {
+	s.mutex.Lock()
+	if id > s.SinceIDs[query] {
+		s.SinceIDs[query] = id
+		s.dirty = true
+	}
+	s.mutex.Unlock()
+}
+
+// ResumeTrack returns the track rules the stream last had, if it was started with the same
+// query it is now; given a different -query, the stream starts over with it
+func (s *HarvestState) ResumeTrack(query []string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.StreamTrack) == 0 || strings.Join(s.StreamQuery, ",") != strings.Join(query, ",") {
+		return query
+	}
+	return append([]string(nil), s.StreamTrack...)
+}
+
+// SetStreamTrack records the track rules a stream started with query now has
+func (s *HarvestState) SetStreamTrack(query []string, track []string) {
+	s.mutex.Lock()
+	s.StreamQuery = append([]string(nil), query...)
+	s.StreamTrack = append([]string(nil), track...)
+	s.dirty = true
+	s.mutex.Unlock()
+	// track changes are rare and made by hand, so they're saved right away
+	if err := s.Save(); err != nil {
+		s.logger.Error("Unable to save harvest state", zap.String("path", s.path), zap.Error(err))
+	}
+}
+
+// TweetReceived records the last tweet a stream (source, e.g. "filter-stream") received
+func (s *HarvestState) TweetReceived(source string, id int64) {
+	s.mutex.Lock()
+	s.LastTweetID = id
+	s.LastTweetAt = time.Now()
+	s.LastTweetSource = source
+	s.dirty = true
+	s.mutex.Unlock()
+}
+
+// LastTweet returns the last tweet source received and when, and false if it didn't yet
+func (s *HarvestState) LastTweet(source string) (int64, time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.LastTweetSource != source || s.LastTweetID == 0 {
+		return 0, time.Time{}, false
+	}
+	return s.LastTweetID, s.LastTweetAt, true
+}
+
+// TweetHarvested records that the tweet with id was harvested, or screened out, so backfills
+// leave it out
+func (s *HarvestState) TweetHarvested(id int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if n := len(s.HarvestedTweets); n > 0 && id <= s.HarvestedTweets[n-1] {
+		// backfills and searches go back in time, the stream doesn't
+		s.harvestedSorted = false
+	}
+	s.HarvestedTweets = append(s.HarvestedTweets, id)
+	s.dirty = true
+}
+
+// HarvestedTweetIDs returns the IDs of the tweets harvested that were posted since then
+func (s *HarvestState) HarvestedTweetIDs(since time.Time) map[int64]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sortHarvestedTweets()
+	result := make(map[int64]bool)
+	for i := len(s.HarvestedTweets) - 1; i >= 0 && !twitter.PostedAt(s.HarvestedTweets[i]).Before(since); i-- {
+		result[s.HarvestedTweets[i]] = true
+	}
+	return result
+}
+
+// sortHarvestedTweets puts HarvestedTweets back in order and drops repeats, with s.mutex held
+func (s *HarvestState) sortHarvestedTweets() {
+	if s.harvestedSorted {
+		return
+	}
+	ids := s.HarvestedTweets
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	unique := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	s.HarvestedTweets = unique
+	s.harvestedSorted = true
+}
+
+// RememberHarvestedIn makes the harvester record every tweet it harvests or screens out in state
+func (h *TweetHarvester) RememberHarvestedIn(state *HarvestState) {
+	h.state = state
+}
+
+// SaveEvery saves the state every interval while it changes, until ctx is done; the harvest
+// itself only records changes in memory
+func (s *HarvestState) SaveEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				s.logger.Error("Unable to save harvest state", zap.String("path", s.path), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Save writes the state out if anything changed since it was last saved
+func (s *HarvestState) Save() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	s.sortHarvestedTweets()
+	cutoff := time.Now().Add(-harvestedTweetsRetention)
+	expired := sort.Search(len(s.HarvestedTweets), func(i int) bool {
+		return !twitter.PostedAt(s.HarvestedTweets[i]).Before(cutoff)
+	})
+	if expired > 0 {
+		s.HarvestedTweets = append([]int64(nil), s.HarvestedTweets[expired:]...)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
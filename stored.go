@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// StoredDocument is a resource's document read back from the store, for the feeds and reports
+// built over it
+type StoredDocument struct {
+	Key         string
+	HarvestedAt time.Time
+	FrontMatter map[string]interface{}
+	Body        string
+}
+
+// Field returns a front matter field as a string, or empty if it's missing or not a string
+func (document *StoredDocument) Field(name string) string {
+	value, _ := document.FrontMatter[name].(string)
+	return value
+}
+
+// Fields returns a front matter list field as strings
+func (document *StoredDocument) Fields(name string) []string {
+	if values, ok := document.FrontMatter[name].([]string); ok {
+		return values
+	}
+	values, _ := document.FrontMatter[name].([]interface{})
+	var result []string
+	for _, value := range values {
+		if text, ok := value.(string); ok {
+			result = append(result, text)
+		}
+	}
+	return result
+}
+
+// URL is the best link we have for the resource: its canonical URL if one was found, else the
+// cleaned one
+func (document *StoredDocument) URL() string {
+	for _, field := range []string{"canonicalURL", "cleanedURL", "finalURL"} {
+		if url := document.Field(field); url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// Title is the page title found by -enrich-metadata or, without one, the resource's URL
+func (document *StoredDocument) Title() string {
+	if title := document.Field("title"); title != "" {
+		return title
+	}
+	return document.URL()
+}
+
+// IsPaywalled is true if -detect-paywalls found the destination behind a paywall
+func (document *StoredDocument) IsPaywalled() bool {
+	paywalled, _ := document.FrontMatter["isPaywalled"].(bool)
+	return paywalled
+}
+
+// newStoredDocument is the StoredDocument for a resource that's just been stored, so the same
+// code can handle resources read back from the store and newly harvested ones
+func newStoredDocument(resource *DocumentTemplateData, document string) *StoredDocument {
+	_, body, _ := splitFrontMatter(document)
+	return &StoredDocument{Key: resource.Slug, HarvestedAt: resource.HarvestedAt, FrontMatter: resource.FrontMatter, Body: body}
+}
+
+// StoredDocument finds the document stored under key
+func (storage *HarvestedResourceStorage) StoredDocument(key string) (*StoredDocument, error) {
+	document, err := storage.ReadDocument(key)
+	if err != nil {
+		return nil, err
+	}
+	return storage.parseStoredDocument(key, document)
+}
+
+// parseStoredDocument dates documents harvested before harvestedAt was recorded by their
+// file's modification time
+func (storage *HarvestedResourceStorage) parseStoredDocument(key string, document string) (*StoredDocument, error) {
+	fields, body, err := readFrontMatter(document)
+	if err != nil {
+		return nil, err
+	}
+	result := &StoredDocument{Key: key, FrontMatter: fields, Body: body}
+	if result.HarvestedAt, err = time.Parse(time.RFC3339, result.Field("harvestedAt")); err != nil {
+		if info, err := os.Stat(storage.keyPath(key)); err == nil {
+			result.HarvestedAt = info.ModTime()
+		}
+	}
+	return result, nil
+}
+
+// StoredDocuments reads back the documents of all the resources in the store (in the default
+// diskv layout, not the ones written in an export format), most recently harvested first
+func (storage *HarvestedResourceStorage) StoredDocuments() []*StoredDocument {
+	var documents []*StoredDocument
+	for key := range storage.diskv.Keys(nil) {
+		if !isDocumentKey(key) || strings.HasPrefix(key, tweetDocumentKeyPrefix) || strings.HasPrefix(key, urlErrorKeyPrefix) {
+			continue
+		}
+		data, err := storage.diskv.Read(key)
+		if err != nil {
+			storage.logger.Error("Unable to read document", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		document, err := storage.parseStoredDocument(key, string(data))
+		if err != nil {
+			storage.logger.Error("Unreadable front matter", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		documents = append(documents, document)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].HarvestedAt.After(documents[j].HarvestedAt)
+	})
+	return documents
+}
+
+// StoredDocumentFilter selects stored documents for the serve mode's APIs; empty fields match
+// everything
+type StoredDocumentFilter struct {
+	// Text is looked for, case insensitively, in the title, URL and body
+	Text    string
+	Domain  string
+	User    string
+	Hashtag string
+	Since   time.Time
+	Limit   int
+}
+
+// Matches tells whether document passes the filter
+func (filter *StoredDocumentFilter) Matches(document *StoredDocument) bool {
+	if filter.Text != "" {
+		text := strings.ToLower(filter.Text)
+		if !strings.Contains(strings.ToLower(document.Title()+" "+document.URL()+" "+document.Body), text) {
+			return false
+		}
+	}
+	if filter.Domain != "" && !strings.EqualFold(destinationDomain(document.Field("finalURL")), filter.Domain) {
+		return false
+	}
+	if filter.User != "" && !strings.EqualFold(document.Field("user"), strings.TrimPrefix(filter.User, "@")) {
+		return false
+	}
+	if filter.Hashtag != "" {
+		found := false
+		for _, hashtag := range document.Fields("hashtags") {
+			found = found || strings.EqualFold(hashtag, strings.TrimPrefix(filter.Hashtag, "#"))
+		}
+		if !found {
+			return false
+		}
+	}
+	return filter.Since.IsZero() || !document.HarvestedAt.Before(filter.Since)
+}
+
+// FindStoredDocuments returns the stored documents passing filter, most recent first
+func (storage *HarvestedResourceStorage) FindStoredDocuments(filter *StoredDocumentFilter) []*StoredDocument {
+	var result []*StoredDocument
+	for _, document := range storage.StoredDocuments() {
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+		if filter.Matches(document) {
+			result = append(result, document)
+		}
+	}
+	return result
+}
+
+// keyPath is where key is stored under the storage's base path
+func (storage *HarvestedResourceStorage) keyPath(key string) string {
+	if storage.layout == nil {
+		return filepath.Join(storage.basePath, key)
+	}
+	return filepath.Join(append(append([]string{storage.basePath}, storage.layout.Transform(key)...), key)...)
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/shah/content-harvester-twitter/pkg/filter"
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"go.uber.org/zap"
+)
+
+// usersLookupBatchSize is the most screen names Twitter resolves in one users/lookup call
+const usersLookupBatchSize = 100
+
+// resolveUserIDs turns a mix of user IDs and screen names (with or without @) into user IDs,
+// which is what the streaming API's follow parameter expects
+func resolveUserIDs(ctx context.Context, scheduler *twitter.RateLimitScheduler, users []string) ([]string, error) {
+	var result []string
+	var screenNames []string
+	for _, user := range users {
+		if _, err := strconv.ParseInt(user, 10, 64); err == nil {
+			result = append(result, user)
+			continue
+		}
+		screenNames = append(screenNames, strings.TrimPrefix(user, "@"))
+	}
+
+	for start := 0; start < len(screenNames); start += usersLookupBatchSize {
+		end := start + usersLookupBatchSize
+		if end > len(screenNames) {
+			end = len(screenNames)
+		}
+
+		var found []anaconda.User
+		err := scheduler.Do(ctx, "users", "/users/lookup", func(api *anaconda.TwitterApi) error {
+			var lookupErr error
+			found, lookupErr = api.GetUsersLookup(strings.Join(screenNames[start:end], ","), nil)
+			return lookupErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range found {
+			result = append(result, user.IdStr)
+		}
+	}
+	return result, nil
+}
+
+// FilterStream is a Source of the tweets of a filter stream whose track queries can change
+// while it runs; changing them reconnects the stream with the new set
+type FilterStream struct {
+	api     *anaconda.TwitterApi
+	logger  *zap.Logger
+	params  url.Values
+	mutex   sync.Mutex
+	track   []string
+	restart chan struct{}
+	status  StreamStatus
+	query   []string
+	state   *HarvestState
+
+	backfill    *StreamBackfill
+	backfillGap time.Duration
+	backfills   sync.WaitGroup
+	// the last tweet received, and whether the stream was disconnected since
+	lastTweetID  int64
+	lastTweetAt  time.Time
+	disconnected bool
+}
+
+// StreamStatus is how the filter stream is doing, for health checks
+type StreamStatus struct {
+	// Connected is true once something arrived on the current connection
+	Connected   bool      `json:"connected"`
+	StartedAt   time.Time `json:"startedAt"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	LastTweetAt time.Time `json:"lastTweetAt"`
+	// LastMessageAt is when anything (a tweet, or a notice like a stall warning) last arrived
+	LastMessageAt time.Time `json:"lastMessageAt"`
+	Reconnects    int       `json:"reconnects"`
+}
+
+// NewFilterStream prepares a stream tracking the track queries, with params for the other
+// filters (follow, language, locations)
+func NewFilterStream(api *anaconda.TwitterApi, logger *zap.Logger, track []string, params url.Values) *FilterStream {
+	result := new(FilterStream)
+	result.api = api
+	result.logger = logger
+	result.params = params
+	result.track = track
+	result.query = track
+	result.restart = make(chan struct{}, 1)
+	return result
+}
+
+// ResumeFrom makes the stream track the rules state says it last had, when it was started with
+// the same queries, and keep state up to date with its rules and the last tweet it received
+func (f *FilterStream) ResumeFrom(state *HarvestState) {
+	f.mutex.Lock()
+	f.track = state.ResumeTrack(f.query)
+	f.state = state
+	track := f.track
+	f.mutex.Unlock()
+	state.SetStreamTrack(f.query, track)
+}
+
+// BackfillWith makes the stream search for the tweets it missed with backfill, after it was
+// disconnected, after a restart the state it resumes from tells of, and after going gap
+// without tweets (0 to only count disconnections), which anaconda reconnects from unseen
+func (f *FilterStream) BackfillWith(backfill *StreamBackfill, gap time.Duration) {
+	f.backfill = backfill
+	f.backfillGap = gap
+}
+
+// Track returns the queries currently tracked
+func (f *FilterStream) Track() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.track...)
+}
+
+// SetTrack replaces the tracked queries and reconnects the stream
+func (f *FilterStream) SetTrack(track []string) {
+	f.mutex.Lock()
+	f.track = append([]string(nil), track...)
+	state := f.state
+	f.mutex.Unlock()
+	if state != nil {
+		state.SetStreamTrack(f.query, track)
+	}
+
+	select {
+	case f.restart <- struct{}{}:
+	default:
+		// a reconnection is already pending and will pick up the new queries
+	}
+}
+
+// AddTrack starts tracking query too, unless it already is
+func (f *FilterStream) AddTrack(query string) {
+	track := f.Track()
+	for _, tracked := range track {
+		if tracked == query {
+			return
+		}
+	}
+	f.SetTrack(append(track, query))
+}
+
+// RemoveTrack stops tracking query and returns whether it was tracked
+func (f *FilterStream) RemoveTrack(query string) bool {
+	track := f.Track()
+	for i, tracked := range track {
+		if tracked == query {
+			f.SetTrack(append(track[:i], track[i+1:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// Status returns how the stream is doing
+func (f *FilterStream) Status() StreamStatus {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.status
+}
+
+func (f *FilterStream) values() url.Values {
+	v := url.Values{}
+	for name, values := range f.params {
+		v[name] = values
+	}
+	if track := f.Track(); len(track) > 0 {
+		v.Set("track", strings.Join(track, ","))
+	}
+	return v
+}
+
+// Start implements Source, streaming tweets until ctx is done
+func (f *FilterStream) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		if f.backfill != nil && f.state != nil {
+			// the harvester not running was a disconnection too
+			if id, at, found := f.state.LastTweet("filter-stream"); found {
+				f.lastTweetID, f.lastTweetAt, f.disconnected = id, at, true
+			}
+		}
+		f.run(ctx, items)
+		f.backfills.Wait()
+	}()
+	return items
+}
+
+// streamBackoff spaces out reconnecting a stream that keeps getting disconnected: a second at
+// first, doubling up to a minute, and back to a second once a connection lasted a minute
+type streamBackoff struct {
+	delay       time.Duration
+	connectedAt time.Time
+}
+
+// connecting notes that the stream is (re)connecting now
+func (b *streamBackoff) connecting() {
+	b.connectedAt = time.Now()
+}
+
+// reconnect logs message and waits out the backoff before the stream reconnects, returning
+// false if ctx is done first
+func (b *streamBackoff) reconnect(ctx context.Context, logger *zap.Logger, message string) bool {
+	if b.delay == 0 || time.Since(b.connectedAt) > time.Minute {
+		b.delay = time.Second
+	}
+	logger.Warn(message, zap.Duration("backoff", b.delay))
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return false
+	}
+	if b.delay *= 2; b.delay > time.Minute {
+		b.delay = time.Minute
+	}
+	return true
+}
+
+// streamEnd is why receive returned
+type streamEnd int
+
+const (
+	// streamStopped is ctx being done
+	streamStopped streamEnd = iota
+	// streamRestarted is the tracked queries changing, which reconnects right away
+	streamRestarted
+	// streamDisconnected is the connection being lost, which reconnects after a backoff
+	streamDisconnected
+)
+
+func (f *FilterStream) run(ctx context.Context, items chan<- Item) {
+	var backoff streamBackoff
+	for connections := 0; ; connections++ {
+		if connections > 0 {
+			streamReconnectsCounter.Inc()
+		}
+		f.mutex.Lock()
+		if connections == 0 {
+			f.status.StartedAt = time.Now()
+		}
+		f.status.Connected = false
+		f.status.ConnectedAt = time.Now()
+		f.status.Reconnects = connections
+		f.mutex.Unlock()
+		v := f.values()
+		f.logger.Info("Connecting to Twitter Stream", zap.String("track", v.Get("track")))
+		backoff.connecting()
+		s := f.api.PublicStreamFilter(v)
+		end := f.receive(ctx, s, items)
+		s.Stop()
+		if end == streamDisconnected && !backoff.reconnect(ctx, f.logger, "Twitter Stream disconnected, reconnecting") {
+			end = streamStopped
+		}
+		if end == streamStopped {
+			f.logger.Info("Stopped Twitter Stream", zap.String("track", v.Get("track")))
+			return
+		}
+	}
+}
+
+// receive returns when the tracked queries change, the stream is disconnected or ctx is done
+func (f *FilterStream) receive(ctx context.Context, s *anaconda.Stream, items chan<- Item) streamEnd {
+	for {
+		select {
+		case t, ok := <-s.C:
+			if !ok {
+				f.mutex.Lock()
+				f.status.Connected = false
+				f.mutex.Unlock()
+				f.disconnected = true
+				return streamDisconnected
+			}
+			f.mutex.Lock()
+			// anaconda reconnects by itself after a disconnect message, the next message
+			// tells it did
+			_, isDisconnect := t.(anaconda.DisconnectMessage)
+			f.status.Connected = !isDisconnect
+			if !isDisconnect {
+				f.status.LastMessageAt = time.Now()
+			}
+			if _, isTweet := t.(anaconda.Tweet); isTweet {
+				f.status.LastTweetAt = time.Now()
+			}
+			f.mutex.Unlock()
+			switch v := t.(type) {
+			case anaconda.DisconnectMessage:
+				f.logger.Warn("Twitter Stream disconnected")
+				f.disconnected = true
+			case anaconda.Tweet:
+				f.noticeGap(ctx, &v, items)
+				//createTweetTestData(contentHarvester, csvWriter, v.Text)
+				select {
+				case items <- Item{Tweet: &v}:
+				case <-ctx.Done():
+					return streamStopped
+				}
+				if f.state != nil {
+					f.state.TweetReceived("filter-stream", v.Id)
+				}
+			}
+		case <-f.restart:
+			return streamRestarted
+		case <-ctx.Done():
+			return streamStopped
+		}
+	}
+}
+
+// noticeGap starts backfilling when tweet is the first one after a disconnection, or after a
+// silence long enough to have been one
+func (f *FilterStream) noticeGap(ctx context.Context, tweet *anaconda.Tweet, items chan<- Item) {
+	gap := f.disconnected || (f.backfillGap > 0 && time.Since(f.lastTweetAt) > f.backfillGap)
+	if f.backfill != nil && f.lastTweetID > 0 && gap {
+		track, sinceID, from := f.Track(), f.lastTweetID, f.lastTweetAt
+		f.backfills.Add(1)
+		go func() {
+			defer f.backfills.Done()
+			f.backfill.Backfill(ctx, track, sinceID, tweet.Id, from, items)
+		}()
+	}
+	f.disconnected = false
+	if tweet.Id > f.lastTweetID {
+		f.lastTweetID = tweet.Id
+	}
+	f.lastTweetAt = time.Now()
+}
+
+// SampleStream is a Source of the tweets in Twitter's sampled stream, a small random share of
+// all tweets, that mention one of the keywords and link to one of the domains; the sample has
+// no filters of its own, so both are matched here
+type SampleStream struct {
+	api      *anaconda.TwitterApi
+	logger   *zap.Logger
+	keywords [][]string
+	domains  filter.DomainList
+}
+
+// NewSampleStream prepares a sampled stream keeping the tweets that mention one of keywords,
+// given like the filter stream's track queries (all the words of one of them), and link to
+// one of domains; without keywords or domains every tweet is kept
+func NewSampleStream(api *anaconda.TwitterApi, logger *zap.Logger, keywords []string, domains filter.DomainList) *SampleStream {
+	result := new(SampleStream)
+	result.api = api
+	result.logger = logger
+	for _, keyword := range keywords {
+		if words := strings.Fields(strings.ToLower(keyword)); len(words) > 0 {
+			result.keywords = append(result.keywords, words)
+		}
+	}
+	result.domains = domains
+	return result
+}
+
+// Start implements Source, streaming tweets until ctx is done
+func (s *SampleStream) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		var backoff streamBackoff
+		for connections := 0; ; connections++ {
+			if connections > 0 {
+				streamReconnectsCounter.Inc()
+			}
+			s.logger.Info("Connecting to Twitter sample stream")
+			backoff.connecting()
+			stream := s.api.PublicStreamSample(url.Values{})
+			s.receive(ctx, stream, items)
+			stream.Stop()
+			if ctx.Err() != nil || !backoff.reconnect(ctx, s.logger, "Twitter sample stream disconnected, reconnecting") {
+				s.logger.Info("Stopped Twitter sample stream")
+				return
+			}
+		}
+	}()
+	return items
+}
+
+// receive returns when the stream or ctx is done
+func (s *SampleStream) receive(ctx context.Context, stream *anaconda.Stream, items chan<- Item) {
+	for {
+		select {
+		case t, ok := <-stream.C:
+			if !ok {
+				return
+			}
+			tweet, isTweet := t.(anaconda.Tweet)
+			if !isTweet || !s.matches(&tweet) {
+				continue
+			}
+			select {
+			case items <- Item{Tweet: &tweet, Provenance: &Provenance{Source: "twitter-sample"}}:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// matches is true if the tweet, or the one it retweets or quotes, mentions one of the keywords
+// and links to one of the domains
+func (s *SampleStream) matches(tweet *anaconda.Tweet) bool {
+	var texts []string
+	var hosts []string
+	for _, t := range []*anaconda.Tweet{tweet, tweet.RetweetedStatus, tweet.QuotedStatus} {
+		if t == nil {
+			continue
+		}
+		texts = append(texts, t.Text, t.FullText, t.ExtendedTweet.FullText)
+		for _, entities := range []anaconda.Entities{t.Entities, t.ExtendedTweet.Entities} {
+			for _, link := range entities.Urls {
+				texts = append(texts, link.Expanded_url)
+				if u, err := url.Parse(link.Expanded_url); err == nil {
+					hosts = append(hosts, strings.TrimPrefix(u.Hostname(), "www."))
+				}
+			}
+		}
+	}
+
+	if len(s.domains) > 0 {
+		linked := false
+		for _, host := range hosts {
+			if s.domains.Matches(host) {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			return false
+		}
+	}
+	if len(s.keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(strings.Join(texts, " "))
+	for _, words := range s.keywords {
+		mentioned := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				mentioned = false
+				break
+			}
+		}
+		if mentioned {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// HarvestSummary counts what happened to the tweets and URLs of a run, to tell how well the
+// filters and rules work
+type HarvestSummary struct {
+	mutex         sync.Mutex
+	tweets        int
+	ignoredTweets map[string]int
+	urls          int
+	saved         int
+	ignored       map[string]int
+	invalid       map[string]int
+	domains       map[string]int
+}
+
+// NewHarvestSummary starts an empty summary
+func NewHarvestSummary() *HarvestSummary {
+	result := new(HarvestSummary)
+	result.ignoredTweets = make(map[string]int)
+	result.ignored = make(map[string]int)
+	result.invalid = make(map[string]int)
+	result.domains = make(map[string]int)
+	return result
+}
+
+// SummarizeTo counts the tweets harvested, and the resources stored and ignored, in summary
+func (h *TweetHarvester) SummarizeTo(summary *HarvestSummary) {
+	h.summary = summary
+	h.storage.summary = summary
+}
+
+func (s *HarvestSummary) tweetHarvested() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tweets++
+}
+
+func (s *HarvestSummary) tweetIgnored(reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ignoredTweets[reason]++
+}
+
+func (s *HarvestSummary) urlsFound(count int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.urls += count
+}
+
+func (s *HarvestSummary) resourceSaved(finalURL string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.saved++
+	s.domains[destinationDomain(finalURL)]++
+}
+
+func (s *HarvestSummary) resourceIgnored(outcome string, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if outcome == "invalid" {
+		s.invalid[reason]++
+		return
+	}
+	s.ignored[reason]++
+}
+
+// Print writes the summary as a human readable report
+func (s *HarvestSummary) Print(out io.Writer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fmt.Fprintf(out, "Tweets processed: %d\n", s.tweets)
+	printSummaryCounts(out, "Tweets ignored", s.ignoredTweets)
+	fmt.Fprintf(out, "URLs found: %d\n", s.urls)
+	fmt.Fprintf(out, "Saved: %d\n", s.saved)
+	printSummaryCounts(out, "Ignored", s.ignored)
+	printSummaryCounts(out, "Invalid", s.invalid)
+	printSummaryCounts(out, "Saved by domain", s.domains)
+}
+
+// Log records the summary as a single message
+func (s *HarvestSummary) Log(logger *zap.Logger) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	logger.Info("Harvest summary",
+		zap.Int("tweets", s.tweets),
+		zap.Any("ignoredTweets", s.ignoredTweets),
+		zap.Int("urls", s.urls),
+		zap.Int("saved", s.saved),
+		zap.Any("ignored", s.ignored),
+		zap.Any("invalid", s.invalid),
+		zap.Any("domains", s.domains),
+	)
+}
+
+func printSummaryCounts(out io.Writer, heading string, counts map[string]int) {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	fmt.Fprintf(out, "%s: %d\n", heading, total)
+
+	// most frequent first, alphabetically for ties
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		fmt.Fprintf(out, "  %6d  %s\n", counts[name], name)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// telegramPollTimeout is how long a getUpdates long poll waits for a post
+const telegramPollTimeout = 50 * time.Second
+
+type telegramEntity struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type telegramMessage struct {
+	MessageID int `json:"message_id"`
+	Chat      struct {
+		Username string `json:"username"`
+	} `json:"chat"`
+	AuthorSignature string           `json:"author_signature"`
+	Text            string           `json:"text"`
+	Caption         string           `json:"caption"`
+	Entities        []telegramEntity `json:"entities"`
+	CaptionEntities []telegramEntity `json:"caption_entities"`
+}
+
+type telegramUpdates struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      []struct {
+		UpdateID    int              `json:"update_id"`
+		ChannelPost *telegramMessage `json:"channel_post"`
+	} `json:"result"`
+}
+
+// TelegramChannels is a Source of the posts in public Telegram channels, as a bot receives
+// them from the Bot API; the bot has to be added to each channel (as an administrator) for
+// Telegram to send it the channel's posts
+type TelegramChannels struct {
+	token    string
+	channels map[string]bool
+	logger   *zap.Logger
+	client   *http.Client
+	offset   int
+}
+
+// NewTelegramChannels receives, with the bot token, the posts in channels (@username)
+func NewTelegramChannels(token string, channels []string, logger *zap.Logger) *TelegramChannels {
+	result := new(TelegramChannels)
+	result.token = token
+	result.channels = make(map[string]bool)
+	for _, channel := range channels {
+		result.channels[strings.ToLower(strings.TrimPrefix(channel, "@"))] = true
+	}
+	result.logger = logger
+	result.client = &http.Client{Timeout: telegramPollTimeout + 10*time.Second}
+	return result
+}
+
+// Start implements Source, receiving posts until ctx is done
+func (t *TelegramChannels) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		backoff := time.Second
+		for {
+			err := t.receive(ctx, items)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				backoff = time.Second
+				continue
+			}
+			t.logger.Warn("Unable to get Telegram updates", zap.Duration("backoff", backoff), zap.Error(err))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > time.Minute {
+				backoff = time.Minute
+			}
+		}
+	}()
+	return items
+}
+
+// receive long polls for the next updates, sending the posts of the channels on
+func (t *TelegramChannels) receive(ctx context.Context, items chan<- Item) error {
+	v := url.Values{}
+	v.Set("timeout", strconv.Itoa(int(telegramPollTimeout.Seconds())))
+	v.Set("allowed_updates", `["channel_post"]`)
+	if t.offset > 0 {
+		v.Set("offset", strconv.Itoa(t.offset))
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://api.telegram.org/bot"+t.token+"/getUpdates?"+v.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		// the error has the URL, and with it the token, in it
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var updates telegramUpdates
+	if err := json.NewDecoder(resp.Body).Decode(&updates); err != nil {
+		return err
+	}
+	if !updates.OK {
+		return fmt.Errorf("getUpdates: %s", updates.Description)
+	}
+
+	for _, update := range updates.Result {
+		t.offset = update.UpdateID + 1
+		post := update.ChannelPost
+		if post == nil || !t.channels[strings.ToLower(post.Chat.Username)] {
+			continue
+		}
+		select {
+		case items <- telegramItem(post):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// telegramItem harvests the post's text or caption, with the links hidden behind their text
+// spelled out
+func telegramItem(post *telegramMessage) Item {
+	text, entities := post.Text, post.Entities
+	if text == "" {
+		text, entities = post.Caption, post.CaptionEntities
+	}
+	for _, entity := range entities {
+		if entity.Type == "text_link" && entity.URL != "" {
+			text += " " + entity.URL
+		}
+	}
+	provenance := &Provenance{Source: "telegram", Channel: "@" + post.Chat.Username, Author: post.AuthorSignature,
+		PostURL: "https://t.me/" + post.Chat.Username + "/" + strconv.Itoa(post.MessageID)}
+	return Item{Text: text, Provenance: provenance}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+	"github.com/shah/content-harvester-twitter/pkg/sources/twitter"
+	"go.uber.org/zap"
+)
+
+// timelinePageSize is the largest page the timeline endpoints hand out
+const timelinePageSize = 200
+
+// timelinePage fetches one page of a timeline with api using the given paging parameters
+type timelinePage func(api *anaconda.TwitterApi, v url.Values) ([]anaconda.Tweet, error)
+
+// walkTimeline pages backwards through a timeline, newest tweets first, passing each tweet to
+// harvest until the API has no older tweets to give
+func walkTimeline(ctx context.Context, scheduler *twitter.RateLimitScheduler, family string, endpoint string, page timelinePage, harvest func(tweet anaconda.Tweet)) error {
+	var maxID int64
+	for {
+		v := url.Values{}
+		v.Set("count", strconv.Itoa(timelinePageSize))
+		if maxID > 0 {
+			v.Set("max_id", strconv.FormatInt(maxID-1, 10))
+		}
+
+		var tweets []anaconda.Tweet
+		err := scheduler.Do(ctx, family, endpoint, func(api *anaconda.TwitterApi) error {
+			var pageErr error
+			tweets, pageErr = page(api, v)
+			return pageErr
+		})
+		if err != nil {
+			return err
+		}
+		if len(tweets) == 0 {
+			return nil
+		}
+
+		for _, tweet := range tweets {
+			if maxID == 0 || tweet.Id < maxID {
+				maxID = tweet.Id
+			}
+			harvest(tweet)
+		}
+	}
+}
+
+// HarvestUserTimeline stores every link shared in a user's timeline, as far back as Twitter allows
+func HarvestUserTimeline(ctx context.Context, scheduler *twitter.RateLimitScheduler, tweets *TweetHarvester, logger *zap.Logger, user string) {
+	screenName := strings.TrimPrefix(user, "@")
+	provenance := &Provenance{Timeline: "@" + screenName}
+	page := func(api *anaconda.TwitterApi, v url.Values) ([]anaconda.Tweet, error) {
+		v.Set("screen_name", screenName)
+		v.Set("include_rts", "true")
+		return api.GetUserTimeline(v)
+	}
+
+	err := walkTimeline(ctx, scheduler, "statuses", "/statuses/user_timeline", page, func(tweet anaconda.Tweet) {
+		tweets.Harvest(tweet, provenance)
+	})
+	if err != nil {
+		logger.Error("Unable to read user timeline", zap.String("timeline", provenance.Timeline), zap.Error(err))
+	}
+}
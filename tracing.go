@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracingServiceName = "content-harvester-twitter"
+
+// tracer records the harvest pipeline's spans; until StartTracing installs a provider the
+// global one is a no-op so the spans cost next to nothing
+var tracer = otel.Tracer("github.com/shah/content-harvester-twitter")
+
+// StartTracing exports spans over OTLP/HTTP to the collector at endpoint (e.g.
+// http://localhost:4318), sampling ratio of the traces; the returned function flushes whatever
+// hasn't been sent yet
+func StartTracing(endpoint string, ratio float64) (func(context.Context) error, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%s isn't an http or https URL", endpoint)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(NewOTLPHTTPExporter(endpoint)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", tracingServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	tracer = provider.Tracer("github.com/shah/content-harvester-twitter")
+	return provider.Shutdown, nil
+}
+
+// startSpan starts a child of the span in ctx, if any
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan ends span, marking it as failed when err isn't nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// urlHostAttribute is recorded instead of the full URL so that spans are cheap to group by destination
+func urlHostAttribute(name string, u *url.URL) attribute.KeyValue {
+	if u == nil {
+		return attribute.String(name, "")
+	}
+	return attribute.String(name, u.Hostname())
+}
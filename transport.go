@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// configureDefaultTransport sends the requests made with net/http's default transport, which is
+// what URL resolution and page fetching use, through proxy (http, https or socks5 URL) and
+// with userAgent; either may be empty
+func configureDefaultTransport(proxy string, userAgent string) error {
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return err
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", proxyURL.Scheme)
+		}
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("can't set a proxy on %T", http.DefaultTransport)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if userAgent != "" {
+		http.DefaultTransport = &userAgentTransport{next: http.DefaultTransport, userAgent: userAgent}
+	}
+	return nil
+}
+
+// userAgentTransport identifies the requests that don't say who they're from
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		// a RoundTripper mustn't change the request it's given
+		identified := *req
+		identified.Header = make(http.Header, len(req.Header)+1)
+		for name, values := range req.Header {
+			identified.Header[name] = values
+		}
+		identified.Header.Set("User-Agent", t.userAgent)
+		req = &identified
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ChimeraCoder/anaconda"
+	"go.uber.org/zap"
+)
+
+// TwitterArchive is a Source of the tweets in a Twitter account archive, as downloaded from
+// Twitter's settings: the zip, the directory it unzips to or one of its tweets.js files
+type TwitterArchive struct {
+	path   string
+	logger *zap.Logger
+}
+
+// NewTwitterArchive reads the archive at path
+func NewTwitterArchive(path string, logger *zap.Logger) *TwitterArchive {
+	result := new(TwitterArchive)
+	result.path = path
+	result.logger = logger
+	return result
+}
+
+// archiveTweetsFile is true for the archive's files of tweets: data/tweets.js, its parts
+// (tweets-part1.js, ...) and, in older archives, data/tweet.js
+func archiveTweetsFile(name string) bool {
+	base := path.Base(filepath.ToSlash(name))
+	return base == "tweet.js" || base == "tweets.js" || (strings.HasPrefix(base, "tweets-part") && strings.HasSuffix(base, ".js"))
+}
+
+// archiveFiles returns the contents of the archive's tweets files and of its account.js
+func (a *TwitterArchive) archiveFiles() (tweets [][]byte, account []byte, err error) {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	read := func(name string) ([]byte, error) { return ioutil.ReadFile(name) }
+	switch {
+	case info.IsDir():
+		names, err = filepath.Glob(filepath.Join(a.path, "data", "*.js"))
+		if err != nil {
+			return nil, nil, err
+		}
+	case strings.HasSuffix(strings.ToLower(a.path), ".zip"):
+		archive, err := zip.OpenReader(a.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer archive.Close()
+		files := make(map[string]*zip.File)
+		for _, file := range archive.File {
+			files[file.Name] = file
+			names = append(names, file.Name)
+		}
+		read = func(name string) ([]byte, error) {
+			reader, err := files[name].Open()
+			if err != nil {
+				return nil, err
+			}
+			defer reader.Close()
+			return ioutil.ReadAll(reader)
+		}
+	default:
+		names = []string{a.path}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		isAccount := path.Base(filepath.ToSlash(name)) == "account.js"
+		if !isAccount && !archiveTweetsFile(name) && name != a.path {
+			continue
+		}
+		data, err := read(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isAccount {
+			account = data
+		} else {
+			tweets = append(tweets, data)
+		}
+	}
+	if len(tweets) == 0 {
+		return nil, nil, fmt.Errorf("%s: no tweets.js in the archive", a.path)
+	}
+	return tweets, account, nil
+}
+
+// archiveJSON strips the "window.YTD.tweets.part0 = " the archive's files start with
+func archiveJSON(data []byte) []byte {
+	if start := bytes.IndexAny(data, "[{"); start > 0 {
+		return data[start:]
+	}
+	return data
+}
+
+// Start implements Source, sending each tweet in the archive on
+func (a *TwitterArchive) Start(ctx context.Context) <-chan Item {
+	items := make(chan Item)
+	go func() {
+		defer close(items)
+		files, accountFile, err := a.archiveFiles()
+		if err != nil {
+			a.logger.Error("Unable to read Twitter archive", zap.String("archive", a.path), zap.Error(err))
+			return
+		}
+		// the tweets in the archive don't say whose they are, account.js does
+		var accounts []struct {
+			Account struct {
+				Username  string `json:"username"`
+				AccountID string `json:"accountId"`
+			} `json:"account"`
+		}
+		if accountFile != nil {
+			if err := json.Unmarshal(archiveJSON(accountFile), &accounts); err != nil {
+				a.logger.Warn("Unable to read the archive's account", zap.String("archive", a.path), zap.Error(err))
+			}
+		}
+
+		for _, data := range files {
+			var tweets []struct {
+				Tweet anaconda.Tweet `json:"tweet"`
+			}
+			if err := json.Unmarshal(archiveJSON(data), &tweets); err != nil {
+				a.logger.Error("Unable to read Twitter archive tweets", zap.String("archive", a.path), zap.Error(err))
+				continue
+			}
+			a.logger.Info("Importing Twitter archive tweets", zap.String("archive", a.path), zap.Int("tweets", len(tweets)))
+			for i := range tweets {
+				tweet := &tweets[i].Tweet
+				if len(accounts) > 0 && tweet.User.ScreenName == "" {
+					tweet.User.ScreenName = accounts[0].Account.Username
+					tweet.User.IdStr = accounts[0].Account.AccountID
+				}
+				// the archive only has the full text, with the t.co links it was posted with
+				if tweet.Text == "" {
+					tweet.Text = tweet.FullText
+				}
+				for _, link := range tweet.Entities.Urls {
+					if link.Expanded_url != "" {
+						tweet.Text = strings.Replace(tweet.Text, link.Url, link.Expanded_url, -1)
+					}
+				}
+				select {
+				case items <- Item{Tweet: tweet, Provenance: &Provenance{Source: "twitter-archive"}}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return items
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shah/content-harvester-twitter/pkg/transport"
+	"github.com/shah/content-harvester-utils"
+	"go.uber.org/zap"
+)
+
+// urlErrorKeyPrefix starts the keys of the error records written for invalid URLs, so they
+// stay out of the stored documents
+const urlErrorKeyPrefix = "url-error-"
+
+// maxDiagnosisTimeout bounds how long diagnosing holds up saving a text
+const maxDiagnosisTimeout = 5 * time.Second
+
+// invalidURLReasons has why each invalid resource of a text couldn't be resolved
+type invalidURLReasons map[*harvester.HarvestedResource]string
+
+// URLErrorDiagnoser works out why URLs the harvester found invalid couldn't be resolved:
+// content-harvester-utils only reports that resolution failed, so the URL is tried again
+type URLErrorDiagnoser struct {
+	client *http.Client
+}
+
+// NewURLErrorDiagnoser gives up on each URL after timeout, maxDiagnosisTimeout at most
+func NewURLErrorDiagnoser(timeout time.Duration) *URLErrorDiagnoser {
+	if timeout <= 0 || timeout > maxDiagnosisTimeout {
+		timeout = maxDiagnosisTimeout
+	}
+	result := new(URLErrorDiagnoser)
+	result.client = &http.Client{Timeout: timeout}
+	return result
+}
+
+// Diagnose returns a short description of what goes wrong resolving originalURL: a parse
+// error, DNS failure, refused connection, TLS error, timeout or HTTP error status
+func (d *URLErrorDiagnoser) Diagnose(originalURL string) string {
+	parsed, err := url.Parse(originalURL)
+	if err != nil {
+		return "Unparseable URL: " + err.Error()
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Sprintf("Unsupported scheme %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "URL without a host"
+	}
+
+	// HEAD isn't always answered properly, GET without reading the body is as cheap
+	resp, err := d.client.Get(parsed.String())
+	if err != nil {
+		return describeRequestError(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "HTTP " + resp.Status
+	}
+	return fmt.Sprintf("Not reproducible, HTTP %s on retry", resp.Status)
+}
+
+func describeRequestError(err error) string {
+	if urlErr, ok := err.(*url.Error); ok && urlErr.Timeout() {
+		return "Timeout"
+	}
+	cause := rootError(err)
+	switch cause := cause.(type) {
+	case *net.DNSError:
+		if cause.Timeout() {
+			return "DNS failure: timeout looking up " + cause.Name
+		}
+		return "DNS failure: " + cause.Err + " " + cause.Name
+	case syscall.Errno:
+		switch cause {
+		case syscall.ECONNREFUSED:
+			return "Connection refused"
+		case syscall.ECONNRESET:
+			return "Connection reset"
+		case syscall.EHOSTUNREACH, syscall.ENETUNREACH:
+			return "Host unreachable"
+		}
+	case *transport.CircuitOpenError:
+		return "Circuit open: " + cause.Host + " has been failing"
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError, tls.RecordHeaderError:
+		return "TLS error: " + cause.Error()
+	}
+	if strings.HasPrefix(cause.Error(), "x509: ") || strings.HasPrefix(cause.Error(), "tls: ") {
+		// newer versions of crypto/tls wrap the certificate errors
+		return "TLS error: " + cause.Error()
+	}
+	if strings.Contains(err.Error(), "redirects") && strings.Contains(err.Error(), "stopped after") {
+		return "Too many redirects"
+	}
+	return cause.Error()
+}
+
+// rootError strips the url.Error, net.OpError and os.SyscallError wrapping of net/http's
+// errors, leaving what actually went wrong
+func rootError(err error) error {
+	for {
+		switch wrapper := err.(type) {
+		case *url.Error:
+			err = wrapper.Err
+		case *net.OpError:
+			err = wrapper.Err
+		case *os.SyscallError:
+			err = wrapper.Err
+		default:
+			return err
+		}
+	}
+}
+
+// DiagnoseInvalidURLsWith makes the storage find out why each invalid URL couldn't be resolved,
+// for the logs, the CSV log, ignore observers and error records
+func (storage *HarvestedResourceStorage) DiagnoseInvalidURLsWith(diagnoser *URLErrorDiagnoser) {
+	storage.diagnoser = diagnoser
+}
+
+// RecordURLErrors makes the storage write an error record, a document whose front matter
+// has the URL and why it couldn't be resolved, for each invalid URL
+func (storage *HarvestedResourceStorage) RecordURLErrors() {
+	storage.recordURLErrors = true
+}
+
+// invalidURLReasons diagnoses the invalid resources, if there's a diagnoser; it doesn't need the
+// storage to itself, so other texts are saved meanwhile
+func (storage *HarvestedResourceStorage) invalidURLReasons(resources []*harvester.HarvestedResource) invalidURLReasons {
+	reasons := make(invalidURLReasons)
+	var invalid []*harvester.HarvestedResource
+	for _, res := range resources {
+		if isURLValid, _ := res.IsValid(); !isURLValid {
+			invalid = append(invalid, res)
+			reasons[res] = "Not sure why"
+		}
+	}
+	if storage.diagnoser != nil && len(invalid) > 0 {
+		// all at once, so a text full of dead links takes one timeout rather than one each
+		diagnosed := make([]string, len(invalid))
+		var wg sync.WaitGroup
+		for i, res := range invalid {
+			wg.Add(1)
+			go func(i int, originalURL string) {
+				defer wg.Done()
+				diagnosed[i] = storage.diagnoser.Diagnose(originalURL)
+			}(i, res.OriginalURLText())
+		}
+		wg.Wait()
+		for i, res := range invalid {
+			reasons[res] = diagnosed[i]
+		}
+	}
+	return reasons
+}
+
+// logInvalidURLs logs the invalid resources of text with their reasons and writes their error
+// records
+func (storage *HarvestedResourceStorage) logInvalidURLs(text string, provenance *Provenance, resources []*harvester.HarvestedResource, reasons invalidURLReasons) {
+	for _, res := range resources {
+		reason, invalid := reasons[res]
+		if !invalid {
+			continue
+		}
+		storage.logger.Info("Invalid URL", zap.String("source", text),
+			zap.String("originalURLText", res.OriginalURLText()),
+			zap.String("reason", reason),
+		)
+		if storage.recordURLErrors && storage.dryRun == nil {
+			storage.writeURLError(text, provenance, res.OriginalURLText(), reason)
+		}
+	}
+}
+
+func (storage *HarvestedResourceStorage) writeURLError(text string, provenance *Provenance, originalURL string, reason string) {
+	hash := sha1.Sum([]byte(originalURL))
+	harvestedAt := time.Now()
+	fields := map[string]interface{}{
+		"originalURL": originalURL,
+		"reason":      reason,
+		"harvestedAt": harvestedAt.Format(time.RFC3339),
+	}
+	provenance.addFrontMatter(fields)
+	document, err := addFrontMatter(text+"\n", fields)
+	if err != nil {
+		storage.logger.Error("Unable to write URL error record", zap.String("originalURLText", originalURL), zap.Error(err))
+		return
+	}
+	record := &DocumentTemplateData{
+		Slug:        urlErrorKeyPrefix + hex.EncodeToString(hash[:8]),
+		OriginalURL: originalURL,
+		Text:        text,
+		HarvestedAt: harvestedAt,
+		Provenance:  provenance,
+		FrontMatter: fields,
+	}
+	storage.writeRecord(record, document)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxWARCFileSize is when a WARC file is closed and the next one started, as archiving tools
+// usually expect files of about a gigabyte
+const maxWARCFileSize = 1024 * 1024 * 1024
+
+// WARCWriter records fetched destinations as WARC 1.0 request/response pairs, in gzipped files
+// with one gzip member per record
+type WARCWriter struct {
+	dir     string
+	prefix  string
+	mutex   sync.Mutex
+	serial  int
+	file    *os.File
+	written int64
+}
+
+// NewWARCWriter creates a writer that puts its files in dir, named prefix-<timestamp>-<serial>.warc.gz
+func NewWARCWriter(dir string, prefix string) (*WARCWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	result := new(WARCWriter)
+	result.dir = dir
+	result.prefix = prefix
+	return result, nil
+}
+
+// WritePage appends the request and response records for page and returns the file they went
+// into along with the response's record ID
+func (w *WARCWriter) WritePage(page *FetchedPage) (string, string, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil || w.written >= maxWARCFileSize {
+		if err := w.rotate(); err != nil {
+			return "", "", err
+		}
+	}
+
+	responseID := newWARCRecordID()
+	date := page.FetchedAt.UTC().Format(time.RFC3339)
+	digest := sha1.Sum(page.Body)
+	response := append(append([]byte{}, page.RawResponseHeader...), page.Body...)
+	err := w.writeRecord([]string{
+		"WARC-Type: response",
+		"WARC-Record-ID: " + responseID,
+		"WARC-Date: " + date,
+		"WARC-Target-URI: " + page.URL.String(),
+		"WARC-Payload-Digest: sha1:" + base32.StdEncoding.EncodeToString(digest[:]),
+		"Content-Type: application/http;msgtype=response",
+	}, response)
+	if err != nil {
+		return "", "", err
+	}
+	err = w.writeRecord([]string{
+		"WARC-Type: request",
+		"WARC-Record-ID: " + newWARCRecordID(),
+		"WARC-Date: " + date,
+		"WARC-Target-URI: " + page.URL.String(),
+		"WARC-Concurrent-To: " + responseID,
+		"Content-Type: application/http;msgtype=request",
+	}, page.RawRequest)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Base(w.file.Name()), responseID, nil
+}
+
+// Close finishes the current WARC file
+func (w *WARCWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *WARCWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+	name := fmt.Sprintf("%s-%s-%05d.warc.gz", w.prefix, time.Now().UTC().Format("20060102150405"), w.serial)
+	file, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return err
+	}
+	w.serial++
+	w.file = file
+	w.written = 0
+	return w.writeRecord([]string{
+		"WARC-Type: warcinfo",
+		"WARC-Record-ID: " + newWARCRecordID(),
+		"WARC-Date: " + time.Now().UTC().Format(time.RFC3339),
+		"WARC-Filename: " + name,
+		"Content-Type: application/warc-fields",
+	}, []byte("software: content-harvester-twitter\r\nformat: WARC File Format 1.0\r\n"))
+}
+
+func (w *WARCWriter) writeRecord(headers []string, block []byte) error {
+	var record bytes.Buffer
+	record.WriteString("WARC/1.0\r\n")
+	for _, header := range headers {
+		record.WriteString(header + "\r\n")
+	}
+	fmt.Fprintf(&record, "Content-Length: %d\r\n\r\n", len(block))
+	record.Write(block)
+	record.WriteString("\r\n\r\n")
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(record.Bytes()); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	n, err := w.file.Write(compressed.Bytes())
+	w.written += int64(n)
+	return err
+}
+
+func newWARCRecordID() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// warcEnricher records each fetched destination in the WARC files and notes where
+type warcEnricher struct {
+	writer *WARCWriter
+	logger *zap.Logger
+}
+
+func (e warcEnricher) EnrichResource(page *FetchedPage, resource *EnrichedResource) {
+	file, recordID, err := e.writer.WritePage(page)
+	if err != nil {
+		e.logger.Error("Unable to write WARC records", zap.String("url", page.URL.String()), zap.Error(err))
+		return
+	}
+	resource.FrontMatter["warcFile"] = file
+	resource.FrontMatter["warcRecordID"] = recordID
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const waybackMachineURL = "https://web.archive.org"
+
+// WaybackMachine asks the Internet Archive to save harvested URLs, providing an off-site copy
+// of everything we harvest; submissions are spaced out to stay within the Archive's limits
+type WaybackMachine struct {
+	client      *http.Client
+	logger      *zap.Logger
+	interval    time.Duration
+	mutex       sync.Mutex
+	lastRequest time.Time
+}
+
+// NewWaybackMachine creates a client that submits a URL at most once every interval
+func NewWaybackMachine(logger *zap.Logger, interval time.Duration) *WaybackMachine {
+	result := new(WaybackMachine)
+	result.client = &http.Client{Timeout: 2 * time.Minute}
+	result.logger = logger
+	result.interval = interval
+	return result
+}
+
+// Save submits destination and returns the URL of the snapshot the Archive made of it
+func (w *WaybackMachine) Save(destination *url.URL) (string, error) {
+	w.mutex.Lock()
+	if wait := w.interval - time.Since(w.lastRequest); wait > 0 {
+		time.Sleep(wait)
+	}
+	w.lastRequest = time.Now()
+	w.mutex.Unlock()
+
+	resp, err := w.client.Get(waybackMachineURL + "/save/" + destination.String())
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Wayback Machine returned %s", resp.Status)
+	}
+
+	if location := resp.Header.Get("Content-Location"); strings.HasPrefix(location, "/web/") {
+		return waybackMachineURL + location, nil
+	}
+	if strings.HasPrefix(resp.Request.URL.Path, "/web/") {
+		return resp.Request.URL.String(), nil
+	}
+	return "", fmt.Errorf("Wayback Machine didn't say where the snapshot of %s is", destination)
+}
+
+func (w *WaybackMachine) EnrichDestination(destination *url.URL, resource *EnrichedResource) {
+	snapshot, err := w.Save(destination)
+	if err != nil {
+		w.logger.Warn("Unable to save in Wayback Machine", zap.String("url", destination.String()), zap.Error(err))
+		return
+	}
+	resource.FrontMatter["waybackURL"] = snapshot
+}
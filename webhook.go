@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// webhookQueueSize is how many events can wait for delivery before harvesting blocks on them
+const webhookQueueSize = 1000
+
+// webhookEvent is the JSON payload POSTed to webhooks
+type webhookEvent struct {
+	Event    string           `json:"event"`
+	Resource *ResourceRecord  `json:"resource,omitempty"`
+	Ignored  *IgnoredResource `json:"ignored,omitempty"`
+	SentAt   time.Time        `json:"sentAt"`
+}
+
+// WebhookNotifier POSTs a JSON event to each of its URLs for every stored resource and,
+// optionally, every ignored one. Deliveries happen in the background, are retried with
+// exponential backoff on network errors and 5xx or 429 responses and, with a secret, are
+// signed with an HMAC-SHA256 of the body in the X-Harvester-Signature header.
+type WebhookNotifier struct {
+	client     *http.Client
+	logger     *zap.Logger
+	urls       []string
+	format     func(event *webhookEvent) ([]byte, error)
+	match      *regexp.Regexp
+	secret     []byte
+	maxRetries int
+	ignored    bool
+	queue      chan *webhookEvent
+	done       sync.WaitGroup
+}
+
+// NewWebhookNotifier starts delivering events to urls
+func NewWebhookNotifier(logger *zap.Logger, urls []string, secret string, timeout time.Duration, maxRetries int, ignored bool) *WebhookNotifier {
+	result := new(WebhookNotifier)
+	result.client = &http.Client{Timeout: timeout}
+	result.logger = logger
+	result.urls = urls
+	result.format = func(event *webhookEvent) ([]byte, error) { return json.Marshal(event) }
+	result.secret = []byte(secret)
+	result.maxRetries = maxRetries
+	result.ignored = ignored
+	result.queue = make(chan *webhookEvent, webhookQueueSize)
+
+	result.done.Add(1)
+	go func() {
+		defer result.done.Done()
+		for event := range result.queue {
+			result.deliver(event)
+		}
+	}()
+	return result
+}
+
+// WriteResource implements ResourceWriter, sending a "saved" event
+func (w *WebhookNotifier) WriteResource(resource *DocumentTemplateData, document string, attachments []Attachment) error {
+	if w.match != nil && !w.match.MatchString(resource.Text+" "+resource.CleanedURL) {
+		if title, _ := resource.FrontMatter["title"].(string); !w.match.MatchString(title) {
+			return nil
+		}
+	}
+	w.queue <- &webhookEvent{Event: "saved", Resource: newResourceRecord(resource), SentAt: time.Now()}
+	return nil
+}
+
+// ResourceIgnored implements IgnoreObserver, sending an "ignored" event if those were asked for
+func (w *WebhookNotifier) ResourceIgnored(resource *IgnoredResource) {
+	if w.ignored {
+		w.queue <- &webhookEvent{Event: "ignored", Ignored: resource, SentAt: time.Now()}
+	}
+}
+
+// Close waits for the queued events to be delivered
+func (w *WebhookNotifier) Close() error {
+	close(w.queue)
+	w.done.Wait()
+	return nil
+}
+
+// Queued implements QueuedWriter
+func (w *WebhookNotifier) Queued() int {
+	return len(w.queue)
+}
+
+func (w *WebhookNotifier) deliver(event *webhookEvent) {
+	body, err := w.format(event)
+	if err != nil {
+		w.logger.Error("Unable to encode webhook event", zap.Error(err))
+		return
+	}
+	var signature string
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	for _, url := range w.urls {
+		backoff := time.Second
+		for attempt := 0; ; attempt++ {
+			retry, err := w.post(url, body, signature)
+			if err == nil {
+				break
+			}
+			if !retry || attempt >= w.maxRetries {
+				w.logger.Error("Unable to deliver webhook event", zap.String("url", url),
+					zap.String("event", event.Event), zap.Int("attempts", attempt+1), zap.Error(err))
+				break
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// post sends body to url and returns whether a failure is worth retrying
+func (w *WebhookNotifier) post(url string, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signature != "" {
+		req.Header.Set("X-Harvester-Signature", signature)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	webSocketWriteTimeout = 10 * time.Second
+	webSocketPongTimeout  = time.Minute
+	webSocketPingInterval = webSocketPongTimeout * 9 / 10
+)
+
+var webSocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// dashboards are expected to be served from anywhere
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// streamWebSocket sends each resource stored while the client is connected as a JSON text
+// frame, pinging the client so dead connections get noticed
+func (s *HarvesterServer) streamWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := webSocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already responded
+		return
+	}
+	defer conn.Close()
+
+	subscriber := s.broadcaster.Subscribe()
+	defer s.broadcaster.Unsubscribe(subscriber)
+
+	// clients don't send anything but we need to read to see pongs and the connection closing
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(webSocketPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case document := <-subscriber:
+			conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+			if err := conn.WriteJSON(newStoredDocumentJSON(document, false)); err != nil {
+				s.logger.Info("WebSocket client gone", zap.String("remoteAddr", r.RemoteAddr), zap.Error(err))
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}